@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
 )
 
 const (
@@ -18,6 +19,15 @@ const (
 	activityRolloutFailed
 	activityInstanceUpdateFailed
 	activityChannelPackageUpdated
+	activityRolloutErrorBudgetExceeded
+	activityChannelRolledBack
+	activityChannelRollbackFailed
+	activityRollbackStarted
+	activityScheduledChangeSkipped
+	activityInstanceUpdateTimedOut
+	activityPackageRetracted
+	activityCriticalUpdatePolicyApplied
+	activityScheduledChangeFailed
 )
 
 const (
@@ -33,10 +43,11 @@ const (
 
 // activityContext represents the context of a given activity entry.
 type activityContext struct {
-	appID      string
-	groupID    string
-	channelID  string
-	instanceID string
+	appID          string
+	groupID        string
+	channelID      string
+	instanceID     string
+	instancesCount int
 }
 
 // Activity represents a CoreRoller activity entry.
@@ -49,6 +60,7 @@ type Activity struct {
 	GroupName       dat.NullString `db:"group_name" json:"group_name"`
 	ChannelName     dat.NullString `db:"channel_name" json:"channel_name"`
 	InstanceID      dat.NullString `db:"instance_id" json:"instance_id"`
+	InstancesCount  dat.NullInt64  `db:"instances_count" json:"instances_count"`
 }
 
 // ActivityQueryParams represents a helper structure used to pass a set of
@@ -94,7 +106,7 @@ func (api *API) activityQuery(teamID string, p ActivityQueryParams) *dat.SelectD
 	}
 
 	query := api.dbR.
-		SelectDoc("a.created_ts", "a.class", "a.severity", "a.version", "a.instance_id", "app.name as application_name", "g.name as group_name", "c.name as channel_name").
+		SelectDoc("a.created_ts", "a.class", "a.severity", "a.version", "a.instance_id", "a.instances_count", "app.name as application_name", "g.name as group_name", "c.name as channel_name").
 		From(`
 			activity a 
 			INNER JOIN application app ON (a.application_id = app.id)
@@ -133,6 +145,26 @@ func (api *API) activityQuery(teamID string, p ActivityQueryParams) *dat.SelectD
 	return query
 }
 
+// newGroupActivityEntryOnce creates a new activity entry related to a specific
+// group, unless an entry of the same class has already been created for the
+// given version and group. It returns if the entry was created.
+//
+// This method is part of the transaction that locks the group while processing
+// an update request, which prevents the entry from being created twice.
+func (api *API) newGroupActivityEntryOnce(tx *runner.Tx, class int, severity int, version, appID, groupID string) (bool, error) {
+	query := `
+	INSERT INTO activity (class, severity, version, application_id, group_id)
+	SELECT $1, $2, $3, $4, $5
+	WHERE NOT EXISTS (SELECT 1 FROM activity WHERE class = $1 AND version = $3 AND group_id = $5)
+	`
+	result, err := tx.SQL(query, class, severity, version, appID, groupID).Exec()
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
 // newGroupActivityEntry creates a new activity entry related to a specific
 // group.
 func (api *API) newGroupActivityEntry(class int, severity int, version, appID, groupID string) error {
@@ -175,6 +207,28 @@ func (api *API) newChannelActivityEntry(class int, severity int, version, appID,
 	return nil
 }
 
+// newPackageActivityEntry creates a new activity entry related to a package of
+// the application provided, recording as well the number of instances
+// affected.
+func (api *API) newPackageActivityEntry(class int, severity int, version, appID string, instancesCount int) error {
+	_, err := api.dbR.InsertInto("activity").
+		Columns("class", "severity", "version", "application_id", "instances_count").
+		Values(class, severity, version, appID, instancesCount).
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	ctx := &activityContext{
+		appID:          appID,
+		instancesCount: instancesCount,
+	}
+	go api.postHipchat(class, severity, version, ctx)
+
+	return nil
+}
+
 // newInstanceActivityEntry creates a new activity entry related to a specific
 // instance.
 func (api *API) newInstanceActivityEntry(class int, severity int, version, appID, groupID, instanceID string) error {
@@ -237,10 +291,42 @@ func (api *API) postHipchat(class, severity int, version string, ctx *activityCo
 		instance, _ := api.GetInstance(ctx.instanceID, ctx.appID)
 		fmt.Fprintf(&msg, "Instance <i>%s</i> reported an error while processing update to version <i>%s</i>", instance.IP, version)
 		color = "yellow"
+	case activityRolloutErrorBudgetExceeded:
+		fmt.Fprintf(&msg, "Roll out of version <i>%s</i> has been paused as the group's failed updates limit was reached. Group's updates have been disabled", version)
+		color = "red"
+	case activityChannelRolledBack:
+		channel, _ := api.GetChannel(ctx.channelID)
+		fmt.Fprintf(&msg, "Channel <i>%s</i> has been rolled back to version <i>%s</i> after a failed roll out", channel.Name, version)
+		color = "yellow"
+	case activityChannelRollbackFailed:
+		channel, _ := api.GetChannel(ctx.channelID)
+		fmt.Fprintf(&msg, "Channel <i>%s</i> could not be rolled back from version <i>%s</i> as no known good package was found", channel.Name, version)
+		color = "red"
+	case activityRollbackStarted:
+		fmt.Fprintf(&msg, "Instances that got the bad version will be rolled back to version <i>%s</i> once the group's updates are enabled again", version)
+		color = "purple"
+	case activityScheduledChangeSkipped:
+		channel, _ := api.GetChannel(ctx.channelID)
+		fmt.Fprintf(&msg, "Scheduled change of channel <i>%s</i> to version <i>%s</i> has been skipped as the package has blacklisted the channel or has been retracted", channel.Name, version)
+		color = "yellow"
+	case activityScheduledChangeFailed:
+		channel, _ := api.GetChannel(ctx.channelID)
+		fmt.Fprintf(&msg, "Scheduled change of channel <i>%s</i> to version <i>%s</i> could not be applied", channel.Name, version)
+		color = "red"
+	case activityInstanceUpdateTimedOut:
+		instance, _ := api.GetInstance(ctx.instanceID, ctx.appID)
+		fmt.Fprintf(&msg, "Instance <i>%s</i> didn't report back the result of the update to version <i>%s</i> before the update timeout", instance.IP, version)
+		color = "yellow"
 	case activityChannelPackageUpdated:
 		channel, _ := api.GetChannel(ctx.channelID)
 		fmt.Fprintf(&msg, "Channel <i>%s</i> is now pointing to version <i>%s</i>", channel.Name, version)
 		color = "purple"
+	case activityCriticalUpdatePolicyApplied:
+		fmt.Fprintf(&msg, "Version <i>%s</i> is a critical update, it's being rolled out using the group's critical updates policy", version)
+		color = "red"
+	case activityPackageRetracted:
+		fmt.Fprintf(&msg, "Version <i>%s</i> has been retracted. %d instances running it will be updated to their channel's version", version, ctx.instancesCount)
+		color = "red"
 	}
 
 	body := map[string]interface{}{
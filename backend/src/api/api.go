@@ -25,7 +25,8 @@ var (
 	// delete database operation.
 	ErrNoRowsAffected = errors.New("coreroller: no rows affected")
 
-	// ErrInvalidSemver indicates that the provided semver version is not valid.
+	// ErrInvalidSemver indicates that the provided version is not valid in the
+	// version scheme of its application (semver by default).
 	ErrInvalidSemver = errors.New("coreroller: invalid semver")
 )
 
@@ -35,6 +36,8 @@ type API struct {
 	dbR      *runner.DB
 	dbDriver string
 	dbURL    string
+
+	groupsCache *groupsCache
 }
 
 // New creates a new API instance, creating the underlying db connection and
@@ -63,6 +66,7 @@ func New(options ...func(*API) error) (*API, error) {
 	for _, option := range options {
 		err := option(api)
 		if err != nil {
+			_ = api.db.Close()
 			return nil, err
 		}
 	}
@@ -77,6 +81,10 @@ func New(options ...func(*API) error) (*API, error) {
 		return nil, err
 	}
 
+	if err := api.backfillVersionSortKeys(); err != nil {
+		return nil, err
+	}
+
 	return api, nil
 }
 
@@ -98,5 +106,8 @@ func OptionInitDB(api *API) error {
 
 // Close releases the connections to the database.
 func (api *API) Close() {
+	if api.groupsCache != nil {
+		api.groupsCache.close()
+	}
 	_ = api.db.DB.Close()
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -13,14 +14,15 @@ const (
 
 // Application represents a CoreRoller application instance.
 type Application struct {
-	ID          string     `db:"id" json:"id"`
-	Name        string     `db:"name" json:"name"`
-	Description string     `db:"description" json:"description"`
-	CreatedTs   time.Time  `db:"created_ts" json:"created_ts"`
-	TeamID      string     `db:"team_id" json:"-"`
-	Groups      []*Group   `db:"groups" json:"groups"`
-	Channels    []*Channel `db:"channels" json:"channels"`
-	Packages    []*Package `db:"packages" json:"packages"`
+	ID            string     `db:"id" json:"id"`
+	Name          string     `db:"name" json:"name"`
+	Description   string     `db:"description" json:"description"`
+	VersionScheme string     `db:"version_scheme" json:"version_scheme"`
+	CreatedTs     time.Time  `db:"created_ts" json:"created_ts"`
+	TeamID        string     `db:"team_id" json:"-"`
+	Groups        []*Group   `db:"groups" json:"groups"`
+	Channels      []*Channel `db:"channels" json:"channels"`
+	Packages      []*Package `db:"packages" json:"packages"`
 
 	Instances struct {
 		Count int `db:"count" json:"count"`
@@ -29,9 +31,16 @@ type Application struct {
 
 // AddApp registers the provided application.
 func (api *API) AddApp(app *Application) (*Application, error) {
+	if app.VersionScheme == "" {
+		app.VersionScheme = VersionSchemeSemver
+	}
+	if !isValidVersionScheme(app.VersionScheme) {
+		return nil, ErrInvalidVersionScheme
+	}
+
 	err := api.dbR.
 		InsertInto("application").
-		Whitelist("name", "description", "team_id").
+		Whitelist("name", "description", "version_scheme", "team_id").
 		Record(app).
 		Returning("*").
 		QueryStruct(app)
@@ -63,6 +72,7 @@ func (api *API) AddAppCloning(app *Application, sourceAppID string) (*Applicatio
 			originalChannelID := channel.ID
 			channel.ApplicationID = app.ID
 			channel.PackageID = dat.NullString{}
+			channel.PromotionSourceChannelID = dat.NullString{} // promotion rules are not cloned
 			channelCopy, err := api.AddChannel(channel)
 			if err != nil {
 				return app, nil // FIXME - think about what we should return to the caller
@@ -76,6 +86,7 @@ func (api *API) AddAppCloning(app *Application, sourceAppID string) (*Applicatio
 				group.ChannelID = channelsIDsMappings[group.ChannelID.String]
 			}
 			group.PolicyUpdatesEnabled = true
+			group.PolicyGateGroupID = dat.NullString{} // gates between groups are not cloned
 			if _, err := api.AddGroup(group); err != nil {
 				return app, nil // FIXME - think about what we should return to the caller
 			}
@@ -86,19 +97,53 @@ func (api *API) AddAppCloning(app *Application, sourceAppID string) (*Applicatio
 }
 
 // UpdateApp updates an existing application using the content of the
-// application provided.
+// application provided. When the version scheme of the application changes,
+// the versions of its packages must be valid in the new one. An empty version
+// scheme leaves the current one unchanged.
 func (api *API) UpdateApp(app *Application) error {
-	result, err := api.dbR.
+	if app.VersionScheme != "" && !isValidVersionScheme(app.VersionScheme) {
+		return ErrInvalidVersionScheme
+	}
+
+	currentScheme, err := api.getVersionScheme(app.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoRowsAffected
+		}
+		return err
+	}
+	if app.VersionScheme == "" {
+		app.VersionScheme = currentScheme
+	}
+
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	result, err := tx.
 		Update("application").
-		SetWhitelist(app, "name", "description").
+		SetWhitelist(app, "name", "description", "version_scheme").
 		Where("id = $1", app.ID).
 		Exec()
 
-	if err == nil && result.RowsAffected == 0 {
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
 		return ErrNoRowsAffected
 	}
 
-	return err
+	if app.VersionScheme != currentScheme {
+		if err := api.updateVersionSortKeys(tx, app.ID, app.VersionScheme); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // DeleteApp removes the application identified by the id provided.
@@ -150,7 +195,7 @@ func (api *API) GetApps(teamID string, page, perPage uint64) ([]*Application, er
 // specify how to query the rows or their destination.
 func (api *API) appsQuery() *dat.SelectDocBuilder {
 	return api.dbR.
-		SelectDoc("id, name, description, created_ts").
+		SelectDoc("id, name, description, version_scheme, created_ts").
 		One("instances", api.appInstancesCountQuery()).
 		Many("groups", api.groupsQuery().Where("application_id = application.id")).
 		Many("channels", api.channelsQuery().Where("application_id = application.id")).
@@ -2,6 +2,30 @@
 // sources:
 // db/drop_all_tables.sql
 // db/migrations/0001_initial.sql
+// db/migrations/0002_rollout_percentage.sql
+// db/migrations/0003_update_windows.sql
+// db/migrations/0004_error_budget.sql
+// db/migrations/0005_rollback_on_failure.sql
+// db/migrations/0006_exact_version.sql
+// db/migrations/0007_rollout_gates.sql
+// db/migrations/0008_channel_promotions.sql
+// db/migrations/0009_channel_scheduled_changes.sql
+// db/migrations/0010_updates_limits.sql
+// db/migrations/0011_instance_labels.sql
+// db/migrations/0012_package_min_version.sql
+// db/migrations/0013_package_deltas.sql
+// db/migrations/0014_package_files.sql
+// db/migrations/0015_package_arches.sql
+// db/migrations/0016_version_sort_key.sql
+// db/migrations/0017_version_scheme.sql
+// db/migrations/0018_package_retraction.sql
+// db/migrations/0019_critical_updates.sql
+// db/migrations/0020_changes_notifications.sql
+// db/migrations/0021_version_sort_key_backfill.sql
+// db/migrations/0022_application_changes_notifications.sql
+// db/migrations/0023_instance_labels_per_app.sql
+// db/migrations/0024_package_payload_arches.sql
+// db/migrations/0025_min_version_sort_key.sql
 // DO NOT EDIT!
 
 package api
@@ -69,7 +93,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _dbDrop_all_tablesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x91\x41\x8e\x85\x30\x0c\x43\xf7\x9c\xa2\xf7\xe0\x30\x91\x09\x19\x88\x28\x6d\xd5\x04\x34\xdc\x7e\x24\xc4\x6c\xbe\xbe\x14\xf6\x2f\xb6\x63\xcf\xbd\xb6\xe4\x98\xb2\x24\xfd\x49\xf2\xab\xe6\x96\x5c\xb0\x27\x86\x31\x66\x19\x87\xaf\xc8\x61\xd2\x2d\x60\xd0\x5a\x56\x86\x6b\x2d\x01\xd9\xc0\x1b\x16\x09\x28\xae\x5d\xaa\x11\xf8\x85\x22\xaf\x28\x45\x72\x40\x2d\xbd\x1e\x2d\x7a\x43\x8b\x39\x0a\x47\xe9\xfe\x31\x32\x87\x1f\x6f\x45\xe9\x7d\x49\x1f\x06\xb4\xaa\x79\xed\x57\x70\x25\xa7\x14\x27\xbf\x5a\x94\xff\x06\xa3\x45\xd9\xf5\x54\x8f\x3c\x9f\x39\xe9\x19\x81\xa6\x0c\xde\xb2\x5a\x24\x3f\xc3\x31\xc1\x84\x76\x5d\xfa\x5d\x89\x8d\xc3\x5f\x00\x00\x00\xff\xff\xda\x81\x6e\xb2\xa2\x02\x00\x00")
+var _dbDrop_all_tablesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\xd3\x5d\x6e\xc3\x30\x08\x07\xf0\xf7\x9e\x22\x8f\xdb\x19\x7a\x18\x44\x30\x49\x50\xa9\x6d\x19\xdc\x2e\xb7\x9f\xfa\xa5\x49\xd1\x24\xf3\xfe\x83\x84\x3f\x38\xb5\x52\x27\xc7\x59\x79\x92\x65\xe2\x1f\x31\xb7\xc9\x19\xaf\x13\xa1\x11\x26\x3e\x9f\xfe\x25\xdd\xb8\xd9\xc0\x60\xad\x2a\x84\x2e\x25\x0f\x64\x45\xba\xe0\xca\x03\x45\xa5\x71\x31\x40\x0a\x74\xa4\x0d\x73\x66\x1d\xa8\xb5\x95\x5e\x47\x63\x48\x36\xc7\x4c\x1c\x64\x60\x8e\xde\xa3\x4d\x21\x1e\xd2\xe1\x03\xb0\x89\x79\x69\xfb\xa0\x8a\x6f\x9c\x1d\x7c\xaf\x1c\x81\x03\xf3\x88\xfe\x26\xbe\xc7\xd6\x09\xef\x25\xc0\xac\x48\x17\x15\xf3\xc8\x3a\xa0\x15\xd5\xd2\xfd\x31\xe6\xca\xa1\x8a\x5e\x13\x3a\xc3\x5d\x72\x2a\xf7\xd8\x61\xc0\xe7\x1f\x63\x29\x7e\xaa\x8c\x36\x4e\x5d\x39\x3d\x67\x5b\xc3\x37\xa1\x38\xc7\x8e\xf1\x25\xc1\x58\x99\xbc\xb4\x60\xd0\x89\xd5\x31\x68\x17\x51\x0e\x52\x6c\xb4\x1d\xe8\xd2\xf3\xeb\xf9\xfd\xe9\x5c\x5c\x96\xfd\x1d\x88\x7d\x7d\x0f\x9a\x27\x74\x9c\xd1\x18\xae\xb2\xb6\xe7\xdd\xdb\xf9\xf4\x0b\x00\x00\xff\xff\x03\x00\xe7\x08\x92\xe2\x87\x04\x00\x00")
 
 func dbDrop_all_tablesSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -84,7 +108,7 @@ func dbDrop_all_tablesSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "db/drop_all_tables.sql", size: 674, mode: os.FileMode(420), modTime: time.Unix(1496479415, 0)}
+	info := bindataFileInfo{name: "db/drop_all_tables.sql", size: 1159, mode: os.FileMode(420), modTime: time.Unix(1792297488, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -109,6 +133,486 @@ func dbMigrations0001_initialSql() (*asset, error) {
 	return a, nil
 }
 
+var _dbMigrations0002_rollout_percentageSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7c\x52\x4d\x8f\xda\x30\x10\x3d\xe3\x5f\xf1\x6e\x9b\xa8\x41\x4a\x57\xea\x89\x5d\x4e\xfd\x07\x55\xcf\xd1\xd4\x1e\x82\x85\x63\xbb\xe3\x71\x5b\xfa\xeb\xab\xd0\x00\xbb\x02\xf6\xec\x37\xef\xcb\x6f\xbd\xc6\xa7\xc9\x8f\x42\xca\xf8\x9e\x8d\x59\xaf\xf1\x4d\x69\x64\x07\x49\x21\xa4\xaa\xc5\x18\x0a\xca\x02\xa5\x1f\x81\x61\xf7\x14\x23\x07\x90\x73\xb0\x29\xd4\x29\x22\x93\x3d\xd0\xc8\x43\xcd\x8e\x94\xdd\xa0\x05\xea\x27\x2e\x4a\x53\xd6\xbf\x70\xbc\xa3\x1a\x14\xb6\x8a\x70\xd4\xe1\xf2\x86\x98\x14\xb1\x86\xb0\x79\x2f\x31\x4a\xaa\xb9\xbc\x53\x48\xc1\xdb\xe3\xb0\x38\x1a\x32\x8b\xe5\x38\xbb\x44\xac\x13\x8b\xb7\xcd\x97\xee\xb9\x85\xdd\xb3\x3d\xa0\x79\x8c\xde\xbe\xa2\x07\x45\xf7\x01\xe1\xcb\x2b\x3e\xf7\x7d\xbb\x31\xc6\x0a\xcf\xa5\xbc\xf1\x74\xc1\x97\x13\xb4\x31\x2b\xef\x50\x58\x3c\x05\x64\xf1\x13\xc9\x11\x07\x3e\x76\x66\x95\x53\xf1\xea\x53\x84\x8f\xca\x23\xcb\x25\xea\xd5\xe3\x02\xd8\xa2\x6f\xe7\x83\x07\x91\x6e\xee\xee\x85\xb9\x71\xdf\x99\x95\xab\x42\x27\x81\x5f\x24\x76\x4f\xd2\x3c\xf7\xb7\x6c\x17\xd0\xcb\x16\x4f\x4f\xf3\xd9\xff\x9c\xde\xa1\x56\xef\xae\x78\xe1\x1d\x0b\x47\xcb\xe5\xfc\x3b\x8d\x77\x2d\x52\x84\xe3\xc0\xca\xb0\x54\x2c\x39\xee\xcc\xaa\x46\xff\xb3\x32\x9a\x33\x53\x87\x73\xd6\xd6\xcc\xb5\xbe\x1d\xdc\xd7\xf4\x3b\x1a\xe3\x24\xe5\xa5\x66\xbf\x03\xff\xf1\x45\xcb\xdd\xc2\x17\x91\xfb\x73\x39\xb1\x2c\x7b\xb9\xd2\x3c\xfc\xe8\xcd\xdd\x55\x3f\x20\xb9\x19\xf8\xc6\xfc\x03\x00\x00\xff\xff\x03\x00\x4e\xc8\xf7\x5f\x38\x03\x00\x00")
+
+func dbMigrations0002_rollout_percentageSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0002_rollout_percentageSql,
+		"db/migrations/0002_rollout_percentage.sql",
+	)
+}
+
+func dbMigrations0002_rollout_percentageSql() (*asset, error) {
+	bytes, err := dbMigrations0002_rollout_percentageSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0002_rollout_percentage.sql", size: 824, mode: os.FileMode(420), modTime: time.Unix(1792294518, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0003_update_windowsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7c\x92\x4f\x8b\xdb\x30\x14\xc4\xcf\xd1\xa7\x98\x5b\x1c\xaa\x40\xd2\x3f\x94\xd6\xb4\xa7\x7e\x85\x3d\x95\x62\xb4\xd2\x73\xf2\x88\x2c\x99\x27\x09\xaf\x29\xfd\xee\xc5\xf1\x3a\x6e\xc1\xec\xc5\x48\x78\x34\xd2\x6f\xe6\x1d\x8f\x78\xd7\xf1\x45\x4c\x26\x3c\xf5\x4a\x1d\x8f\x78\xea\xdd\xb4\x1b\x38\xb8\x38\x24\xa5\xac\xd0\xb4\xcf\xe6\xd9\x13\x2e\x12\x4b\xdf\x94\xbb\xa4\x99\x25\xa8\xd4\x8e\x1d\x12\x09\x1b\x8f\x5e\xb8\x33\x32\xe2\x46\xa3\x56\xbb\x81\xe8\xe6\xcc\x98\xc0\x21\xd3\x85\xe4\xe7\x2f\x84\x98\x11\x8a\xf7\xb0\x57\xb2\x37\x54\x46\xc4\x8c\x8d\xa7\x70\xc9\xd7\x6a\xd1\x6b\x9c\x0f\xf8\x8e\xd3\x41\xab\x5d\xca\x46\x72\x93\xb9\x23\xdc\x3f\x8b\x81\x56\x3b\x0a\x6e\xfb\xc7\xfc\x4c\x76\x28\x85\xdd\x7a\xa5\x50\x4b\x42\xc1\x52\x9a\x41\x12\x2a\x76\x07\xc4\x00\x47\x9e\x32\xc1\x9a\x64\x8d\x23\x75\xa8\x1f\xdc\x1c\x1c\xbd\x4c\x92\x4d\xf4\xe5\xa2\xe9\x00\x87\x44\x92\x27\xd4\xb8\x2d\x5e\xe9\x56\x26\x8d\x05\x42\xe3\x61\xa6\x76\x89\x3c\xd9\x8c\xfd\xef\xb3\x7e\xaf\x3f\xe8\x8f\xfa\xd3\x9f\xbd\xc6\xfe\xf4\xe5\xeb\xe9\x34\x2d\xce\x9f\xe7\x05\x3b\xb4\x12\xbb\x05\x67\xb8\x92\x10\xfa\xe8\xd9\x8e\x4d\x6c\x5b\xb6\xd4\x5c\x63\x91\x84\x6f\xc8\x52\xa8\x56\xca\xf8\x4c\xf2\x6f\x9b\x09\x4e\x62\x0f\x1b\x7d\xe9\xc2\xd6\xd9\xfa\x3e\x16\x8f\x31\xf9\x11\x87\xb0\x69\x63\x9c\x7b\xc3\x05\xcf\x31\x7a\x32\x53\xd4\xad\x29\x3e\xa3\x35\x3e\xad\xa5\xd5\x4a\xcd\x69\x2d\x6e\x89\xf2\x1b\x20\xaf\xa4\xec\xc0\x01\xd5\x6b\x5a\x8f\xd6\xd7\x48\xfe\xaf\x60\x6a\xe9\x0e\x3b\xbf\x9b\x5b\xd0\x0b\xa7\x9c\xb6\xb4\xcb\x2c\xd4\xea\x2f\x00\x00\x00\xff\xff\x03\x00\xab\x6e\x23\xd5\x24\x03\x00\x00")
+
+func dbMigrations0003_update_windowsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0003_update_windowsSql,
+		"db/migrations/0003_update_windows.sql",
+	)
+}
+
+func dbMigrations0003_update_windowsSql() (*asset, error) {
+	bytes, err := dbMigrations0003_update_windowsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0003_update_windows.sql", size: 804, mode: os.FileMode(420), modTime: time.Unix(1792294643, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0004_error_budgetSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xac\x90\xc1\x4a\x04\x31\x10\x44\xef\xf9\x8a\x3a\xee\xa2\x03\xa3\xe0\x69\x5c\x4f\xfa\x09\x9e\x87\xde\xa4\x27\x06\x33\x9d\xd0\xe9\xe0\xfa\xf7\xb2\x07\x61\x0f\x0a\xcb\xb0\x1f\x50\xf5\xaa\xde\x30\xe0\x6e\x4d\x51\xc9\x18\xef\xd5\xb9\x61\xc0\x9b\x6a\x51\x1c\x7b\x88\x6c\xce\x51\x36\x56\x18\x1d\x33\x23\x6a\xe9\xb5\x81\x42\x80\x2f\xb9\xaf\x82\x5a\x72\xf2\xdf\xf3\x4a\xa7\x79\xa1\x94\x39\xcc\xbd\x06\x32\x6e\x48\x62\x1c\x59\x11\x78\xa1\x9e\x0d\x23\xa4\x18\xa4\xe7\x0c\xff\xc1\xfe\x13\xbb\xff\xb3\x2f\x07\x8c\xfb\x69\x2b\x7a\xae\xac\x9e\xc5\x28\x32\xa4\xaf\xac\xc9\xef\x9e\xee\x1f\xf7\x5b\xa6\x5c\x76\x9d\x57\x81\x24\x5c\x47\x7e\x3e\xe0\x61\x3c\xbf\x70\x97\x8a\x5f\xcb\x97\xfc\xe9\x34\x68\xa9\xbf\xcf\xd2\x02\x3e\xa5\x66\xed\x2a\xd2\x74\x83\xba\xc9\xfd\x00\x00\x00\xff\xff\x03\x00\xee\xb2\x83\x26\x08\x02\x00\x00")
+
+func dbMigrations0004_error_budgetSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0004_error_budgetSql,
+		"db/migrations/0004_error_budget.sql",
+	)
+}
+
+func dbMigrations0004_error_budgetSql() (*asset, error) {
+	bytes, err := dbMigrations0004_error_budgetSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0004_error_budget.sql", size: 520, mode: os.FileMode(420), modTime: time.Unix(1792294884, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0005_rollback_on_failureSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x92\xc1\x8e\xdb\x30\x0c\x44\xcf\xd1\x57\xf0\x98\xa0\xc9\xa5\xc0\x9e\x7c\x2a\xd0\x5f\xe8\x59\x60\x24\x3a\x21\x42\x8b\x06\x45\xed\x6e\xfa\xf5\x85\xb7\x8e\xe3\x00\x49\xd1\xab\xc9\x79\x1e\x0d\xe7\x70\x80\x6f\x03\x9f\x0c\x9d\xe0\xd7\x18\xc2\xe1\x00\x3f\x9a\xeb\x80\xce\x09\x4c\x45\x8e\x98\x2e\x35\x04\x14\x27\x03\xc7\xa3\x10\x9c\x4c\xdb\x58\x01\x73\x86\xa4\xd2\x86\x02\xa3\x0a\xa7\x6b\xbc\xed\x47\x2d\xb1\x47\x96\x66\x04\x47\x55\x21\x2c\x90\xa9\xc7\x26\x0e\x3d\x4a\x25\x28\xea\x50\x9a\x48\xf7\x00\x4e\x67\x2c\x85\x64\x4d\x9e\x90\x94\xe3\x17\xf5\x9d\xac\xb2\x16\x78\x47\x4b\x67\xb4\xed\xf7\xb7\xb7\x5d\x17\x42\x32\x9a\xdc\x3f\x20\xe2\x88\xe9\x82\x27\x8a\x67\xae\xae\x76\x85\x6d\xd8\x70\x86\x4a\xc6\x28\x30\x1a\x0f\x68\x57\xb8\xd0\x75\x1f\x36\x7f\xf5\x39\x7a\x05\xe7\x81\xaa\xe3\x30\xfa\xef\xc5\x70\x6a\x66\x54\x3c\x2e\xb3\xc5\xfc\xa4\x9d\x7f\xc7\x19\x5a\xe3\xbc\x8c\xc0\xa8\x27\xa3\x92\xa8\x2e\xaf\xda\x72\xde\x81\x4e\x49\x08\x39\x41\xc2\x9a\x30\xd3\x3e\x6c\x6e\x5e\xff\x05\x99\x77\x5e\x40\xc2\x2a\x07\x2e\x99\x3e\xa7\x95\x97\x51\xdc\x4d\x4f\x32\x2e\x95\xcc\x81\x8b\xeb\xff\x48\xf6\x70\xb7\xbb\x0b\x9b\x4a\x42\xc9\xe1\xf1\x3b\xf4\xa6\xc3\xf2\xee\x8f\x33\x19\xad\xa7\x5c\x57\xf7\x0f\xeb\x06\xfe\xd4\x8f\x12\x42\x36\x1d\xe7\x73\x72\x0f\xf4\xc9\xd5\xeb\x4b\x6b\x73\x02\x5d\x78\xda\xa4\x2f\xd4\x5c\xa5\x3b\xeb\x49\xa9\xba\x67\x0d\x7f\xae\x7e\x5d\xf6\x2e\xfc\x01\x00\x00\xff\xff\x03\x00\x91\x59\xd1\x64\x4f\x03\x00\x00")
+
+func dbMigrations0005_rollback_on_failureSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0005_rollback_on_failureSql,
+		"db/migrations/0005_rollback_on_failure.sql",
+	)
+}
+
+func dbMigrations0005_rollback_on_failureSql() (*asset, error) {
+	bytes, err := dbMigrations0005_rollback_on_failureSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0005_rollback_on_failure.sql", size: 847, mode: os.FileMode(420), modTime: time.Unix(1792295016, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0006_exact_versionSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6c\xcd\x31\x0e\xc2\x30\x0c\x05\xd0\x3d\xa7\xf8\x3b\xca\x09\xba\x72\x00\x16\xe6\xca\x6d\x9c\x2a\x92\x6b\x47\x89\x03\xe5\xf6\x48\x08\x98\x7a\x80\xa7\x17\x23\x2e\x7b\xd9\x1a\x39\xe3\x5e\x43\x88\x11\xb7\xa2\xca\x09\x0f\x6e\xbd\x98\xf6\x10\x48\x9c\x1b\x9c\x16\x61\x6c\xcd\x46\xed\xa0\x94\xb0\x9a\x8c\x5d\x51\x4d\xca\xfa\x9a\xf9\xa0\xd5\xe7\x2f\xc2\x62\x26\x4c\x8a\xc4\x99\x86\x38\x32\x49\x67\xa8\x39\x74\x88\x4c\x9f\xe7\xff\x5e\xed\xa9\xa7\x4b\x6a\x56\x7f\x4d\xc9\xe0\xa3\x74\xef\xa7\xe1\x14\xde\x00\x00\x00\xff\xff\x03\x00\xfc\x2d\xa8\x64\xc9\x00\x00\x00")
+
+func dbMigrations0006_exact_versionSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0006_exact_versionSql,
+		"db/migrations/0006_exact_version.sql",
+	)
+}
+
+func dbMigrations0006_exact_versionSql() (*asset, error) {
+	bytes, err := dbMigrations0006_exact_versionSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0006_exact_version.sql", size: 201, mode: os.FileMode(420), modTime: time.Unix(1792295076, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0007_rollout_gatesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9c\x91\x4d\x6a\xc3\x30\x10\x85\xf7\x3a\xc5\x5b\x3a\xb4\x06\x37\xd0\x95\x9b\xae\x7a\x82\x42\xd7\x46\x95\x5e\x1c\x11\x59\x12\xa3\x51\x7f\x6e\x5f\x52\x30\x14\x1a\x08\xc9\x7a\xe6\xfb\x66\xe6\x4d\xdf\xe3\x6e\x09\xb3\x58\x25\xde\x8a\x31\x7d\x8f\xd7\x1c\x63\x6e\x8a\xd9\x2a\xab\x31\x36\x2a\x05\x6a\xdf\x23\x31\x4b\x6e\xa5\xc2\x7a\x0f\x97\x63\x5b\x12\x4a\x8e\xc1\x7d\x4f\xa7\xde\xe9\xb7\x3a\x05\x8f\xd6\x82\x87\x70\x4f\x61\x72\xac\x2b\xd6\x05\xbf\x41\x4e\xf0\x8c\x54\xa2\x52\x91\x5a\x8c\xe3\x15\x23\x6a\x73\x8e\xb5\x4e\x85\xe2\x98\xd4\xce\x44\x6a\x0b\x25\xb8\xee\xf1\x7e\xbb\x81\x3b\xd0\x1d\xd1\x5d\x40\x9e\x77\x18\x60\x93\xbf\xa4\x7e\xda\xe1\x61\x18\x36\x57\x2d\x98\xed\x71\xd2\xb0\x10\x1f\x56\xdc\xc1\x4a\xb7\x3d\x09\xcc\xdf\x9c\x5f\xf2\x67\x3a\x9b\xab\x97\x5c\x56\x69\xd8\x83\x5f\xa1\x6a\x3d\xaf\x1f\x6f\xe4\xff\x1d\x79\xa3\x68\xfd\xf5\x68\x7e\x00\x00\x00\xff\xff\x03\x00\x0e\x3c\x90\x90\x42\x02\x00\x00")
+
+func dbMigrations0007_rollout_gatesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0007_rollout_gatesSql,
+		"db/migrations/0007_rollout_gates.sql",
+	)
+}
+
+func dbMigrations0007_rollout_gatesSql() (*asset, error) {
+	bytes, err := dbMigrations0007_rollout_gatesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0007_rollout_gates.sql", size: 578, mode: os.FileMode(420), modTime: time.Unix(1792295162, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0008_channel_promotionsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9c\x90\xc1\x4a\x05\x31\x0c\x45\xf7\xfd\x8a\x2c\xdf\x43\x0a\xe2\xb6\x4b\xfd\x05\xd7\x25\xb6\x19\x27\xd8\x26\x43\x9a\xaa\x9f\x2f\xc8\x38\x82\xcc\xc6\xb7\xce\xbd\x27\x39\x89\x11\xee\x3a\xbf\x1a\x3a\xc1\xf3\x16\x42\x8c\xf0\xb8\xa2\x08\x35\xd8\x4c\xbb\x3a\xab\x8c\x10\xb0\x39\x19\x38\xbe\x34\x82\xb2\xcf\xb1\x56\x28\xda\x66\x97\xdf\x68\x1e\x3a\xad\x50\xde\x33\x99\x2b\xcc\xc9\x15\x8c\x16\x32\x92\x42\xe3\xa8\x5f\xb8\x5e\x41\x05\x2a\x35\x72\x82\x41\x0e\x32\x5b\x4b\xff\xd9\x85\x6f\xd9\xb9\x13\xbc\xa3\x95\x15\xed\xf2\x70\x7f\x4d\xdf\x0e\x87\xd3\x93\x7e\xc8\xf9\xf9\xd5\x74\xfb\x61\xf2\x02\xf4\xc9\xc3\xc7\x19\x3d\xdd\x58\xff\xf3\x88\x14\xbe\x00\x00\x00\xff\xff\x03\x00\xdc\x05\x96\xf9\x6c\x01\x00\x00")
+
+func dbMigrations0008_channel_promotionsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0008_channel_promotionsSql,
+		"db/migrations/0008_channel_promotions.sql",
+	)
+}
+
+func dbMigrations0008_channel_promotionsSql() (*asset, error) {
+	bytes, err := dbMigrations0008_channel_promotionsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0008_channel_promotions.sql", size: 364, mode: os.FileMode(420), modTime: time.Unix(1792295267, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0009_channel_scheduled_changesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x94\x92\x31\x4f\xc3\x30\x10\x85\xe7\xfa\x57\xdc\xd6\x44\x34\x03\x08\xa6\x22\x26\xfe\x01\x62\x8e\x5c\xfb\x35\xb1\xea\x38\xd1\xf9\x5c\x5a\x7e\x3d\x4a\x9b\xa4\x91\x4a\x90\x98\x3c\xdc\x77\xef\x9d\xdf\x5d\x51\xd0\x43\xe3\x2a\xd6\x02\xfa\xec\x94\x2a\x0a\xfa\x30\x35\x6c\xf2\xb0\x64\x6a\x1d\x02\xfc\xe5\xad\x10\x95\x32\x8c\x1e\x14\xbd\xf3\x18\xab\x65\x1c\xf9\xf2\xca\x51\xa6\x56\xce\x52\x4a\xce\x52\xc7\xae\xd1\x7c\xa6\x03\xce\x64\xb1\xd7\xc9\xcb\xa5\x50\x56\x08\xe8\x4d\xcb\xe3\x73\x96\x6f\xd4\xea\x26\x22\x91\xc4\x35\x88\xa2\x9b\x4e\xbe\x29\xb4\x42\x21\x79\xbf\x51\xab\xab\xfb\x1d\x31\xea\x9a\xc4\x8c\x20\xe5\x54\xfb\xad\x77\x77\xa6\xa3\x66\x53\x6b\xce\x9e\x5e\xf2\x89\x20\x53\xc3\x1c\x28\x9b\x61\xaf\x6f\xb4\x5e\x5f\x46\x13\x2d\x29\x92\x0b\x82\x0a\x3c\xd9\x3d\xce\xe5\x3b\x6e\x0d\x62\xbc\x1b\xae\x77\x1e\x62\x1a\x23\x99\x2c\x19\x7b\x30\x82\x41\x9c\x82\xce\x9c\xcd\xa9\x0d\x64\xe1\x21\x20\xa3\xa3\xd1\x16\xbd\xbe\x36\x07\x5d\xe1\x4f\x91\x81\x59\x10\x51\xf9\x76\xda\x9f\x0b\x16\xa7\x1e\x59\x5e\xe1\x6d\xea\x7c\xfb\x8f\xb6\x6b\x56\x1b\x9a\xaf\xb3\x37\x9e\x9f\xd9\x7b\xfb\x15\x94\xb2\xdc\x76\xc3\x21\xb9\x3d\xe1\xe4\xa2\xc4\x65\xe1\xe1\x13\x5b\xf5\x03\x00\x00\xff\xff\x03\x00\x27\x0b\x1d\x1f\xb0\x02\x00\x00")
+
+func dbMigrations0009_channel_scheduled_changesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0009_channel_scheduled_changesSql,
+		"db/migrations/0009_channel_scheduled_changes.sql",
+	)
+}
+
+func dbMigrations0009_channel_scheduled_changesSql() (*asset, error) {
+	bytes, err := dbMigrations0009_channel_scheduled_changesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0009_channel_scheduled_changes.sql", size: 688, mode: os.FileMode(420), modTime: time.Unix(1792295348, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0010_updates_limitsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9c\x91\xb1\x4e\x03\x31\x10\x44\xfb\xfb\x8a\x29\x41\xe8\xa4\xf4\x27\xa8\x68\xf8\x00\xea\xd3\xc6\xde\x0b\x56\xd6\xbb\x96\xbd\x16\xe1\xef\x11\x91\x72\x29\x12\x45\x24\x9d\x9b\x79\xfb\x3c\x33\x8e\x78\xc9\x69\x57\xc9\x19\x9f\x65\x18\xc6\x11\x1f\x1a\xb9\xb0\x46\x56\x47\x30\x0d\xbd\xd6\xbf\x27\x69\x84\xa7\xcc\x11\xd6\x1d\xbd\x44\x72\x6e\x90\x94\x93\xb7\x61\x20\x71\xae\x70\xda\x0a\x63\x57\xad\x97\x06\x8a\x11\xc1\xa4\x67\x45\x31\x49\xe1\x67\xce\x74\x98\xcf\xc4\xf9\xc4\x48\xea\xbc\xe3\x8a\xc8\x0b\x75\x71\x6c\xa0\xe6\xd0\x2e\x82\xf0\xc5\x61\x8f\xa7\xdb\xf9\xb7\x57\x6c\x9e\xa7\xff\x2b\x1c\x7f\x31\x5b\x7f\xd0\xe0\x32\x7e\x8f\xc0\x39\x9d\xa9\xee\xe7\x85\x92\x70\xc4\xd6\x4c\x98\x74\x15\x58\x48\x1a\xaf\x12\xd3\x71\x97\x75\xa7\x77\xfb\xd6\xab\x8d\xc7\x6a\xe5\x74\x2e\x2d\xe0\x43\x6a\xde\x6e\x1e\x9e\xee\xc6\x5c\x6d\xe0\x31\xcc\xe5\x94\xd3\xf0\x0b\x00\x00\xff\xff\x03\x00\xba\x0a\xb3\x17\x90\x02\x00\x00")
+
+func dbMigrations0010_updates_limitsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0010_updates_limitsSql,
+		"db/migrations/0010_updates_limits.sql",
+	)
+}
+
+func dbMigrations0010_updates_limitsSql() (*asset, error) {
+	bytes, err := dbMigrations0010_updates_limitsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0010_updates_limits.sql", size: 656, mode: os.FileMode(420), modTime: time.Unix(1792295438, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0011_instance_labelsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xac\x92\xcf\x6e\x32\x21\x14\xc5\xd7\xc3\x53\x9c\x9d\x4c\xbe\x21\xf9\xd2\xc4\x95\x4d\x57\x4d\x9a\x3e\x40\xd7\x06\xe1\xaa\x44\x84\xe9\x05\xac\xbe\x7d\xc3\xa8\xd3\x89\x99\xee\xba\xe5\xfc\xee\x9f\x73\xb8\x4a\xe1\xdf\xd1\xed\x58\x67\xc2\x47\x2f\x84\x52\x78\x0f\x29\xeb\x60\x08\x5e\x6f\xc8\x27\x21\x0c\x53\x95\xb3\xde\x78\x82\xbb\xa9\xeb\x41\x85\x14\xcd\xf8\xe2\x2c\x4e\x9a\xcd\x5e\xb3\x5c\xfe\x6f\x11\x62\x46\x28\xde\x83\x69\x4b\x4c\xc1\x50\x1a\xab\x21\x9d\x6d\x11\x03\x2c\x79\xca\x04\xa3\x93\xd1\x96\x3a\xd1\x1c\xe8\x32\xdf\xc5\xec\xc9\x1c\x20\xab\xfe\xfc\x82\xc5\xa2\xed\x44\x73\xd2\xbe\xd0\x88\x3f\x2d\x97\x3f\x7c\x27\x9a\x9e\xdd\x51\xf3\x05\xb5\x44\x4e\xb6\xec\xea\x4b\x2b\xda\xd5\x68\xcd\x05\x4b\xe7\xba\xce\xa3\xbb\x03\x5d\x3a\x0c\x53\x2a\xad\x14\xde\x38\x96\xfe\x9a\x0c\x12\x79\x32\x39\xf2\x63\x44\xbb\xca\x5c\x3b\xac\xef\xcc\x10\x94\x45\x22\x76\xda\x63\xb2\xd9\xdf\x5a\xbe\x8e\x76\x16\xa5\x38\x3b\xfb\x03\x03\x91\x7e\xcf\xbf\x04\xf7\x59\x08\xf2\xde\x69\x12\xd6\xf4\x56\x5e\xe3\x57\x10\xc2\x72\xec\xef\x77\xb1\x05\x9d\x5d\xca\x69\xde\xfe\x6d\xc0\x6a\xbe\xe4\x21\xf6\x11\xfe\x06\x00\x00\xff\xff\x03\x00\x31\xb2\x6f\xc1\x9e\x02\x00\x00")
+
+func dbMigrations0011_instance_labelsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0011_instance_labelsSql,
+		"db/migrations/0011_instance_labels.sql",
+	)
+}
+
+func dbMigrations0011_instance_labelsSql() (*asset, error) {
+	bytes, err := dbMigrations0011_instance_labelsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0011_instance_labels.sql", size: 670, mode: os.FileMode(420), modTime: time.Unix(1792295859, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0012_package_min_versionSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x74\xcd\xb1\xaa\x02\x31\x10\x46\xe1\x3e\x4f\xf1\x97\xf7\x22\x69\x84\xad\xb6\xb5\xb5\xb4\x96\x31\x19\xd7\xc1\x24\x13\x26\xc9\xea\xe3\x0b\xa2\x62\x63\x7f\xf8\x8e\xf7\xd8\x64\x59\x8c\x3a\xe3\x50\x9d\xf3\x1e\x7b\x29\x92\x47\x46\xd3\x61\x81\xb1\xb2\x35\xd1\xe2\x1c\xa5\xce\x86\x4e\xa7\xc4\xa8\x14\xae\xb4\x30\x28\x46\x04\x4d\x23\x17\x64\x29\xc7\x57\x8b\x95\x2c\x5c\xc8\xfe\xb6\xd3\xf4\x3f\x3f\xd1\xcf\x64\xa7\xb7\x1f\x56\x34\xad\x6f\x4c\xce\xe0\xbb\xb4\xde\xbe\xd9\xd9\x3d\x00\x00\x00\xff\xff\x03\x00\x75\xff\xb9\x16\xae\x00\x00\x00")
+
+func dbMigrations0012_package_min_versionSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0012_package_min_versionSql,
+		"db/migrations/0012_package_min_version.sql",
+	)
+}
+
+func dbMigrations0012_package_min_versionSql() (*asset, error) {
+	bytes, err := dbMigrations0012_package_min_versionSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0012_package_min_version.sql", size: 174, mode: os.FileMode(420), modTime: time.Unix(1792296000, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0013_package_deltasSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x64\x91\xcd\x6e\xea\x30\x10\x85\xd7\xf8\x29\x66\x47\xa2\x4b\x24\x2e\x02\x36\x54\x5d\xf1\x0a\x5d\x47\x53\x7b\x42\x2c\x1c\x3b\x1d\x8f\x69\xc3\xd3\x57\x49\x89\xa1\x74\x7b\x7e\x26\xf1\x77\xaa\x0a\xfe\x75\xf6\xc4\x28\x04\x6f\xbd\x52\x55\x05\x47\x72\x82\xd0\xe3\xe0\x02\x9a\xa8\x94\x66\x1a\x5d\xc1\x77\x47\xd0\xa3\x3e\xe3\x89\x6a\x33\x85\x0a\xb5\xb0\x06\x52\xb2\x06\x7a\xb6\x1d\xf2\x00\x67\x1a\xc0\x50\x83\xc9\xc9\x64\xd4\x27\xf2\x34\x9e\xaf\x2f\xdb\xa2\x5c\xa9\x45\xc3\xa1\xab\x2f\xc4\xd1\x06\x0f\x17\x64\xdd\x22\x17\x9b\xdd\xae\x04\x1f\x04\x7c\x72\x0e\x74\x4b\xfa\x0c\xc5\xaf\xe4\xcb\x2b\x2c\x97\x63\x3f\xb1\x7b\xa8\xed\xff\xd6\xc6\x40\x4e\x37\xd6\x91\xc7\x8e\x72\xe5\xff\x7a\x3d\xea\xd1\x5e\xef\xda\x66\x92\x5a\x8c\x6d\x96\xf6\xdb\x29\xd5\xe2\x66\xb7\x7f\x12\x7f\x78\x98\x5a\x22\x88\xed\x28\x0a\x76\xbd\x5c\xf3\xa3\x75\x62\x26\x2f\x75\xf6\xf2\x0f\xae\xd4\x62\xc6\x37\x53\x9b\x2d\x60\x6a\x88\xc9\x6b\x8a\x33\x62\x28\xac\x29\x21\x78\x30\xe4\x48\x08\x34\x46\x8d\x86\x46\x02\xde\x7e\x24\x82\xe2\x7e\x6c\x05\x8f\xac\x4a\x55\x1e\xa6\x25\xf3\xb2\xc7\xf0\xe9\x95\x32\x1c\xfa\xdb\x8c\xb6\x01\xfa\xb2\x51\xe2\xd3\xa0\xb7\x8f\x1c\xd4\x37\x00\x00\x00\xff\xff\x03\x00\xcf\x15\x30\x95\x18\x02\x00\x00")
+
+func dbMigrations0013_package_deltasSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0013_package_deltasSql,
+		"db/migrations/0013_package_deltas.sql",
+	)
+}
+
+func dbMigrations0013_package_deltasSql() (*asset, error) {
+	bytes, err := dbMigrations0013_package_deltasSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0013_package_deltas.sql", size: 536, mode: os.FileMode(420), modTime: time.Unix(1792296073, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0014_package_filesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x5c\x90\xc1\x6e\xf2\x30\x10\x84\xcf\xf1\x53\xcc\x8d\x44\x7f\x22\xf1\xa3\x96\x0b\x55\x4f\xbd\xf6\xd8\x33\x5a\xec\x0d\x59\x61\xec\xb0\xb6\xdb\xd2\xa7\xaf\x82\x20\xb4\xbd\xce\x7c\x1a\xed\x7e\x5d\x87\x7f\x47\xd9\x2b\x65\xc6\xdb\x68\x4c\xd7\xe1\xb5\xf8\x2c\x5d\x2f\x9e\x31\x92\x3d\xd0\x9e\x93\x31\x56\x79\x42\x32\xed\xee\xf1\xf6\xc2\xd4\xa6\x12\x87\xc4\x2a\xe4\x31\xaa\x1c\x49\xcf\x38\xf0\xb9\x35\xd5\x18\x93\x64\x89\x01\x12\x32\xef\x59\x11\x62\x46\x28\xde\xb7\xa6\x0a\x74\x64\xbc\x93\xda\x81\xb4\xfe\xbf\x5c\x36\x73\x09\x3b\xb0\x3d\xa0\xbe\x10\x4f\xcf\x58\x2c\x9a\xd6\x54\x49\xbe\xee\xfc\x6a\x39\x45\x03\xa5\x61\x8e\xd6\x0f\xb7\x68\x9b\x06\x5a\x3d\xae\xff\x34\xca\xa7\x22\xca\x0e\xbb\x18\x3d\x53\x80\xe3\x9e\x8a\xcf\xc8\x5a\xf8\xe7\x61\xb7\xe7\xc4\xa1\x14\x71\x73\x05\xe5\x9e\x95\x83\xe5\x74\x13\x80\x5a\x5c\x83\x38\x6d\x79\xce\x0c\x4b\xc9\x92\xe3\xd6\x54\x25\xc8\xa9\x30\xea\xfb\x58\x8b\xe9\x9f\xc6\x34\x9b\x8b\xe4\x59\xfa\x4b\xfc\x08\xc6\x38\x8d\xe3\x55\xae\xf4\xe0\x4f\x49\x39\xfd\xd6\x7c\xdd\xde\x98\x6f\x00\x00\x00\xff\xff\x03\x00\x51\x7b\xb5\x86\xb2\x01\x00\x00")
+
+func dbMigrations0014_package_filesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0014_package_filesSql,
+		"db/migrations/0014_package_files.sql",
+	)
+}
+
+func dbMigrations0014_package_filesSql() (*asset, error) {
+	bytes, err := dbMigrations0014_package_filesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0014_package_files.sql", size: 434, mode: os.FileMode(420), modTime: time.Unix(1792296171, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0015_package_archesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6c\x92\x31\x73\xfa\x30\x0c\xc5\xe7\xf8\x53\x68\x23\xb9\x7f\x72\xc7\x9f\x03\x16\x7a\x9d\xba\x76\xec\x9c\x53\x6d\x85\xf8\x70\x9c\x54\x96\x69\xe1\xd3\xf7\x9c\x42\x02\xb4\x8b\x07\xe9\xf7\x9e\x65\x3d\x57\x15\xfc\xeb\xec\x9e\x51\x08\xde\x06\xa5\xaa\x0a\x5e\xa3\x13\x5b\x21\xeb\xd6\x0a\x69\x89\x4c\x30\xa0\x3e\xe0\x9e\x82\x52\xe8\x84\x18\x04\xdf\xdd\x54\x05\x34\x06\x74\xef\x62\xe7\x21\xa9\xe0\x98\x4e\xe4\x7c\xb5\x2c\x76\x4a\x69\xa6\x64\x7e\x27\xa9\x47\x2e\x57\x99\x35\x10\xa3\x35\x30\xb0\xed\x90\x4f\x70\xa0\x13\x18\x6a\x30\x3a\x19\x1b\xf5\x9e\x3c\xa5\xe1\xea\xe3\x3a\x2f\x4a\x95\x3d\x5e\x00\xbe\x17\xf0\xd1\x39\xd0\x2d\xe9\x03\xe4\x23\xf0\xf4\x0c\x8b\x45\xc2\x23\xbb\x99\xde\x6c\x7f\xe3\x09\x98\xe8\xc6\x3a\xf2\xd8\xd1\x24\xf9\xbf\x5c\xa6\x7a\xb0\x67\xba\xbd\xb4\x54\x59\x8b\x61\x9e\x63\xbb\x1e\xa9\x16\x57\x9b\xed\x43\xf1\xe7\xf5\xa6\x96\x00\x62\x3b\x0a\x82\xdd\x20\xe7\xe9\x8d\x3a\x32\x93\x97\x7a\xea\x4d\x03\x96\x2a\xbb\x2e\xeb\xba\xa4\x6b\x0b\x98\x1a\x62\xf2\x9a\xc2\x94\x41\x6e\x4d\x01\xbd\x07\x43\x8e\x84\x40\x63\xd0\x68\x28\x6d\xc0\xdb\x8f\x48\x90\xcf\x66\xe5\x98\x52\xa1\x52\x38\xb7\xf1\xbf\xf4\x9f\x5e\x29\xc3\xfd\x70\x09\xcb\x36\x40\x5f\x36\x48\xb8\x8f\xed\xe2\xbd\xfb\xfb\x33\x8c\xfa\xcb\x6f\x98\x0d\x92\x70\xa7\xbe\x01\x00\x00\xff\xff\x03\x00\x5c\xa3\xcd\xd1\x6d\x02\x00\x00")
+
+func dbMigrations0015_package_archesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0015_package_archesSql,
+		"db/migrations/0015_package_arches.sql",
+	)
+}
+
+func dbMigrations0015_package_archesSql() (*asset, error) {
+	bytes, err := dbMigrations0015_package_archesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0015_package_arches.sql", size: 621, mode: os.FileMode(420), modTime: time.Unix(1792296291, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0016_version_sort_keySql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x94\x90\x41\x4e\xc3\x40\x0c\x45\xf7\x39\xc5\x57\x57\xad\x20\x27\xe8\x12\xae\x00\xdb\xc8\x9d\x71\x83\x95\xe9\x78\x34\x76\x68\x72\x7b\x34\x54\x45\xa0\xb2\x68\x97\x96\xa5\xf7\xff\x7f\x7d\x8f\xa7\x93\x8c\x95\x9c\xf1\x56\xba\xae\xef\xf1\xce\xd5\x44\xb3\xc1\xb4\x3a\x26\x5e\x0d\xdb\xa2\x65\x4e\xe4\x1c\x71\x58\xe1\x1f\x0c\x2a\x02\xcd\x30\xa7\xea\x73\xc1\x51\x2b\x78\x11\x73\xc9\x23\xaa\x9e\x6d\xd7\x75\x94\x9c\x2b\x9c\x0e\x89\x51\x28\x4c\x34\x32\x28\x46\x04\x4d\xf3\x29\xe3\xf3\x12\x33\xb4\x94\x61\xe2\x15\xce\x8b\xb7\x67\xcb\xc1\xe6\x65\xb3\xff\x43\x90\x6c\x4e\x39\xf0\x40\xa5\x24\x09\xe4\xa2\xf9\x41\x5c\x17\x2a\xb7\x43\x72\xe4\xa5\xb5\xbf\xb6\xda\xfe\x62\x0e\x12\x9f\x6f\x60\xbb\xfd\xb7\x99\x1f\x53\xaf\x7a\xce\xff\x0f\x8c\x55\xcb\xb5\x92\x1c\x2f\x4e\xec\x86\x77\xc7\xb4\x7b\x41\x5f\x00\x00\x00\xff\xff\x03\x00\x77\xc0\x04\xb6\xc1\x01\x00\x00")
+
+func dbMigrations0016_version_sort_keySqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0016_version_sort_keySql,
+		"db/migrations/0016_version_sort_key.sql",
+	)
+}
+
+func dbMigrations0016_version_sort_keySql() (*asset, error) {
+	bytes, err := dbMigrations0016_version_sort_keySqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0016_version_sort_key.sql", size: 449, mode: os.FileMode(420), modTime: time.Unix(1792296536, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0017_version_schemeSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x74\xcd\x31\x0e\xc2\x30\x0c\x05\xd0\x3d\xa7\xf8\x5b\x41\xa8\x12\x62\xed\xca\x15\x98\x91\x49\x0c\x8d\xe4\xd8\x91\xeb\x86\xeb\xb3\x32\xc0\x09\xde\x3c\xe3\xd4\xea\xcb\x29\x18\xb7\x9e\x12\x49\xb0\x23\xe8\x21\x0c\xea\x5d\x6a\xa6\xa8\xa6\xa0\x52\x90\x4d\xf6\xa6\x18\xec\x5b\x35\xbd\x6f\x79\xe5\xc6\x18\xe4\x79\x25\x3f\x5c\xce\x47\xa8\x05\x74\x17\x41\xe1\x27\xed\x12\x98\x36\x6e\x83\x7d\x5a\x52\xfa\x96\xae\xf6\xd6\xff\x56\x71\xeb\xbf\xb1\x25\x7d\x00\x00\x00\xff\xff\x03\x00\x7f\x39\x73\x7c\xb0\x00\x00\x00")
+
+func dbMigrations0017_version_schemeSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0017_version_schemeSql,
+		"db/migrations/0017_version_scheme.sql",
+	)
+}
+
+func dbMigrations0017_version_schemeSql() (*asset, error) {
+	bytes, err := dbMigrations0017_version_schemeSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0017_version_scheme.sql", size: 176, mode: os.FileMode(420), modTime: time.Unix(1792296919, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0018_package_retractionSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x8f\x3b\x0e\xc3\x20\x10\x44\x7b\x4e\xb1\x7d\xe4\x13\xd0\xe6\x0a\xa9\xad\x35\xac\x2d\x94\x65\x41\x30\x24\x4a\x4e\x9f\xd6\xf9\x49\xee\xe7\x8d\xde\x9b\x26\x3a\xe5\xb4\x35\x86\xd0\xa5\x3a\xc7\x0a\x69\x04\x5e\x54\xa8\x72\xb8\xf2\x26\xc4\x31\x52\x28\x3a\xb2\x51\x13\x34\x0e\x90\x48\x4b\x29\x2a\x6c\x64\x05\x64\x43\x95\xa2\xac\x3c\x14\xb4\xb2\x76\xf1\x87\x8f\x66\x74\x42\xca\xd2\xc1\xb9\xe2\xf9\x0e\x72\x40\xba\x25\x3c\xf6\x64\xb2\x0e\xb6\x20\x7d\x0e\x65\x18\x28\x19\x64\x93\xe6\x9d\xdb\xb7\x9c\xcb\xdd\x7e\xd7\xc4\x56\xea\x97\x85\x3f\x3e\x9d\xd1\xff\x48\xee\xe7\x1f\x96\xde\xbd\x00\x00\x00\xff\xff\x03\x00\xa6\xe4\x1a\xc9\x69\x01\x00\x00")
+
+func dbMigrations0018_package_retractionSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0018_package_retractionSql,
+		"db/migrations/0018_package_retraction.sql",
+	)
+}
+
+func dbMigrations0018_package_retractionSql() (*asset, error) {
+	bytes, err := dbMigrations0018_package_retractionSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0018_package_retraction.sql", size: 361, mode: os.FileMode(420), modTime: time.Unix(1792297116, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0019_critical_updatesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xac\x90\x41\x4a\xc6\x40\x0c\x46\xf7\x73\x8a\x2c\x15\xf9\xa1\xfb\xa2\x2b\xaf\xe0\xba\xa4\x99\x74\x1c\x9a\x26\x43\x9a\x41\xbd\xbd\x2b\xa5\x6a\x05\x85\x7f\x9f\xbc\x8f\xf7\x2e\x17\xb8\xdb\x6a\x71\x0c\x86\xa7\x96\x12\x4a\xb0\x43\xe0\x2c\x0c\x0d\x69\xc5\xc2\x80\x39\x03\x99\xf4\x4d\x81\xbc\x46\x25\x14\x98\xcd\x84\x51\x41\x2d\x40\xbb\x08\x64\x5e\xb0\x4b\xc0\x82\xb2\xf3\xf8\x85\x53\xdc\x7a\xdb\x8f\x98\x66\x52\xe9\x6d\xfa\xa0\x4d\xbd\x65\x0c\xde\xaf\x4c\xdd\xf0\x75\x22\x53\xea\xee\xac\xf1\x39\x52\x35\xb8\xb0\xff\x1c\x19\x80\x9e\x99\x56\xb8\xf9\x23\xe8\xe1\x1e\x86\xdb\x31\xa5\x63\xc3\x47\x7b\xd1\xf3\x8a\xd9\xad\x7d\xcf\x78\x6a\x74\x3c\xfc\x25\xd4\xbf\xff\xce\x0d\xc6\xf4\x0e\x00\x00\xff\xff\x03\x00\x06\x0f\x4f\xe1\xff\x01\x00\x00")
+
+func dbMigrations0019_critical_updatesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0019_critical_updatesSql,
+		"db/migrations/0019_critical_updates.sql",
+	)
+}
+
+func dbMigrations0019_critical_updatesSql() (*asset, error) {
+	bytes, err := dbMigrations0019_critical_updatesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0019_critical_updates.sql", size: 511, mode: os.FileMode(420), modTime: time.Unix(1792297253, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0020_changes_notificationsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xcc\x95\xc1\x6a\x1c\x31\x0c\x86\xcf\xf1\x53\xe8\xb0\x90\x84\x36\x4f\x30\xa7\x84\x2e\xbd\xb4\xbd\x34\x3d\x1b\xad\xad\xf1\x9a\x68\x65\x57\x96\xd9\xf6\xed\xcb\x64\x76\xa0\x4b\x4b\x0f\x85\x80\x6f\x33\xc2\x92\xbf\x5f\x3f\xe6\x7f\x78\x80\x77\xa7\x9c\x14\x8d\xe0\x5b\x75\xee\xf7\xff\xaf\x86\x46\x27\x12\x7b\xa2\x94\xc5\x05\xa5\xa5\x5a\x14\x94\x2a\x63\x20\x98\xbb\x04\xcb\x45\x40\x8a\xe5\xf9\xa7\x0f\x47\x94\x44\xed\xee\x1e\x94\xac\xab\x34\x30\xcd\x29\x91\x02\x36\xd8\xed\xdc\xe1\x75\xce\x4d\x25\x9d\x8b\x9e\xa0\x26\xbf\x36\xde\xdd\x86\xa2\xa4\x85\x99\x74\x1b\x72\xfb\x1e\x9e\x3f\xfa\xe7\xc7\xa7\x4f\x7b\xff\xe5\xf1\xf3\xfe\x7e\x72\x37\xeb\x54\x90\xce\x3c\x39\x92\x38\xb9\xdd\x0e\x18\x25\x75\x4c\x04\x95\x6b\x6a\xdf\x79\xfa\xbb\x86\xbd\x44\xb7\x49\xd8\xa8\xae\xb1\x01\x67\x23\x85\x2c\x8d\xd4\x16\x99\xbd\xc6\x8b\xe0\x48\x4c\xeb\x97\x69\x97\xf0\x5a\x15\x48\x5a\x7a\x6d\x30\x17\x05\xc2\x70\x84\xb6\xdd\x05\xf4\x83\x42\x37\x82\xaa\x25\x50\xec\x4a\x7f\x6c\x68\x7a\x0b\x16\xbf\x9e\xf2\xe7\x2c\xb1\x9c\x47\x02\x5b\xbc\x2d\xdd\x7c\xb3\xc5\xa9\x81\xc0\x18\x0f\xc4\xbe\x11\x53\xb0\xa2\x63\x90\x2d\x7d\x42\x3c\x06\x4c\xc5\xf0\x32\x8c\x67\x17\x18\x7f\xd9\x90\x3f\x30\x86\x17\xce\xcd\xc6\xc2\x8b\xc4\x86\x63\x21\xcd\x99\x07\xf3\x10\x35\x1c\x07\x79\x6f\x45\xa9\x34\x8f\x6b\x96\xfd\x27\xd2\x55\xea\x7c\x28\x67\x71\x2e\x6a\xa9\xff\xc8\xc8\x80\x2d\x60\xa4\xc9\xfd\x02\x00\x00\xff\xff\x03\x00\xa6\xb9\xc0\x31\x84\x07\x00\x00")
+
+func dbMigrations0020_changes_notificationsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0020_changes_notificationsSql,
+		"db/migrations/0020_changes_notifications.sql",
+	)
+}
+
+func dbMigrations0020_changes_notificationsSql() (*asset, error) {
+	bytes, err := dbMigrations0020_changes_notificationsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0020_changes_notifications.sql", size: 1924, mode: os.FileMode(420), modTime: time.Unix(1792297486, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0021_version_sort_key_backfillSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x94\x90\x41\x6e\xc3\x20\x10\x45\xf7\x9c\xe2\x2f\x1d\xb5\x3e\x81\xb7\x3d\x41\xa5\xae\x11\x85\xb1\x3d\x32\x01\xc4\x8c\x6b\xfb\xf6\x15\x55\x22\xa5\x55\xa4\x34\x1b\x56\xf3\xfe\xfb\xfc\xbe\xc7\xcb\x99\xa7\xea\x94\xf0\x51\x8c\xe9\x7b\xbc\xe7\x4d\xb0\xb1\xce\x79\x55\x7c\x51\x15\xce\x09\x92\xab\x62\xa1\x03\xdd\x2a\x14\xa0\x19\x7e\x26\xbf\xb4\xd7\x95\x78\xa0\x9d\xa8\xab\xba\x16\xf0\x08\x9d\xa9\x12\x58\x5a\x9c\x4b\x87\xce\x9c\x26\x44\x1a\xb5\x81\x9f\xce\x2f\x23\xc7\x78\x32\xc6\x57\x6a\x62\x4e\x81\x76\x14\xe7\x17\x37\x91\xbd\x28\x6d\x53\xda\x85\x0e\x7b\x66\x11\x4e\x93\xe5\xb0\x37\xcf\xe5\x0e\x1d\x87\x13\xb6\x1f\xd3\x5f\x04\x2c\x48\x6b\x8c\xc3\x6f\x03\x27\x51\x97\x3c\x59\x57\x4a\x64\xef\xb4\x31\x8f\x74\xf7\x20\x74\xb7\x09\x1c\x5e\xaf\x0d\x1e\x17\x32\xb7\x8b\xbf\xe5\x2d\x19\x13\x6a\x2e\xd7\x86\x23\x68\x67\x51\xf9\xd7\x1a\xc3\x7d\xf4\xe9\x6f\x0e\xe6\x1b\x00\x00\xff\xff\x03\x00\xae\x3d\x33\xfa\x07\x02\x00\x00")
+
+func dbMigrations0021_version_sort_key_backfillSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0021_version_sort_key_backfillSql,
+		"db/migrations/0021_version_sort_key_backfill.sql",
+	)
+}
+
+func dbMigrations0021_version_sort_key_backfillSql() (*asset, error) {
+	bytes, err := dbMigrations0021_version_sort_key_backfillSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0021_version_sort_key_backfill.sql", size: 519, mode: os.FileMode(420), modTime: time.Unix(1792299148, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0022_application_changes_notificationsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x5c\x8f\x41\x4e\x03\x31\x0c\x45\xf7\x39\xc5\xdf\x01\x42\x73\x82\x6e\x39\x02\xac\x51\xe4\xf9\x93\x44\x9a\x89\x23\xdb\x81\x72\x7b\xd4\x22\x55\xb4\x3b\xcb\xb2\xfc\xde\x5b\x16\xbc\x1e\xad\x58\x0e\xe2\x63\xa4\xb4\x2c\x78\xaf\x44\x31\x9d\xc3\x21\x59\x2a\xd1\xba\xec\x73\xa5\x23\x2a\x91\xc7\xd8\x9b\xe4\x68\xda\x9f\x1c\x5f\x34\x6f\xda\xe1\x52\x79\x30\x25\x31\x5e\x3e\x85\xb5\x52\x68\xe8\x1a\x6d\xfb\xf9\x94\x9a\x7b\xa1\x23\x6f\x41\x43\xeb\x4e\x0b\xa8\x61\x8e\xf5\x72\xad\x86\x95\x3b\xff\xa6\xb0\xd9\xe5\xba\xed\xff\x59\xd8\xd4\xc0\x2c\x15\x1e\x39\x78\xb0\x07\x78\xa6\xcc\x20\x86\xa9\x70\x9d\xc6\x07\xde\xf3\xcb\xe9\x1a\x74\x0b\x7c\xd3\xef\x9e\xd2\x6a\x3a\x6e\x86\x6d\x03\xcf\xcd\xc3\x1f\x5d\xef\xf1\xa7\xf4\x0b\x00\x00\xff\xff\x03\x00\x36\xab\x1d\xb9\x29\x01\x00\x00")
+
+func dbMigrations0022_application_changes_notificationsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0022_application_changes_notificationsSql,
+		"db/migrations/0022_application_changes_notifications.sql",
+	)
+}
+
+func dbMigrations0022_application_changes_notificationsSql() (*asset, error) {
+	bytes, err := dbMigrations0022_application_changes_notificationsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0022_application_changes_notifications.sql", size: 297, mode: os.FileMode(420), modTime: time.Unix(1792299242, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0023_instance_labels_per_appSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xa4\x52\x41\x6e\xdb\x30\x10\xbc\xf3\x15\x73\x8c\x51\xdb\x1f\x10\xda\x53\x2f\x7d\x40\xcf\xc6\x8a\xdc\x24\xdb\xac\x48\x62\x49\xd5\xd0\xef\x0b\xd1\x6e\x62\x13\xae\x53\xc0\x17\x09\xdc\xc1\xec\x90\x33\xb3\xdb\xe1\xcb\x24\x2f\x46\x95\xf1\x33\x3b\xb7\xdb\xe1\x47\x2c\x95\xa2\x67\x28\x8d\xac\x05\x64\x0c\xe3\x9c\xac\x72\x40\x66\x03\xe5\xbc\x45\x49\xa8\xaf\xbc\x34\xb4\xd4\x64\x1f\x18\xa8\xe0\xc8\xaa\xce\x91\x56\x36\x54\x1a\x95\x21\xe7\xad\x87\xb6\x15\xc1\x52\x86\x4f\xb1\x54\x23\x89\xb5\x83\x0f\xf9\x8d\x97\xe1\x1e\x9f\x42\x80\x4f\x3a\x4f\x71\x95\x54\xf1\x54\x25\xc5\x83\x04\xcc\xb3\x04\x18\x3f\xb3\x71\xf4\x5c\x2e\x61\x3c\x49\xd8\x20\x45\x04\x56\xae\x0c\x4f\xc5\x53\xe0\xc1\x39\x89\x85\x6d\xbd\x45\x4d\xbd\xd2\xd3\xfb\x59\xc2\xb6\x13\xdb\xe2\x8d\x97\x2d\x7e\x93\xce\xbc\x71\x85\x95\x7d\x85\xee\xaf\x18\x42\xfb\x9e\xa4\xfb\x46\xd3\x7d\x23\xba\x67\x4b\x53\xaf\xaa\xee\x57\x92\xf8\x31\xbd\x7c\x85\x10\xda\xf7\x52\x07\x5f\xaf\x75\xdd\xf1\x95\x8d\xa1\x9d\x36\xa4\x20\xce\xaa\x83\x73\x67\x0f\x6e\x89\x9f\xb8\xff\x64\xde\x8b\xa5\x41\xb7\x83\x29\x5c\x11\x53\x3d\x6f\xf9\x24\xdb\x6c\x32\x91\x2d\xab\xbf\x9f\x27\xb0\x19\x5a\x71\xdf\x8b\xfc\x3d\x1d\xe3\xe3\xed\xbb\x63\x10\x61\x2e\x12\x5f\xfa\xf1\xf8\xd7\xb9\x2e\x99\xf1\xea\x4c\x31\x80\xd6\x06\x34\x64\xfd\x9f\x26\x9d\x5d\xdf\x30\x76\xa3\xe1\x3f\x9e\x74\xc3\xf8\x07\xbc\x3e\x79\xfb\x07\x00\x00\xff\xff\x03\x00\x59\xf5\xde\xff\x24\x04\x00\x00")
+
+func dbMigrations0023_instance_labels_per_appSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0023_instance_labels_per_appSql,
+		"db/migrations/0023_instance_labels_per_app.sql",
+	)
+}
+
+func dbMigrations0023_instance_labels_per_appSql() (*asset, error) {
+	bytes, err := dbMigrations0023_instance_labels_per_appSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0023_instance_labels_per_app.sql", size: 1060, mode: os.FileMode(420), modTime: time.Unix(1792300821, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0024_package_payload_archesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xac\x92\xcd\xae\xda\x30\x10\x85\xf7\x7e\x8a\xd9\x01\x6a\x22\x55\xdd\xa6\xea\x8a\x57\xe8\x3a\x1a\xec\x09\xb1\x70\xc6\xa9\x3d\xe1\xe7\xed\x2b\x9b\xbf\x90\x02\xe9\xbd\xba\x1b\x24\x3c\x9c\x61\xce\x77\x4e\x59\xc2\xb7\xce\x6e\x03\x0a\xc1\xef\x5e\xa9\xb2\x84\x35\x39\xc1\x08\xc8\x06\x1a\xeb\x28\xc2\x86\x9c\xe7\x2d\x88\x07\x69\x09\x3a\xb4\x0c\x3d\x9e\x9c\x47\x03\xbe\xc9\x6f\x3d\xea\x1d\x6e\xa9\x00\x1f\xd2\xcf\x3c\x53\x9a\x58\x89\x69\x1f\x06\xdd\x5a\x21\x2d\x43\xa0\xab\x30\xc2\xa1\x25\xce\xda\x34\x06\x1b\x21\x92\x28\x85\x4e\x28\x80\xe0\xc6\xdd\x96\xd6\x26\xdd\x03\x68\x0c\x68\xef\x86\x8e\xcf\x8a\x7d\xfa\xc4\xb0\xfc\xf1\x7d\x05\xba\x25\xbd\x83\x65\x7e\xff\xf9\x0b\x16\x8b\x55\xf5\x66\x93\x09\xbe\x07\xed\x39\x4a\x40\xcb\xf2\x38\xad\xaf\xdf\xac\xa9\x9b\xe0\xbb\x7a\x4f\x21\x5a\xcf\xf5\x8e\x4e\x95\xd2\x81\x50\x08\x06\xb6\x7f\x06\x02\xcb\x86\x8e\xaf\xe5\xe9\x9c\x7f\x76\x80\xe7\xc9\x39\xcb\xbb\xa4\x00\xed\xd1\x51\xd4\x94\xbd\x14\xc9\x49\x01\xe3\x15\xab\xea\x39\xa3\x14\xd4\x97\x20\xca\x8b\x5e\x11\x4a\xc3\xb1\x43\xc6\x8e\xe6\xc1\x4c\x55\x99\xcb\x55\x3a\xe6\x91\xff\x7b\x16\x47\x52\x26\x0c\xe3\xe6\xae\xfd\x81\x95\x32\xe4\x48\x28\xf3\x9a\x30\x3e\xb4\x14\xee\x4d\x63\x2f\xc0\x83\x73\x95\xca\x46\xcf\xd7\xda\x06\xe8\x68\xa3\xc4\x0f\x06\xfa\x1f\x4d\xbb\x25\x52\xcd\xf4\xfb\x82\xef\x01\xc1\x34\xfd\x67\x26\x33\xb8\x4f\x79\x7c\x9b\xcd\x7c\x43\x66\x9c\xdd\x5a\xf9\xcc\xd8\x25\xc7\xbf\x00\x00\x00\xff\xff\x03\x00\x11\x72\x56\xc8\x81\x04\x00\x00")
+
+func dbMigrations0024_package_payload_archesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0024_package_payload_archesSql,
+		"db/migrations/0024_package_payload_arches.sql",
+	)
+}
+
+func dbMigrations0024_package_payload_archesSql() (*asset, error) {
+	bytes, err := dbMigrations0024_package_payload_archesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0024_package_payload_arches.sql", size: 1153, mode: os.FileMode(420), modTime: time.Unix(1792300936, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dbMigrations0025_min_version_sort_keySql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x90\x31\x6e\xeb\x40\x0c\x44\xfb\x3d\xc5\xc0\xd5\xff\x48\x74\x02\x95\xc9\x0d\x82\xd4\x02\xad\xa5\x65\xc2\xbb\x5c\x61\x49\xc5\xca\xed\x83\x15\x1c\x25\x85\x8a\x94\x04\x38\xf3\x1e\xa6\xeb\xf0\x94\x65\xaa\xe4\x8c\xf7\x39\x84\xae\xc3\x5b\xa9\x8e\x1b\x7f\xa2\x5c\xe0\x57\xc6\x4c\xe3\x8d\x26\x36\x64\x51\xc9\x4b\xc6\x07\x57\x93\xa2\xcf\xb0\x02\xbf\x92\x6f\x5f\xe6\x3c\xcf\xa2\x13\xcc\x8b\x72\xeb\xd9\x73\x23\x29\xce\x0c\xe3\xc4\xa3\x73\x84\xe8\x96\x88\xe4\x74\x26\xe3\x10\x28\x39\x57\x38\x9d\xd3\x4e\x03\xc5\x88\xb1\xa4\x25\x6b\xe3\x0e\x0f\xe6\x60\xa5\xfa\xd0\xe4\x9c\x57\x6f\x0f\xa9\x99\x9f\x5e\x4e\x7d\x08\x63\xe5\x76\x88\x46\x5e\xbf\x7b\x86\xa3\xf0\x90\xc5\x4c\x74\x1a\x24\xae\x28\xba\x33\xff\x49\xfc\x8f\xfb\x95\x2b\xff\x66\x42\x0c\x5a\x1c\xba\xa4\x04\xd2\x78\xec\xd3\x9e\x96\x94\xfa\x6d\xc2\x7d\xd2\xd7\x72\xd7\x10\x62\x2d\xf3\x43\x4b\x2e\xe0\x55\xcc\xed\xcf\x82\xfd\xe1\x3c\x5b\xe5\x63\x9f\x9f\xce\xa3\xae\x3e\x7c\x01\x00\x00\xff\xff\x03\x00\xcb\x7a\x4b\x39\xe4\x01\x00\x00")
+
+func dbMigrations0025_min_version_sort_keySqlBytes() ([]byte, error) {
+	return bindataRead(
+		_dbMigrations0025_min_version_sort_keySql,
+		"db/migrations/0025_min_version_sort_key.sql",
+	)
+}
+
+func dbMigrations0025_min_version_sort_keySql() (*asset, error) {
+	bytes, err := dbMigrations0025_min_version_sort_keySqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "db/migrations/0025_min_version_sort_key.sql", size: 484, mode: os.FileMode(420), modTime: time.Unix(1792301262, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -163,6 +667,30 @@ func AssetNames() []string {
 var _bindata = map[string]func() (*asset, error){
 	"db/drop_all_tables.sql": dbDrop_all_tablesSql,
 	"db/migrations/0001_initial.sql": dbMigrations0001_initialSql,
+	"db/migrations/0002_rollout_percentage.sql": dbMigrations0002_rollout_percentageSql,
+	"db/migrations/0003_update_windows.sql": dbMigrations0003_update_windowsSql,
+	"db/migrations/0004_error_budget.sql": dbMigrations0004_error_budgetSql,
+	"db/migrations/0005_rollback_on_failure.sql": dbMigrations0005_rollback_on_failureSql,
+	"db/migrations/0006_exact_version.sql": dbMigrations0006_exact_versionSql,
+	"db/migrations/0007_rollout_gates.sql": dbMigrations0007_rollout_gatesSql,
+	"db/migrations/0008_channel_promotions.sql": dbMigrations0008_channel_promotionsSql,
+	"db/migrations/0009_channel_scheduled_changes.sql": dbMigrations0009_channel_scheduled_changesSql,
+	"db/migrations/0010_updates_limits.sql": dbMigrations0010_updates_limitsSql,
+	"db/migrations/0011_instance_labels.sql": dbMigrations0011_instance_labelsSql,
+	"db/migrations/0012_package_min_version.sql": dbMigrations0012_package_min_versionSql,
+	"db/migrations/0013_package_deltas.sql": dbMigrations0013_package_deltasSql,
+	"db/migrations/0014_package_files.sql": dbMigrations0014_package_filesSql,
+	"db/migrations/0015_package_arches.sql": dbMigrations0015_package_archesSql,
+	"db/migrations/0016_version_sort_key.sql": dbMigrations0016_version_sort_keySql,
+	"db/migrations/0017_version_scheme.sql": dbMigrations0017_version_schemeSql,
+	"db/migrations/0018_package_retraction.sql": dbMigrations0018_package_retractionSql,
+	"db/migrations/0019_critical_updates.sql": dbMigrations0019_critical_updatesSql,
+	"db/migrations/0020_changes_notifications.sql": dbMigrations0020_changes_notificationsSql,
+	"db/migrations/0021_version_sort_key_backfill.sql": dbMigrations0021_version_sort_key_backfillSql,
+	"db/migrations/0022_application_changes_notifications.sql": dbMigrations0022_application_changes_notificationsSql,
+	"db/migrations/0023_instance_labels_per_app.sql": dbMigrations0023_instance_labels_per_appSql,
+	"db/migrations/0024_package_payload_arches.sql": dbMigrations0024_package_payload_archesSql,
+	"db/migrations/0025_min_version_sort_key.sql": dbMigrations0025_min_version_sort_keySql,
 }
 
 // AssetDir returns the file names below a certain
@@ -209,6 +737,30 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"drop_all_tables.sql": &bintree{dbDrop_all_tablesSql, map[string]*bintree{}},
 		"migrations": &bintree{nil, map[string]*bintree{
 			"0001_initial.sql": &bintree{dbMigrations0001_initialSql, map[string]*bintree{}},
+			"0002_rollout_percentage.sql": &bintree{dbMigrations0002_rollout_percentageSql, map[string]*bintree{}},
+			"0003_update_windows.sql": &bintree{dbMigrations0003_update_windowsSql, map[string]*bintree{}},
+			"0004_error_budget.sql": &bintree{dbMigrations0004_error_budgetSql, map[string]*bintree{}},
+			"0005_rollback_on_failure.sql": &bintree{dbMigrations0005_rollback_on_failureSql, map[string]*bintree{}},
+			"0006_exact_version.sql": &bintree{dbMigrations0006_exact_versionSql, map[string]*bintree{}},
+			"0007_rollout_gates.sql": &bintree{dbMigrations0007_rollout_gatesSql, map[string]*bintree{}},
+			"0008_channel_promotions.sql": &bintree{dbMigrations0008_channel_promotionsSql, map[string]*bintree{}},
+			"0009_channel_scheduled_changes.sql": &bintree{dbMigrations0009_channel_scheduled_changesSql, map[string]*bintree{}},
+			"0010_updates_limits.sql": &bintree{dbMigrations0010_updates_limitsSql, map[string]*bintree{}},
+			"0011_instance_labels.sql": &bintree{dbMigrations0011_instance_labelsSql, map[string]*bintree{}},
+			"0012_package_min_version.sql": &bintree{dbMigrations0012_package_min_versionSql, map[string]*bintree{}},
+			"0013_package_deltas.sql": &bintree{dbMigrations0013_package_deltasSql, map[string]*bintree{}},
+			"0014_package_files.sql": &bintree{dbMigrations0014_package_filesSql, map[string]*bintree{}},
+			"0015_package_arches.sql": &bintree{dbMigrations0015_package_archesSql, map[string]*bintree{}},
+			"0016_version_sort_key.sql": &bintree{dbMigrations0016_version_sort_keySql, map[string]*bintree{}},
+			"0017_version_scheme.sql": &bintree{dbMigrations0017_version_schemeSql, map[string]*bintree{}},
+			"0018_package_retraction.sql": &bintree{dbMigrations0018_package_retractionSql, map[string]*bintree{}},
+			"0019_critical_updates.sql": &bintree{dbMigrations0019_critical_updatesSql, map[string]*bintree{}},
+			"0020_changes_notifications.sql": &bintree{dbMigrations0020_changes_notificationsSql, map[string]*bintree{}},
+			"0021_version_sort_key_backfill.sql": &bintree{dbMigrations0021_version_sort_key_backfillSql, map[string]*bintree{}},
+			"0022_application_changes_notifications.sql": &bintree{dbMigrations0022_application_changes_notificationsSql, map[string]*bintree{}},
+			"0023_instance_labels_per_app.sql": &bintree{dbMigrations0023_instance_labels_per_appSql, map[string]*bintree{}},
+			"0024_package_payload_arches.sql": &bintree{dbMigrations0024_package_payload_archesSql, map[string]*bintree{}},
+			"0025_min_version_sort_key.sql": &bintree{dbMigrations0025_min_version_sort_keySql, map[string]*bintree{}},
 		}},
 	}},
 }}
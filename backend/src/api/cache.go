@@ -0,0 +1,295 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
+)
+
+const (
+	changesNotificationChannel = "coreroller_changes"
+	listenerMinReconnectDelay  = 1 * time.Second
+	listenerMaxReconnectDelay  = 1 * time.Minute
+	listenerPingInterval       = 1 * time.Minute
+)
+
+// groupsCache is an in-memory cache of the groups used to process updates
+// requests, including their policy, their channel and package and their
+// application's version scheme and retracted versions. The cache is flushed
+// every time groups,
+// channels, packages or applications change, as notified by the
+// database, so that all CoreRoller instances using the same database are kept
+// consistent.
+type groupsCache struct {
+	sync.RWMutex
+	groups     map[string]*Group
+	generation uint64
+	listener   *pq.Listener
+}
+
+// OptionCache enables the in-memory cache of the groups used to process updates
+// requests, which is invalidated using the database notifications sent when
+// groups, channels or packages change.
+func OptionCache(api *API) error {
+	cache := &groupsCache{
+		groups: make(map[string]*Group),
+	}
+
+	cache.listener = pq.NewListener(api.dbURL, listenerMinReconnectDelay, listenerMaxReconnectDelay, nil)
+	if err := cache.listener.Listen(changesNotificationChannel); err != nil {
+		_ = cache.listener.Close()
+		return err
+	}
+
+	go cache.watchChanges()
+	api.groupsCache = cache
+
+	return nil
+}
+
+// watchChanges flushes the cache every time a change notification is received
+// until the listener is closed. The cache is flushed as well when the listener
+// reconnects (notifications may have been missed in the meantime).
+func (c *groupsCache) watchChanges() {
+	for {
+		select {
+		case _, ok := <-c.listener.Notify:
+			if !ok {
+				return
+			}
+			c.flush()
+		case <-time.After(listenerPingInterval):
+			go func() {
+				_ = c.listener.Ping()
+			}()
+		}
+	}
+}
+
+// flush removes all the entries in the cache.
+func (c *groupsCache) flush() {
+	c.Lock()
+	c.groups = make(map[string]*Group)
+	c.generation++
+	c.Unlock()
+}
+
+// get returns a deep copy of the group identified by the id provided, if it's
+// in the cache, along with the current generation of the cache.
+func (c *groupsCache) get(groupID string) (*Group, uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	group, ok := c.groups[groupID]
+	if !ok {
+		return nil, c.generation, false
+	}
+
+	return copyGroup(group), c.generation, true
+}
+
+// set adds a deep copy of the group provided to the cache, unless the cache
+// has been flushed since the generation given (the group may be stale then).
+func (c *groupsCache) set(group *Group, generation uint64) {
+	groupCopy := copyGroup(group)
+
+	c.Lock()
+	if c.generation == generation {
+		c.groups[group.ID] = groupCopy
+	}
+	c.Unlock()
+}
+
+// close stops listening for change notifications.
+func (c *groupsCache) close() {
+	_ = c.listener.Close()
+}
+
+// getUpdatesGroup returns the group identified by the id provided with the
+// details needed to process updates requests: its policy, channel, package,
+// application's version scheme and retracted versions, current rollout stage
+// and rollout gate status. Unlike GetGroup, instances stats and version
+// breakdown are not included. All but the rollout stage and the gate status
+// are served from the cache when enabled, the rest is read using the given
+// connection so that it can be part of the transaction processing an update
+// request.
+func (api *API) getUpdatesGroup(db runner.Connection, groupID string) (*Group, error) {
+	group, err := api.getCachedUpdatesGroup(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rollout stage and the gate status change over time and with the
+	// updates of the instances, so they can't be cached.
+	if len(group.PolicyRolloutStages) > 0 {
+		var stage struct {
+			RolloutStage *RolloutStageStatus `json:"rollout_stage"`
+		}
+
+		err := db.
+			SelectDoc("id").
+			One("rollout_stage", api.groupRolloutStageQuery()).
+			From("groups").
+			Where("id = $1", groupID).
+			QueryStruct(&stage)
+
+		if err != nil {
+			return nil, err
+		}
+		group.RolloutStage = stage.RolloutStage
+	}
+
+	if err := api.setGroupGateStatus(db, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// getCachedUpdatesGroup returns the group identified by the id provided with
+// the details needed to process updates requests that can be cached (all of
+// them but the rollout stage and the gate status), from the cache when
+// enabled.
+func (api *API) getCachedUpdatesGroup(db runner.Connection, groupID string) (*Group, error) {
+	var group *Group
+	var generation uint64
+
+	if api.groupsCache != nil {
+		group, generation, _ = api.groupsCache.get(groupID)
+	}
+
+	if group == nil {
+		group = &Group{}
+
+		err := api.updatesGroupsQuery(db).
+			Where("id = $1", groupID).
+			QueryStruct(group)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if api.groupsCache != nil {
+			api.groupsCache.set(group, generation)
+		}
+	}
+
+	return group, nil
+}
+
+// updatesGroupsQuery returns a SelectDocBuilder prepared to return groups
+// with the details needed to process updates requests.
+func (api *API) updatesGroupsQuery(db runner.Connection) *dat.SelectDocBuilder {
+	return db.
+		SelectDoc("*",
+			"(SELECT version_scheme FROM application WHERE id = groups.application_id) AS version_scheme",
+			"(SELECT array_agg(version) FROM package WHERE application_id = groups.application_id AND retracted) AS retracted_versions").
+		One("channel", api.channelsQuery().Where("id = groups.channel_id")).
+		Many("policy_update_windows", api.groupUpdateWindowsQuery()).
+		Many("policy_rollout_stages", api.groupRolloutStagesQuery()).
+		Many("policy_label_selector", api.groupLabelSelectorQuery()).
+		From("groups")
+}
+
+// copyGroup returns a deep copy of the group provided, so that the groups in
+// the cache can't be modified by the callers using them (the decisions made
+// and the Omaha responses built modify the groups' packages, for example).
+func copyGroup(group *Group) *Group {
+	groupCopy := *group
+
+	if group.PolicyUpdateWindows != nil {
+		groupCopy.PolicyUpdateWindows = make([]*UpdateWindow, len(group.PolicyUpdateWindows))
+		for i, window := range group.PolicyUpdateWindows {
+			windowCopy := *window
+			if window.Weekdays != nil {
+				windowCopy.Weekdays = append(make([]int, 0, len(window.Weekdays)), window.Weekdays...)
+			}
+			groupCopy.PolicyUpdateWindows[i] = &windowCopy
+		}
+	}
+	if group.PolicyRolloutStages != nil {
+		groupCopy.PolicyRolloutStages = make([]*RolloutStage, len(group.PolicyRolloutStages))
+		for i, stage := range group.PolicyRolloutStages {
+			stageCopy := *stage
+			groupCopy.PolicyRolloutStages[i] = &stageCopy
+		}
+	}
+	if group.PolicyLabelSelector != nil {
+		groupCopy.PolicyLabelSelector = make([]*Label, len(group.PolicyLabelSelector))
+		for i, label := range group.PolicyLabelSelector {
+			labelCopy := *label
+			groupCopy.PolicyLabelSelector[i] = &labelCopy
+		}
+	}
+	if group.VersionBreakdown != nil {
+		groupCopy.VersionBreakdown = make([]*VersionBreakdownEntry, len(group.VersionBreakdown))
+		for i, entry := range group.VersionBreakdown {
+			entryCopy := *entry
+			groupCopy.VersionBreakdown[i] = &entryCopy
+		}
+	}
+	groupCopy.RetractedVersions = copyStrings(group.RetractedVersions)
+	if group.Gate != nil {
+		gateCopy := *group.Gate
+		groupCopy.Gate = &gateCopy
+	}
+	if group.RolloutStage != nil {
+		rolloutStageCopy := *group.RolloutStage
+		groupCopy.RolloutStage = &rolloutStageCopy
+	}
+	if group.Channel != nil {
+		channelCopy := *group.Channel
+		if group.Channel.Package != nil {
+			channelCopy.Package = copyPackage(group.Channel.Package)
+		}
+		groupCopy.Channel = &channelCopy
+	}
+
+	return &groupCopy
+}
+
+// copyPackage returns a deep copy of the package provided.
+func copyPackage(pkg *Package) *Package {
+	pkgCopy := *pkg
+
+	pkgCopy.ChannelsBlacklist = copyStrings(pkg.ChannelsBlacklist)
+	if pkg.CoreosAction != nil {
+		actionCopy := *pkg.CoreosAction
+		pkgCopy.CoreosAction = &actionCopy
+	}
+	if pkg.Deltas != nil {
+		pkgCopy.Deltas = make([]*PackageDelta, len(pkg.Deltas))
+		for i, delta := range pkg.Deltas {
+			deltaCopy := *delta
+			pkgCopy.Deltas[i] = &deltaCopy
+		}
+	}
+	if pkg.Files != nil {
+		pkgCopy.Files = make([]*PackageFile, len(pkg.Files))
+		for i, file := range pkg.Files {
+			fileCopy := *file
+			pkgCopy.Files[i] = &fileCopy
+		}
+	}
+	if pkg.Arches != nil {
+		pkgCopy.Arches = make([]*PackageArch, len(pkg.Arches))
+		for i, pkgArch := range pkg.Arches {
+			pkgArchCopy := *pkgArch
+			pkgCopy.Arches[i] = &pkgArchCopy
+		}
+	}
+
+	return &pkgCopy
+}
+
+// copyStrings returns a copy of the slice of strings provided.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append(make([]string, 0, len(s)), s...)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestGroupsCache(t *testing.T) {
+	a, err := New(OptionInitDB, OptionCache)
+	assert.NoError(t, err)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.2.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg1.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+
+	group, err := a.getUpdatesGroup(a.dbR, tGroup.ID)
+	assert.NoError(t, err)
+	assert.True(t, group.PolicyUpdatesEnabled)
+	assert.Equal(t, tPkg1.Version, group.Channel.Package.Version)
+	assert.Equal(t, VersionSchemeSemver, group.VersionScheme)
+
+	_, _, cached := a.groupsCache.get(tGroup.ID)
+	assert.True(t, cached)
+
+	tGroup.PolicyUpdatesEnabled = false
+	err = a.UpdateGroup(tGroup)
+	assert.NoError(t, err)
+	assert.True(t, waitForCacheFlush(a, tGroup.ID), "Group changes must invalidate the cache.")
+
+	group, _ = a.getUpdatesGroup(a.dbR, tGroup.ID)
+	assert.False(t, group.PolicyUpdatesEnabled)
+
+	tChannel.PackageID = dat.NullStringFrom(tPkg2.ID)
+	err = a.UpdateChannel(tChannel)
+	assert.NoError(t, err)
+	assert.True(t, waitForCacheFlush(a, tGroup.ID), "Channel changes must invalidate the cache.")
+
+	group, _ = a.getUpdatesGroup(a.dbR, tGroup.ID)
+	assert.Equal(t, tPkg2.Version, group.Channel.Package.Version)
+
+	tPkg2.URL = "http://sample.url/pkg2"
+	err = a.UpdatePackage(tPkg2)
+	assert.NoError(t, err)
+	assert.True(t, waitForCacheFlush(a, tGroup.ID), "Package changes must invalidate the cache.")
+
+	group, _ = a.getUpdatesGroup(a.dbR, tGroup.ID)
+	assert.Equal(t, "http://sample.url/pkg2", group.Channel.Package.URL)
+
+	tApp.Description = "updated"
+	err = a.UpdateApp(tApp)
+	assert.NoError(t, err)
+	assert.True(t, waitForCacheFlush(a, tGroup.ID), "Application changes must invalidate the cache.")
+}
+
+// waitForCacheFlush waits for the group provided to be removed from the cache,
+// returning false if it's still there after a few seconds.
+func waitForCacheFlush(a *API, groupID string) bool {
+	for i := 0; i < 50; i++ {
+		if _, _, cached := a.groupsCache.get(groupID); !cached {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return false
+}
+
+func TestCopyGroup(t *testing.T) {
+	group := &Group{
+		ID:                  "group1",
+		PolicyUpdateWindows: []*UpdateWindow{{Weekdays: []int{1, 2}, StartTime: "02:00", EndTime: "04:00"}},
+		PolicyLabelSelector: []*Label{{Key: "zone", Value: "eu"}},
+		RetractedVersions:   []string{"1.0.0"},
+		Channel: &Channel{
+			Name:    "stable",
+			Package: &Package{Version: "1.1.0", Files: []*PackageFile{{Name: "app.tgz"}}, Arches: []*PackageArch{{Arch: "arm64"}}},
+		},
+	}
+
+	groupCopy := copyGroup(group)
+	assert.Equal(t, group, groupCopy)
+
+	groupCopy.PolicyUpdateWindows[0].Weekdays[0] = 0
+	groupCopy.PolicyLabelSelector[0].Value = "us"
+	groupCopy.RetractedVersions[0] = "1.0.1"
+	groupCopy.Channel.Name = "beta"
+	groupCopy.Channel.Package.Version = "1.2.0"
+	groupCopy.Channel.Package.Files[0].Name = "app2.tgz"
+	groupCopy.Channel.Package.Arches[0].URL = "http://sample.url/pkg-arm64"
+
+	assert.Equal(t, 1, group.PolicyUpdateWindows[0].Weekdays[0])
+	assert.Equal(t, "eu", group.PolicyLabelSelector[0].Value)
+	assert.Equal(t, "1.0.0", group.RetractedVersions[0])
+	assert.Equal(t, "stable", group.Channel.Name)
+	assert.Equal(t, "1.1.0", group.Channel.Package.Version)
+	assert.Equal(t, "app.tgz", group.Channel.Package.Files[0].Name)
+	assert.Equal(t, "", group.Channel.Package.Arches[0].URL)
+}
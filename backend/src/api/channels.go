@@ -1,10 +1,12 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"time"
 
 	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
 )
 
 var (
@@ -15,17 +17,25 @@ var (
 	// ErrBlacklistedChannel error indicates an attempt of creating/updating a
 	// channel using a package that has blacklisted the channel.
 	ErrBlacklistedChannel = errors.New("coreroller: blacklisted channel")
+
+	// ErrNoKnownGoodPackage error indicates that a channel couldn't be rolled
+	// back as no previous package was found in the channel's history.
+	ErrNoKnownGoodPackage = errors.New("coreroller: no known good package found")
 )
 
 // Channel represents a CoreRoller application's channel.
 type Channel struct {
-	ID            string         `db:"id" json:"id"`
-	Name          string         `db:"name" json:"name"`
-	Color         string         `db:"color" json:"color"`
-	CreatedTs     time.Time      `db:"created_ts" json:"created_ts"`
-	ApplicationID string         `db:"application_id" json:"application_id"`
-	PackageID     dat.NullString `db:"package_id" json:"package_id"`
-	Package       *Package       `db:"package" json:"package"`
+	ID                       string         `db:"id" json:"id"`
+	Name                     string         `db:"name" json:"name"`
+	Color                    string         `db:"color" json:"color"`
+	CreatedTs                time.Time      `db:"created_ts" json:"created_ts"`
+	ApplicationID            string         `db:"application_id" json:"application_id"`
+	PackageID                dat.NullString `db:"package_id" json:"package_id"`
+	PackageUpdatedTs         time.Time      `db:"package_updated_ts" json:"package_updated_ts"`
+	RolledBackVersion        dat.NullString `db:"rolled_back_version" json:"rolled_back_version"`
+	PromotionSourceChannelID dat.NullString `db:"promotion_source_channel_id" json:"promotion_source_channel_id"`
+	PromotionSoakTime        dat.NullString `db:"promotion_soak_time" json:"promotion_soak_time"`
+	Package                  *Package       `db:"package" json:"package"`
 }
 
 // AddChannel registers the provided channel.
@@ -36,14 +46,28 @@ func (api *API) AddChannel(channel *Channel) (*Channel, error) {
 		}
 	}
 
+	if err := api.validatePromotionRule(channel, channel.ApplicationID); err != nil {
+		return nil, err
+	}
+
 	err := api.dbR.
 		InsertInto("channel").
-		Whitelist("name", "color", "application_id", "package_id").
+		Whitelist("name", "color", "application_id", "package_id", "promotion_source_channel_id", "promotion_soak_time").
 		Record(channel).
 		Returning("*").
 		QueryStruct(channel)
 
-	return channel, err
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.PackageID.String != "" {
+		if err := api.addChannelPackageHistoryEntry(api.dbR, channel.ID, channel.PackageID.String); err != nil {
+			return nil, err
+		}
+	}
+
+	return channel, nil
 }
 
 // UpdateChannel updates an existing channel using the content of the channel
@@ -61,11 +85,20 @@ func (api *API) UpdateChannel(channel *Channel) error {
 		}
 	}
 
-	result, err := api.dbR.
+	if err := api.validatePromotionRule(channel, channelBeforeUpdate.ApplicationID); err != nil {
+		return err
+	}
+
+	query := api.dbR.
 		Update("channel").
-		SetWhitelist(channel, "name", "color", "package_id").
-		Where("id = $1", channel.ID).
-		Exec()
+		SetWhitelist(channel, "name", "color", "package_id", "promotion_source_channel_id", "promotion_soak_time").
+		Where("id = $1", channel.ID)
+
+	if channelBeforeUpdate.PackageID.String != channel.PackageID.String {
+		query.Set("package_updated_ts", nowUTC).Set("rolled_back_version", nil)
+	}
+
+	result, err := query.Exec()
 
 	if err != nil {
 		return err
@@ -76,6 +109,9 @@ func (api *API) UpdateChannel(channel *Channel) error {
 	}
 
 	if channelBeforeUpdate.PackageID.String != channel.PackageID.String && pkg != nil {
+		if err := api.addChannelPackageHistoryEntry(api.dbR, channel.ID, pkg.ID); err != nil {
+			return err
+		}
 		_ = api.newChannelActivityEntry(activityChannelPackageUpdated, activityInfo, pkg.Version, pkg.ApplicationID, channel.ID)
 	}
 
@@ -125,9 +161,92 @@ func (api *API) GetChannels(appID string, page, perPage uint64) ([]*Channel, err
 	return channels, err
 }
 
-// validatePackage checks if a package belongs to the application provided and
-// that the channel is not in the package's channels blacklist. It returns the
-// package if everything is ok.
+// rollbackChannel points the channel of the group provided back to the last
+// known good package in the channel's history after a failed rollout of the
+// version provided, returning the package. The bad version is recorded in the
+// channel so that the instances that already got it can be downgraded once
+// the group's updates are enabled again. No package is returned when the
+// channel doesn't point to the bad version anymore.
+//
+// This method is part of the transaction that halts a failed rollout.
+func (api *API) rollbackChannel(tx *runner.Tx, group *Group, badVersion string) (*Package, error) {
+	if group.Channel == nil || group.Channel.Package == nil {
+		return nil, nil
+	}
+	channel := group.Channel
+
+	// The channel has already been pointed to a different package.
+	if channel.Package.Version != badVersion {
+		return nil, nil
+	}
+
+	pkg, err := api.getLastKnownGoodPackage(tx, channel, badVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.
+		Update("channel").
+		Set("package_id", pkg.ID).
+		Set("package_updated_ts", nowUTC).
+		Set("rolled_back_version", badVersion).
+		Where("id = $1 AND package_id = $2", channel.ID, channel.Package.ID).
+		Exec()
+
+	if err != nil || result.RowsAffected == 0 {
+		return nil, err
+	}
+	if err := api.addChannelPackageHistoryEntry(tx, channel.ID, pkg.ID); err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+// getLastKnownGoodPackage returns the package the channel provided was pointing
+// to before the bad version, based on the channel's packages history. Packages
+// that have been retracted or have blacklisted the channel are skipped.
+func (api *API) getLastKnownGoodPackage(db runner.Connection, channel *Channel, badVersion string) (*Package, error) {
+	var packageID string
+
+	query := `
+	SELECT h.package_id
+	FROM channel_package_history h, package p
+	WHERE h.channel_id = $1 AND
+		h.package_id = p.id AND
+		p.version != $2 AND
+		NOT p.retracted AND
+		p.id NOT IN (SELECT package_id FROM package_channel_blacklist WHERE channel_id = $1)
+	ORDER BY h.created_ts DESC, h.id DESC
+	LIMIT 1
+	`
+	err := db.SQL(query, channel.ID, badVersion).QueryScalar(&packageID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNoKnownGoodPackage
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return api.GetPackage(packageID)
+}
+
+// addChannelPackageHistoryEntry records that the channel provided has been
+// pointed to the package provided.
+func (api *API) addChannelPackageHistoryEntry(db runner.Connection, channelID, packageID string) error {
+	_, err := db.
+		InsertInto("channel_package_history").
+		Columns("channel_id", "package_id").
+		Values(channelID, packageID).
+		Exec()
+
+	return err
+}
+
+// validatePackage checks if a package belongs to the application provided, that
+// it hasn't been retracted and that the channel is not in the package's
+// channels blacklist. It returns the package if everything is ok.
 func (api *API) validatePackage(packageID, channelID, appID string) (*Package, error) {
 	pkg, err := api.GetPackage(packageID)
 	if err == nil {
@@ -135,6 +254,10 @@ func (api *API) validatePackage(packageID, channelID, appID string) (*Package, e
 			return nil, ErrInvalidPackage
 		}
 
+		if pkg.Retracted {
+			return nil, ErrRetractedPackage
+		}
+
 		for _, blacklistedChannelID := range pkg.ChannelsBlacklist {
 			if channelID == blacklistedChannelID {
 				return nil, ErrBlacklistedChannel
@@ -13,4 +13,14 @@ drop table if exists event_type cascade;
 drop table if exists event cascade;
 drop table if exists activity cascade;
 drop table if exists package_channel_blacklist cascade;
+drop table if exists group_rollout_stage cascade;
+drop table if exists group_update_window cascade;
+drop table if exists channel_package_history cascade;
+drop table if exists channel_scheduled_change cascade;
+drop table if exists instance_label cascade;
+drop table if exists group_label_selector cascade;
+drop table if exists package_delta cascade;
+drop table if exists package_file cascade;
+drop table if exists package_arch cascade;
+drop function if exists notify_changes() cascade;
 drop table if exists database_migrations;
@@ -0,0 +1,23 @@
+-- +migrate Up
+
+-- Staged rollouts
+
+alter table channel add column package_updated_ts timestamptz default current_timestamp not null;
+
+alter table groups add column policy_rollout_percentage numeric(5,2) check (policy_rollout_percentage >= 0 and policy_rollout_percentage <= 100);
+
+create table group_rollout_stage (
+	id serial primary key,
+	position integer not null check (position > 0),
+	percentage numeric(5,2) not null check (percentage >= 0 and percentage <= 100),
+	duration varchar(20) not null check (duration <> ''),
+	group_id uuid not null references groups (id) on delete cascade,
+	unique (group_id, position)
+);
+
+-- +migrate Down
+
+drop table if exists group_rollout_stage cascade;
+
+alter table groups drop column if exists policy_rollout_percentage;
+alter table channel drop column if exists package_updated_ts;
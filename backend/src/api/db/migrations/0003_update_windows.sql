@@ -0,0 +1,26 @@
+-- +migrate Up
+
+-- Update windows
+
+create table group_update_window (
+	id serial primary key,
+	weekdays integer[] not null check (array_length(weekdays, 1) > 0),
+	start_time time not null,
+	end_time time not null,
+	group_id uuid not null references groups (id) on delete cascade
+);
+
+create index on group_update_window (group_id);
+
+insert into group_update_window (weekdays, start_time, end_time, group_id)
+	select '{1,2,3,4,5}', '09:00', '17:00', id from groups where policy_office_hours = true;
+
+alter table groups drop column policy_office_hours;
+
+-- +migrate Down
+
+alter table groups add column policy_office_hours boolean default false not null;
+
+update groups set policy_office_hours = true where id in (select group_id from group_update_window);
+
+drop table if exists group_update_window cascade;
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+-- Error budget
+
+alter table groups add column policy_max_failed_updates integer default 0 not null check (policy_max_failed_updates >= 0);
+alter table groups add column policy_max_failed_updates_percentage numeric(5,2) default 0 not null check (policy_max_failed_updates_percentage >= 0 and policy_max_failed_updates_percentage <= 100);
+
+-- +migrate Down
+
+alter table groups drop column if exists policy_max_failed_updates_percentage;
+alter table groups drop column if exists policy_max_failed_updates;
@@ -0,0 +1,25 @@
+-- +migrate Up
+
+-- Automatic rollbacks
+
+alter table groups add column policy_rollback_on_failure boolean default false not null;
+alter table channel add column rolled_back_version varchar(255);
+
+create table channel_package_history (
+	id serial primary key,
+	created_ts timestamptz default current_timestamp not null,
+	channel_id uuid not null references channel (id) on delete cascade,
+	package_id uuid not null references package (id) on delete cascade
+);
+
+create index on channel_package_history (channel_id);
+
+insert into channel_package_history (channel_id, package_id)
+	select id, package_id from channel where package_id is not null;
+
+-- +migrate Down
+
+drop table if exists channel_package_history cascade;
+
+alter table channel drop column if exists rolled_back_version;
+alter table groups drop column if exists policy_rollback_on_failure;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- Pinned versions
+
+alter table groups add column policy_exact_version boolean default false not null;
+
+-- +migrate Down
+
+alter table groups drop column if exists policy_exact_version;
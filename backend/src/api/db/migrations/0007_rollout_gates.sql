@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Rollout gates
+
+alter table groups add column policy_gate_group_id uuid references groups (id) on delete set null;
+alter table groups add column policy_gate_success_percentage numeric(5,2) check (policy_gate_success_percentage >= 0 and policy_gate_success_percentage <= 100);
+alter table groups add column policy_gate_soak_time varchar(20);
+
+-- +migrate Down
+
+alter table groups drop column if exists policy_gate_soak_time;
+alter table groups drop column if exists policy_gate_success_percentage;
+alter table groups drop column if exists policy_gate_group_id;
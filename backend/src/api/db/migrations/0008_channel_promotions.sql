@@ -0,0 +1,11 @@
+-- +migrate Up
+
+-- Channel promotions
+
+alter table channel add column promotion_source_channel_id uuid references channel (id) on delete set null;
+alter table channel add column promotion_soak_time varchar(20);
+
+-- +migrate Down
+
+alter table channel drop column if exists promotion_soak_time;
+alter table channel drop column if exists promotion_source_channel_id;
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+-- Scheduled channel changes
+
+create table channel_scheduled_change (
+	id uuid primary key default uuid_generate_v4(),
+	scheduled_ts timestamptz not null,
+	created_ts timestamptz default current_timestamp not null,
+	created_by varchar(25) not null check (created_by <> ''),
+	status integer default 1 not null,
+	processed_ts timestamptz,
+	channel_id uuid not null references channel (id) on delete cascade,
+	package_id uuid not null references package (id) on delete cascade
+);
+
+create index on channel_scheduled_change (channel_id);
+create index on channel_scheduled_change (status, scheduled_ts);
+
+-- +migrate Down
+
+drop table if exists channel_scheduled_change cascade;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Independent concurrent and timed out updates limits
+
+alter table groups add column policy_max_concurrent_updates integer default 0 not null check (policy_max_concurrent_updates >= 0);
+alter table groups add column policy_max_timed_out_updates integer default 0 not null check (policy_max_timed_out_updates >= 0);
+alter table groups add column policy_timed_out_mark_failed boolean default false not null;
+
+-- +migrate Down
+
+alter table groups drop column if exists policy_timed_out_mark_failed;
+alter table groups drop column if exists policy_max_timed_out_updates;
+alter table groups drop column if exists policy_max_concurrent_updates;
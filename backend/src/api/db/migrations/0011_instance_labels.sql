@@ -0,0 +1,27 @@
+-- +migrate Up
+
+-- Instance labels
+
+create table instance_label (
+	instance_id varchar(50) not null references instance (id) on delete cascade,
+	key varchar(50) not null check (key <> ''),
+	value varchar(255) not null,
+	primary key (instance_id, key)
+);
+
+create index on instance_label (key, value);
+
+-- Group label selectors
+
+create table group_label_selector (
+	id serial primary key,
+	key varchar(50) not null check (key <> ''),
+	value varchar(255) not null,
+	group_id uuid not null references groups (id) on delete cascade,
+	unique (group_id, key)
+);
+
+-- +migrate Down
+
+drop table if exists group_label_selector cascade;
+drop table if exists instance_label cascade;
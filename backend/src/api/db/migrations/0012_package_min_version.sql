@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- Minimum source version
+
+alter table package add column min_version varchar(255);
+
+-- +migrate Down
+
+alter table package drop column if exists min_version;
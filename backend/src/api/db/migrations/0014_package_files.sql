@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- Multi-file packages
+
+create table package_file (
+	id serial primary key,
+	position integer not null,
+	name varchar(100) not null check (name <> ''),
+	size varchar(20),
+	hash varchar(64),
+	hash_sha256 varchar(64),
+	required boolean default true not null,
+	package_id uuid not null references package (id) on delete cascade,
+	unique (package_id, name)
+);
+
+-- +migrate Down
+
+drop table if exists package_file cascade;
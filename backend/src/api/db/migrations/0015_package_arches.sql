@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Multi-architecture packages
+
+alter table package add column arch varchar(20);
+
+create table package_arch (
+	id uuid primary key default uuid_generate_v4(),
+	arch varchar(20) not null check (arch <> ''),
+	url varchar(256) not null check (url <> ''),
+	filename varchar(100),
+	size varchar(20),
+	hash varchar(64),
+	sha256 varchar(64),
+	created_ts timestamptz default current_timestamp not null,
+	package_id uuid not null references package (id) on delete cascade,
+	unique (package_id, arch)
+);
+
+-- +migrate Down
+
+drop table if exists package_arch cascade;
+
+alter table package drop column if exists arch;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Versions sort keys (populated by the api on startup for existing rows)
+
+alter table package add column version_sort_key text collate "C";
+alter table instance_application add column version_sort_key text collate "C";
+
+create index on package (application_id, version_sort_key);
+
+-- +migrate Down
+
+alter table package drop column if exists version_sort_key;
+alter table instance_application drop column if exists version_sort_key;
@@ -0,0 +1,7 @@
+-- +migrate Up
+
+alter table application add column version_scheme varchar(20) not null default 'semver';
+
+-- +migrate Down
+
+alter table application drop column version_scheme;
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+alter table package add column retracted boolean not null default false;
+alter table package add column retracted_ts timestamptz;
+alter table activity add column instances_count integer;
+
+-- +migrate Down
+
+alter table package drop column retracted;
+alter table package drop column retracted_ts;
+alter table activity drop column instances_count;
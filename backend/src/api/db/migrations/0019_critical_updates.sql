@@ -0,0 +1,11 @@
+-- +migrate Up
+
+alter table package add column critical boolean not null default false;
+alter table groups add column policy_critical_updates boolean not null default false;
+alter table groups add column policy_critical_max_concurrent_updates integer not null default 0 check (policy_critical_max_concurrent_updates >= 0);
+
+-- +migrate Down
+
+alter table package drop column critical;
+alter table groups drop column policy_critical_updates;
+alter table groups drop column policy_critical_max_concurrent_updates;
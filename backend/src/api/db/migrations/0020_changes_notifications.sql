@@ -0,0 +1,26 @@
+-- +migrate Up
+
+-- +migrate StatementBegin
+create or replace function notify_changes() returns trigger as $$
+begin
+	perform pg_notify('coreroller_changes', TG_TABLE_NAME);
+	return null;
+end;
+$$ language plpgsql;
+-- +migrate StatementEnd
+
+create trigger notify_changes after insert or update or delete or truncate on groups for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on group_update_window for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on group_rollout_stage for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on group_label_selector for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on channel for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on package for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on package_channel_blacklist for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on package_delta for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on package_file for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on package_arch for each statement execute procedure notify_changes();
+create trigger notify_changes after insert or update or delete or truncate on coreos_action for each statement execute procedure notify_changes();
+
+-- +migrate Down
+
+drop function notify_changes() cascade;
@@ -0,0 +1,12 @@
+-- +migrate Up
+
+-- Rows without version sort key (used to check cheaply on startup if there is
+-- anything left to backfill)
+
+create index package_version_sort_key_missing_idx on package (id) where version_sort_key is null;
+create index instance_application_version_sort_key_missing_idx on instance_application (application_id, version) where version_sort_key is null;
+
+-- +migrate Down
+
+drop index if exists package_version_sort_key_missing_idx;
+drop index if exists instance_application_version_sort_key_missing_idx;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- The groups cache includes the application's version scheme
+
+create trigger notify_changes after insert or update or delete or truncate on application for each statement execute procedure notify_changes();
+
+-- +migrate Down
+
+drop trigger if exists notify_changes on application;
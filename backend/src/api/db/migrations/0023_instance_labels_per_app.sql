@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Instance labels are reported per app, so they are stored per app as well
+
+alter table instance_label drop constraint instance_label_pkey;
+alter table instance_label add column application_id uuid references application (id) on delete cascade;
+
+insert into instance_label (instance_id, application_id, key, value)
+select l.instance_id, ia.application_id, l.key, l.value
+from instance_label l
+join instance_application ia on ia.instance_id = l.instance_id
+where l.application_id is null;
+
+delete from instance_label where application_id is null;
+
+alter table instance_label alter column application_id set not null;
+alter table instance_label add primary key (instance_id, application_id, key);
+
+-- +migrate Down
+
+alter table instance_label drop constraint instance_label_pkey;
+delete from instance_label a using instance_label b where a.instance_id = b.instance_id and a.key = b.key and a.application_id > b.application_id;
+alter table instance_label drop column application_id;
+alter table instance_label add primary key (instance_id, key);
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Deltas and files belong to the main payload of the package, or to one of its
+-- architecture payloads when the arch is set
+
+alter table package_delta add column arch varchar(20) check (arch <> '');
+alter table package_delta drop constraint package_delta_package_id_from_version_key;
+create unique index package_delta_package_id_arch_from_version_key on package_delta (package_id, coalesce(arch, ''), from_version);
+
+alter table package_file add column arch varchar(20) check (arch <> '');
+alter table package_file drop constraint package_file_package_id_name_key;
+create unique index package_file_package_id_arch_name_key on package_file (package_id, coalesce(arch, ''), name);
+
+-- +migrate Down
+
+delete from package_delta where arch is not null;
+drop index if exists package_delta_package_id_arch_from_version_key;
+alter table package_delta drop column arch;
+alter table package_delta add unique (package_id, from_version);
+
+delete from package_file where arch is not null;
+drop index if exists package_file_package_id_arch_name_key;
+alter table package_file drop column arch;
+alter table package_file add unique (package_id, name);
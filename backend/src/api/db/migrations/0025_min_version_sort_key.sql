@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Sort key of the packages minimum version, so that the stepping stone
+-- packages can be selected in the database
+
+alter table package add column min_version_sort_key text collate "C";
+
+create index package_min_version_sort_key_missing_idx on package (id) where min_version is not null and min_version_sort_key is null;
+
+-- +migrate Down
+
+drop index if exists package_min_version_sort_key_missing_idx;
+alter table package drop column if exists min_version_sort_key;
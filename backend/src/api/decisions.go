@@ -0,0 +1,283 @@
+package api
+
+import (
+	"time"
+
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
+)
+
+// UpdateCheck represents one of the checks performed when deciding if an
+// update should be granted to an instance, including the values used as input
+// and its outcome.
+type UpdateCheck struct {
+	Name   string                 `json:"name"`
+	Input  map[string]interface{} `json:"input"`
+	Passed bool                   `json:"passed"`
+}
+
+// UpdateDecision represents the decision of granting (or not) an update to an
+// instance, along with the checks performed to make it. The checks stop at the
+// first one that doesn't pass, which is the reason why the update wasn't
+// granted.
+type UpdateDecision struct {
+	InstanceID      string         `json:"instance_id"`
+	InstanceVersion string         `json:"instance_version"`
+	GroupID         string         `json:"group_id"`
+	Package         *Package       `json:"package"`
+	Checks          []*UpdateCheck `json:"checks"`
+	Granted         bool           `json:"granted"`
+	Error           string         `json:"error,omitempty"`
+
+	err          error
+	updatesStats *UpdatesStats
+	critical     bool
+}
+
+// ExplainUpdateDecision returns the decision that would be made if the
+// instance provided requested an update now from the given architecture,
+// including the details of all the checks performed. No changes are made to
+// the instance or the group. The instance must belong to the group provided,
+// as the decision depends on the group's stats and policies.
+func (api *API) ExplainUpdateDecision(instanceID, appID, groupID, arch string) (*UpdateDecision, error) {
+	instance, err := api.GetInstance(instanceID, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := api.getUpdatesGroup(api.dbR, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.ApplicationID != appID || instance.Application.GroupID.String != groupID {
+		return nil, ErrInvalidApplicationOrGroup
+	}
+
+	return api.decideUpdate(api.dbR, instance, group, arch, time.Now()), nil
+}
+
+// decideUpdate runs the pipeline of checks that decides if the instance
+// provided, running on the given architecture, should get the package the
+// group's channel is pointing to at the time given. It doesn't have any side
+// effects, so it's used both to process updates requests and to explain the
+// decisions made. The group's updates stats are read using the connection
+// provided, which is the transaction locking the group when processing an
+// update request.
+func (api *API) decideUpdate(db runner.Connection, instance *Instance, group *Group, arch string, now time.Time) *UpdateDecision {
+	d := &UpdateDecision{
+		InstanceID:      instance.ID,
+		InstanceVersion: instance.Application.Version,
+		GroupID:         group.ID,
+	}
+
+	updateInProgress := false
+	if instance.Application.Status.Valid {
+		switch int(instance.Application.Status.Int64) {
+		case InstanceStatusUpdateGranted, InstanceStatusDownloading, InstanceStatusDownloaded, InstanceStatusInstalled:
+			updateInProgress = true
+		}
+	}
+	if !d.check("update_in_progress", map[string]interface{}{
+		"status": instance.Application.Status,
+	}, !updateInProgress, ErrUpdateInProgressOnInstance) {
+		return d
+	}
+
+	if !d.check("package_found", map[string]interface{}{
+		"channel_id": group.ChannelID,
+	}, group.Channel != nil && group.Channel.Package != nil, ErrNoPackageFound) {
+		return d
+	}
+	pkg := group.Channel.Package
+
+	if !d.check("channel_blacklist", map[string]interface{}{
+		"channel_id":         group.Channel.ID,
+		"channels_blacklist": pkg.ChannelsBlacklist,
+	}, !isBlacklisted(pkg, group.Channel.ID), ErrNoUpdatePackageAvailable) {
+		return d
+	}
+
+	scheme := group.VersionScheme
+
+	// Instances running a version the channel has been rolled back from are
+	// allowed to get the (older) package the channel is pointing to now.
+	rollback := group.Channel.RolledBackVersion.Valid && group.Channel.RolledBackVersion.String == instance.Application.Version
+
+	// Instances running a retracted version are forced to the package the
+	// channel is pointing to as a priority update, downgrading them if needed.
+	retracted := false
+	if instance.Application.Version != pkg.Version {
+		retracted = isVersionRetracted(group, instance.Application.Version)
+	}
+
+	if !d.check("version", map[string]interface{}{
+		"instance_version":    instance.Application.Version,
+		"package_version":     pkg.Version,
+		"version_scheme":      scheme,
+		"exact_version":       group.PolicyExactVersion,
+		"rolled_back_version": group.Channel.RolledBackVersion,
+		"retracted":           retracted,
+	}, rollback || retracted || isUpdateAvailable(scheme, instance.Application.Version, pkg.Version, group.PolicyExactVersion), ErrNoUpdatePackageAvailable) {
+		return d
+	}
+
+	// Instances running a version lower than the package's minimum version
+	// get the newest intermediate package they can be updated to instead.
+	var steppingStone *Package
+	if !rollback && !canBeInstalledFrom(scheme, pkg, instance.Application.Version) {
+		steppingStone, _ = api.getSteppingStonePackage(scheme, pkg, instance.Application.Version, group.Channel.ID)
+	}
+	minVersionInput := map[string]interface{}{
+		"instance_version": instance.Application.Version,
+		"min_version":      pkg.MinVersion,
+		"rollback":         rollback,
+	}
+	if steppingStone != nil {
+		minVersionInput["stepping_stone_version"] = steppingStone.Version
+	}
+	if !d.check("min_version", minVersionInput, rollback || canBeInstalledFrom(scheme, pkg, instance.Application.Version) || steppingStone != nil, ErrNoUpdatePackageAvailable) {
+		return d
+	}
+
+	updatePkg := pkg
+	if steppingStone != nil {
+		updatePkg = steppingStone
+	}
+	_, archAvailable := updatePkg.ArchPayload(arch)
+	if !d.check("arch", map[string]interface{}{
+		"arch":         arch,
+		"package_arch": updatePkg.Arch,
+		"arches":       updatePkg.Arches,
+	}, archAvailable, ErrNoPackageForArch) {
+		return d
+	}
+
+	if !d.check("label_selector", map[string]interface{}{
+		"instance_labels":       instance.Labels,
+		"policy_label_selector": group.PolicyLabelSelector,
+	}, matchesLabelSelector(instance.Labels, group.PolicyLabelSelector), ErrNoUpdatePackageAvailable) {
+		return d
+	}
+
+	percentage := rolloutPercentage(group)
+	if !d.check("rollout_cohort", map[string]interface{}{
+		"rollout_percentage": percentage,
+		"rollout_stage":      group.RolloutStage,
+		"rollback":           rollback,
+		"retracted":          retracted,
+	}, rollback || retracted || inRolloutCohort(instance.ID, pkg.Version, percentage), ErrNoUpdatePackageAvailable) {
+		return d
+	}
+
+	if !d.check("rollout_gate", map[string]interface{}{
+		"gate":      group.Gate,
+		"rollback":  rollback,
+		"retracted": retracted,
+	}, rollback || retracted || group.Gate == nil || group.Gate.Open, ErrRolloutGateClosed) {
+		return d
+	}
+
+	updatesStats, err := api.getGroupUpdatesStats(db, group)
+	if !d.check("updates_stats", map[string]interface{}{}, err == nil, ErrGetUpdatesStatsFailed) {
+		return d
+	}
+	d.updatesStats = updatesStats
+
+	if !d.check("updates_enabled", map[string]interface{}{
+		"policy_updates_enabled": group.PolicyUpdatesEnabled,
+	}, group.PolicyUpdatesEnabled, ErrUpdatesDisabled) {
+		return d
+	}
+
+	// Critical packages are rolled out using the group's critical updates
+	// policy when enabled, which ignores the update windows, safe mode and the
+	// updates per period limit and has its own concurrent updates limit.
+	critical := updatePkg.Critical && group.PolicyCriticalUpdates
+	d.critical = critical
+	d.check("critical_update", map[string]interface{}{
+		"package_critical":        updatePkg.Critical,
+		"policy_critical_updates": group.PolicyCriticalUpdates,
+		"applied":                 critical,
+	}, true, nil)
+
+	if !d.check("update_windows", map[string]interface{}{
+		"policy_timezone":       group.PolicyTimezone,
+		"policy_update_windows": group.PolicyUpdateWindows,
+		"now":                   now,
+		"critical":              critical,
+	}, critical || len(group.PolicyUpdateWindows) == 0 || inUpdateWindow(group.PolicyUpdateWindows, group.PolicyTimezone.String, now), ErrUpdatesDisabled) {
+		return d
+	}
+
+	maxUpdatesPerPeriod := group.PolicyMaxUpdatesPerPeriod
+	maxConcurrentUpdates := group.PolicyMaxConcurrentUpdates
+	if maxConcurrentUpdates == 0 {
+		maxConcurrentUpdates = group.PolicyMaxUpdatesPerPeriod
+	}
+	maxTimedOutUpdates := group.PolicyMaxTimedOutUpdates
+	if maxTimedOutUpdates == 0 {
+		maxTimedOutUpdates = group.PolicyMaxUpdatesPerPeriod
+	}
+
+	safeModeApplied := !critical && group.PolicySafeMode && updatesStats.UpdatesToCurrentVersionAttempted == 0
+	if safeModeApplied {
+		maxUpdatesPerPeriod, maxConcurrentUpdates, maxTimedOutUpdates = 1, 1, 1
+	}
+	d.check("safe_mode", map[string]interface{}{
+		"policy_safe_mode":                     group.PolicySafeMode,
+		"updates_to_current_version_attempted": updatesStats.UpdatesToCurrentVersionAttempted,
+		"applied":                              safeModeApplied,
+	}, true, nil)
+
+	if !d.check("max_updates_per_period", map[string]interface{}{
+		"policy_period_interval":         group.PolicyPeriodInterval,
+		"max_updates_per_period":         maxUpdatesPerPeriod,
+		"updates_granted_in_last_period": updatesStats.UpdatesGrantedInLastPeriod,
+		"retracted":                      retracted,
+		"critical":                       critical,
+	}, retracted || critical || updatesStats.UpdatesGrantedInLastPeriod < maxUpdatesPerPeriod, ErrMaxUpdatesPerPeriodLimitReached) {
+		return d
+	}
+
+	if critical {
+		maxConcurrentUpdates = group.PolicyCriticalMaxConcurrentUpdates
+	}
+	if !d.check("max_concurrent_updates", map[string]interface{}{
+		"max_concurrent_updates": maxConcurrentUpdates,
+		"updates_in_progress":    updatesStats.UpdatesInProgress,
+		"critical":               critical,
+	}, (critical && maxConcurrentUpdates == 0) || updatesStats.UpdatesInProgress < maxConcurrentUpdates, ErrMaxConcurrentUpdatesLimitReached) {
+		return d
+	}
+
+	if !d.check("max_timed_out_updates", map[string]interface{}{
+		"policy_update_timeout": group.PolicyUpdateTimeout,
+		"max_timed_out_updates": maxTimedOutUpdates,
+		"updates_timed_out":     updatesStats.UpdatesTimedOut,
+	}, updatesStats.UpdatesTimedOut < maxTimedOutUpdates, ErrMaxTimedOutUpdatesLimitReached) {
+		return d
+	}
+
+	d.Granted = true
+	d.Package = updatePkg
+
+	return d
+}
+
+// check records the outcome of a check in the decision, setting the error
+// provided as the reason of the decision when the check didn't pass. It
+// returns if the check passed.
+func (d *UpdateDecision) check(name string, input map[string]interface{}, passed bool, err error) bool {
+	d.Checks = append(d.Checks, &UpdateCheck{
+		Name:   name,
+		Input:  input,
+		Passed: passed,
+	})
+
+	if !passed {
+		d.err = err
+		d.Error = err.Error()
+	}
+
+	return passed
+}
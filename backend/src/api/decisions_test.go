@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestExplainUpdateDecision(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+	tInstance1, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	tInstance2, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	tInstance3, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.3", "12.1.0", tApp.ID, tGroup.ID)
+
+	_, err := a.ExplainUpdateDecision(tInstance1.ID, uuid.NewV4().String(), tGroup.ID, "")
+	assert.Error(t, err)
+
+	tGroup2, _ := a.AddGroup(&Group{Name: "group2", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes"})
+	_, err = a.ExplainUpdateDecision(tInstance1.ID, tApp.ID, tGroup2.ID, "")
+	assert.Equal(t, ErrInvalidApplicationOrGroup, err, "Instance doesn't belong to the group.")
+
+	decision, err := a.ExplainUpdateDecision(tInstance1.ID, tApp.ID, tGroup.ID, "")
+	assert.NoError(t, err)
+	assert.True(t, decision.Granted)
+	assert.Equal(t, "12.1.0", decision.Package.Version)
+	assert.Equal(t, "max_timed_out_updates", decision.Checks[len(decision.Checks)-1].Name)
+
+	instance, _ := a.GetInstance(tInstance1.ID, tApp.ID)
+	assert.False(t, instance.Application.Status.Valid, "Explaining a decision has no side effects.")
+	assert.False(t, instance.Application.UpdateInProgress)
+
+	decision, _ = a.ExplainUpdateDecision(tInstance3.ID, tApp.ID, tGroup.ID, "")
+	assert.False(t, decision.Granted)
+	assert.Equal(t, ErrNoUpdatePackageAvailable.Error(), decision.Error)
+	lastCheck := decision.Checks[len(decision.Checks)-1]
+	assert.Equal(t, "version", lastCheck.Name)
+	assert.False(t, lastCheck.Passed)
+	assert.Equal(t, "12.1.0", lastCheck.Input["instance_version"])
+
+	_, _ = a.GetUpdatePackage(tInstance1.ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+
+	decision, _ = a.ExplainUpdateDecision(tInstance2.ID, tApp.ID, tGroup.ID, "")
+	assert.False(t, decision.Granted)
+	assert.Equal(t, ErrMaxUpdatesPerPeriodLimitReached.Error(), decision.Error, "Safe mode allows only one update until the first one is completed.")
+
+	instance, _ = a.GetInstance(tInstance2.ID, tApp.ID)
+	assert.False(t, instance.Application.Status.Valid, "Explaining a decision doesn't put the instance on hold.")
+}
@@ -83,10 +83,12 @@ type Event struct {
 }
 
 // RegisterEvent registers an event posted by an instance in CoreRoller. The
-// event will be bound to an application/group combination.
+// event will be bound to an application/group combination. An error is also
+// returned when the event was registered but its consequences couldn't be
+// triggered.
 func (api *API) RegisterEvent(instanceID, appID, groupID string, etype, eresult int, previousVersion, errorCode string) error {
 	var err error
-	if appID, groupID, err = api.validateApplicationAndGroup(appID, groupID); err != nil {
+	if appID, groupID, _, err = api.validateApplicationAndGroup(appID, groupID); err != nil {
 		return err
 	}
 
@@ -129,60 +131,144 @@ func (api *API) RegisterEvent(instanceID, appID, groupID string, etype, eresult
 	}
 
 	lastUpdateVersion := instance.Application.LastUpdateVersion.String
-	_ = api.triggerEventConsequences(instanceID, appID, groupID, lastUpdateVersion, etype, eresult)
 
-	return nil
+	return api.triggerEventConsequences(instanceID, appID, groupID, lastUpdateVersion, etype, eresult)
 }
 
 // triggerEventConsequences is in charge of triggering the consequences of a
 // given event. Depending on the type of the event and its result, the status
 // of the instance may be updated, new activity entries could be created, etc.
 func (api *API) triggerEventConsequences(instanceID, appID, groupID, lastUpdateVersion string, etype, result int) error {
-	group, err := api.GetGroup(groupID)
+	group, err := api.getUpdatesGroup(api.dbR, groupID)
 	if err != nil {
 		return err
 	}
 
 	// TODO: should we also consider ResultSuccess in the next check? CoreOS ~ generic conflicts?
 	if etype == EventUpdateComplete && result == ResultSuccessReboot {
-		_ = api.updateInstanceStatus(instanceID, appID, InstanceStatusComplete)
+		_ = api.updateInstanceStatus(api.dbR, instanceID, appID, InstanceStatusComplete)
 
-		updatesStats, err := api.getGroupUpdatesStats(group)
+		updatesStats, err := api.getGroupUpdatesStats(api.dbR, group)
 		if err != nil {
 			return err
 		}
 		if updatesStats.UpdatesToCurrentVersionSucceeded == updatesStats.TotalInstances {
-			_ = api.setGroupRolloutInProgress(groupID, false)
+			_ = api.setGroupRolloutInProgress(api.dbR, groupID, false)
 			_ = api.newGroupActivityEntry(activityRolloutFinished, activitySuccess, lastUpdateVersion, appID, groupID)
 		}
 	}
 
 	if etype == EventUpdateDownloadStarted && result == ResultSuccess {
-		_ = api.updateInstanceStatus(instanceID, appID, InstanceStatusDownloading)
+		_ = api.updateInstanceStatus(api.dbR, instanceID, appID, InstanceStatusDownloading)
 	}
 
 	if etype == EventUpdateDownloadFinished && result == ResultSuccess {
-		_ = api.updateInstanceStatus(instanceID, appID, InstanceStatusDownloaded)
+		_ = api.updateInstanceStatus(api.dbR, instanceID, appID, InstanceStatusDownloaded)
 	}
 
 	if etype == EventUpdateInstalled && result == ResultSuccess {
-		_ = api.updateInstanceStatus(instanceID, appID, InstanceStatusInstalled)
+		_ = api.updateInstanceStatus(api.dbR, instanceID, appID, InstanceStatusInstalled)
 	}
 
 	if result == ResultFailed {
-		_ = api.updateInstanceStatus(instanceID, appID, InstanceStatusError)
+		_ = api.updateInstanceStatus(api.dbR, instanceID, appID, InstanceStatusError)
 		_ = api.newInstanceActivityEntry(activityInstanceUpdateFailed, activityError, lastUpdateVersion, appID, groupID, instanceID)
 
-		updatesStats, err := api.getGroupUpdatesStats(group)
+		updatesStats, err := api.getGroupUpdatesStats(api.dbR, group)
 		if err != nil {
 			return err
 		}
-		if updatesStats.UpdatesToCurrentVersionAttempted == 1 {
-			_ = api.disableUpdates(groupID)
-			_ = api.setGroupRolloutInProgress(groupID, false)
-			_ = api.newGroupActivityEntry(activityRolloutFailed, activityError, lastUpdateVersion, appID, groupID)
+		if hasErrorBudget(group) {
+			if errorBudgetExceeded(group, updatesStats) {
+				return api.haltRollout(group, lastUpdateVersion, activityRolloutErrorBudgetExceeded)
+			}
+		} else if updatesStats.UpdatesToCurrentVersionAttempted == 1 {
+			return api.haltRollout(group, lastUpdateVersion, activityRolloutFailed)
 		}
 	}
 
 	return nil
 }
+
+// haltRollout disables the updates of the group provided after a failed
+// rollout of the version given, rolling back the group's channel when its
+// policy requires it. The changes are made in a single transaction holding the
+// group's lock, and nothing is done when the group's updates have already
+// been disabled. The group's updates are not enabled again after a rollback,
+// so that the downgrades are started by the user once the failure has been
+// reviewed.
+func (api *API) haltRollout(group *Group, version string, activityClass int) error {
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	if err := api.lockGroup(tx, group.ID); err != nil {
+		return err
+	}
+
+	result, err := tx.
+		Update("groups").
+		Set("policy_updates_enabled", false).
+		Set("rollout_in_progress", false).
+		Where("id = $1 AND policy_updates_enabled", group.ID).
+		Exec()
+
+	if err != nil || result.RowsAffected == 0 {
+		return err
+	}
+
+	var pkg *Package
+	var rollbackErr error
+	if group.PolicyRollbackOnFailure {
+		pkg, rollbackErr = api.rollbackChannel(tx, group, version)
+		if rollbackErr != nil && rollbackErr != ErrNoKnownGoodPackage {
+			return rollbackErr
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	_ = api.newGroupActivityEntry(activityClass, activityError, version, group.ApplicationID, group.ID)
+	if rollbackErr == ErrNoKnownGoodPackage {
+		_ = api.newChannelActivityEntry(activityChannelRollbackFailed, activityError, version, group.ApplicationID, group.Channel.ID)
+	}
+	if pkg != nil {
+		_ = api.newChannelActivityEntry(activityChannelRolledBack, activityWarning, pkg.Version, group.ApplicationID, group.Channel.ID)
+		_ = api.newGroupActivityEntry(activityRollbackStarted, activityInfo, pkg.Version, group.ApplicationID, group.ID)
+	}
+
+	return nil
+}
+
+// hasErrorBudget checks if the group provided has an error budget defined, in
+// which case it replaces the default behaviour of disabling updates when the
+// first update attempt fails.
+func hasErrorBudget(group *Group) bool {
+	return group.PolicyMaxFailedUpdates > 0 || group.PolicyMaxFailedUpdatesPercentage > 0
+}
+
+// errorBudgetExceeded checks if the number of failed updates to the current
+// version has reached the limit defined in the group's error budget, or if
+// their percentage over the attempts so far is above the one allowed. The
+// percentage is evaluated from the first failure, so a rollout whose first
+// attempts fail is paused straight away.
+func errorBudgetExceeded(group *Group, updatesStats *UpdatesStats) bool {
+	failed := updatesStats.UpdatesToCurrentVersionFailed
+	attempted := updatesStats.UpdatesToCurrentVersionAttempted
+
+	if group.PolicyMaxFailedUpdates > 0 && failed >= group.PolicyMaxFailedUpdates {
+		return true
+	}
+
+	if group.PolicyMaxFailedUpdatesPercentage > 0 && float64(failed)*100 > group.PolicyMaxFailedUpdatesPercentage*float64(attempted) {
+		return true
+	}
+
+	return false
+}
@@ -108,3 +108,132 @@ func TestRegisterEvent_TriggerEventConsequences_FirstUpdateAttemptFailed(t *test
 	group, _ := a.GetGroup(tGroup.ID)
 	assert.Equal(t, false, group.PolicyUpdatesEnabled, "First update attempt failed.")
 }
+
+func TestRegisterEvent_TriggerEventConsequences_ErrorBudgetExceeded(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 5, PolicyUpdateTimeout: "60 minutes", PolicyMaxFailedUpdates: 2})
+	tInstance1, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "1.0.0", tApp.ID, tGroup.ID)
+	tInstance2, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "1.0.0", tApp.ID, tGroup.ID)
+	tInstance3, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.3", "1.0.0", tApp.ID, tGroup.ID)
+
+	_, _ = a.GetUpdatePackage(tInstance1.ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	_, _ = a.GetUpdatePackage(tInstance2.ID, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	_, _ = a.GetUpdatePackage(tInstance3.ID, "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+
+	err := a.RegisterEvent(tInstance1.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultFailed, "", "")
+	assert.NoError(t, err)
+	group, _ := a.GetGroup(tGroup.ID)
+	assert.Equal(t, true, group.PolicyUpdatesEnabled, "Error budget not exhausted yet.")
+
+	err = a.RegisterEvent(tInstance2.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultFailed, "", "")
+	assert.NoError(t, err)
+	group, _ = a.GetGroup(tGroup.ID)
+	assert.Equal(t, false, group.PolicyUpdatesEnabled, "Max failed updates reached.")
+	assert.Equal(t, false, group.RolloutInProgress)
+}
+
+func TestRegisterEvent_TriggerEventConsequences_ErrorBudgetPercentageExceeded(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 5, PolicyUpdateTimeout: "60 minutes", PolicyMaxFailedUpdatesPercentage: 50})
+	tInstance1, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "1.0.0", tApp.ID, tGroup.ID)
+	tInstance2, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "1.0.0", tApp.ID, tGroup.ID)
+	tInstance3, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.3", "1.0.0", tApp.ID, tGroup.ID)
+
+	_, _ = a.GetUpdatePackage(tInstance1.ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	_, _ = a.GetUpdatePackage(tInstance2.ID, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	_, _ = a.GetUpdatePackage(tInstance3.ID, "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+
+	err := a.RegisterEvent(tInstance1.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultSuccessReboot, "", "")
+	assert.NoError(t, err)
+	err = a.RegisterEvent(tInstance2.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultSuccessReboot, "", "")
+	assert.NoError(t, err)
+
+	err = a.RegisterEvent(tInstance3.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultFailed, "", "")
+	assert.NoError(t, err)
+	group, _ := a.GetGroup(tGroup.ID)
+	assert.Equal(t, true, group.PolicyUpdatesEnabled, "Failure rate (33%) below error budget.")
+}
+
+func TestErrorBudgetExceeded(t *testing.T) {
+	group := &Group{PolicyMaxFailedUpdatesPercentage: 30}
+
+	assert.False(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 10, UpdatesToCurrentVersionFailed: 3}))
+	assert.True(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 10, UpdatesToCurrentVersionFailed: 4}))
+	assert.False(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 10, UpdatesToCurrentVersionFailed: 0}))
+
+	// The percentage is evaluated from the first failure
+	assert.True(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 1, UpdatesToCurrentVersionFailed: 1}))
+	assert.True(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 3, UpdatesToCurrentVersionFailed: 1}))
+	assert.False(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 4, UpdatesToCurrentVersionFailed: 1}))
+
+	group = &Group{PolicyMaxFailedUpdates: 3}
+	assert.False(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 100, UpdatesToCurrentVersionFailed: 2}))
+	assert.True(t, errorBudgetExceeded(group, &UpdatesStats{UpdatesToCurrentVersionAttempted: 100, UpdatesToCurrentVersionFailed: 3}))
+}
+
+func TestRegisterEvent_TriggerEventConsequences_RollbackOnFailure(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.0.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg1.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 5, PolicyUpdateTimeout: "60 minutes", PolicyRollbackOnFailure: true})
+	tInstance1, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	tInstance2, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+
+	tChannel.PackageID = dat.NullStringFrom(tPkg2.ID)
+	_ = a.UpdateChannel(tChannel)
+
+	_, err := a.GetUpdatePackage(tInstance1.ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	_, err = a.GetUpdatePackage(tInstance2.ID, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	err = a.RegisterEvent(tInstance1.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultFailed, "", "")
+	assert.NoError(t, err)
+
+	channel, _ := a.GetChannel(tChannel.ID)
+	assert.Equal(t, tPkg1.ID, channel.PackageID.String, "Channel rolled back to the last known good package.")
+	assert.Equal(t, dat.NullStringFrom("12.1.0"), channel.RolledBackVersion)
+	group, _ := a.GetGroup(tGroup.ID)
+	assert.Equal(t, false, group.PolicyUpdatesEnabled, "Updates must be enabled again by the user to roll back instances.")
+
+	group.PolicyUpdatesEnabled = true
+	err = a.UpdateGroup(group)
+	assert.NoError(t, err)
+
+	err = a.RegisterEvent(tInstance2.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultSuccessReboot, "", "")
+	assert.NoError(t, err)
+
+	pkg, err := a.GetUpdatePackage(tInstance2.ID, "10.0.0.2", "12.1.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err, "Downgrade allowed for instances running the rolled back version.")
+	assert.Equal(t, "12.0.0", pkg.Version)
+
+	tChannel2, _ := a.AddChannel(&Channel{Name: "test_channel2", Color: "red", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg2.ID)})
+	tGroup2, _ := a.AddGroup(&Group{Name: "group2", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel2.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 5, PolicyUpdateTimeout: "60 minutes", PolicyRollbackOnFailure: true})
+	tInstance3, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup2.ID)
+
+	_, _ = a.GetUpdatePackage(tInstance3.ID, "10.0.0.3", "12.0.0", tApp.ID, tGroup2.ID)
+	err = a.RegisterEvent(tInstance3.ID, tApp.ID, tGroup2.ID, EventUpdateComplete, ResultFailed, "", "")
+	assert.NoError(t, err)
+
+	channel, _ = a.GetChannel(tChannel2.ID)
+	assert.Equal(t, tPkg2.ID, channel.PackageID.String, "No known good package in the channel's history.")
+	group, _ = a.GetGroup(tGroup2.ID)
+	assert.Equal(t, false, group.PolicyUpdatesEnabled)
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
+)
+
+var (
+	// ErrInvalidGateGroup error indicates that the group a group's rollout is
+	// gated on doesn't belong to the same application, is the group itself or
+	// would create a cycle of gated groups.
+	ErrInvalidGateGroup = errors.New("coreroller: invalid gate group")
+
+	// ErrInvalidGatePolicy error indicates that the success percentage or the
+	// soak time provided for the rollout gate are not valid.
+	ErrInvalidGatePolicy = errors.New("coreroller: invalid gate policy")
+
+	// ErrRolloutGateClosed indicates that the group is gated on another group
+	// that hasn't rolled out successfully the version yet.
+	ErrRolloutGateClosed = errors.New("coreroller: rollout gate closed")
+)
+
+// GateStatus represents the status of the rollout gate of a group, which is
+// gated on the rollout of the same version in an upstream group. The gate opens
+// when the percentage of instances in the upstream group that have been
+// updated successfully to the version reaches the success percentage required
+// and the soak time has passed since then.
+type GateStatus struct {
+	GroupID           string       `db:"group_id" json:"group_id"`
+	Version           string       `db:"version" json:"version"`
+	SuccessPercentage float64      `db:"success_percentage" json:"success_percentage"`
+	SoakEndsTs        dat.NullTime `db:"soak_ends_ts" json:"soak_ends_ts"`
+	Open              bool         `db:"open" json:"open"`
+}
+
+// validateGatePolicy checks that the group the group provided is gated on (if
+// any) belongs to the same application and that the gate doesn't create a
+// cycle, as well as the gate's success percentage and soak time.
+func (api *API) validateGatePolicy(group *Group, appID string) error {
+	if !group.PolicyGateGroupID.Valid {
+		return nil
+	}
+
+	if group.PolicyGateSuccessPercentage.Valid {
+		if group.PolicyGateSuccessPercentage.Float64 < 0 || group.PolicyGateSuccessPercentage.Float64 > 100 {
+			return ErrInvalidGatePolicy
+		}
+	}
+
+	if group.PolicyGateSoakTime.Valid {
+		var validSoakTime bool
+		err := api.dbR.SQL("SELECT $1::interval >= interval '0'", group.PolicyGateSoakTime.String).QueryScalar(&validSoakTime)
+		if err != nil || !validSoakTime {
+			return ErrInvalidGatePolicy
+		}
+	}
+
+	visited := map[string]bool{group.ID: true}
+	gateGroupID := group.PolicyGateGroupID.String
+	for gateGroupID != "" {
+		if visited[gateGroupID] {
+			return ErrInvalidGateGroup
+		}
+		visited[gateGroupID] = true
+
+		gateGroup, err := api.GetGroup(gateGroupID)
+		if err != nil || gateGroup.ApplicationID != appID {
+			return ErrInvalidGateGroup
+		}
+		gateGroupID = gateGroup.PolicyGateGroupID.String
+	}
+
+	return nil
+}
+
+// gateRollout identifies the rollout of a version in the upstream group of a
+// rollout gate.
+type gateRollout struct {
+	GroupID string
+	Version string
+}
+
+// setGroupGateStatus sets the status of the rollout gate of the group provided
+// for the version its channel is pointing to, if the group is gated on another
+// group.
+func (api *API) setGroupGateStatus(db runner.Connection, group *Group) error {
+	return api.setGroupsGateStatus(db, []*Group{group})
+}
+
+// setGroupsGateStatus sets the status of the rollout gates of the groups
+// provided for the version their channels are pointing to, for the ones gated
+// on another group. The gates of all groups are evaluated using the same
+// queries, based on the updates stats of their upstream groups.
+func (api *API) setGroupsGateStatus(db runner.Connection, groups []*Group) error {
+	var gated []*Group
+	for _, group := range groups {
+		if group.PolicyGateGroupID.Valid && group.Channel != nil && group.Channel.Package != nil {
+			gated = append(gated, group)
+		}
+	}
+	if len(gated) == 0 {
+		return nil
+	}
+
+	rollouts := make([]gateRollout, len(gated))
+	for i, group := range gated {
+		rollouts[i] = gateRollout{GroupID: group.PolicyGateGroupID.String, Version: group.Channel.Package.Version}
+	}
+
+	updatesStats, err := api.getGateUpdatesStats(db, rollouts)
+	if err != nil {
+		return err
+	}
+
+	var soaking []*Group
+	requiredInstances := make(map[string]int)
+
+	for i, group := range gated {
+		gateStatus := &GateStatus{
+			GroupID: rollouts[i].GroupID,
+			Version: rollouts[i].Version,
+		}
+		group.Gate = gateStatus
+
+		stats, ok := updatesStats[rollouts[i]]
+		if !ok || stats.TotalInstances == 0 {
+			continue
+		}
+		gateStatus.SuccessPercentage = float64(stats.UpdatesToCurrentVersionSucceeded) * 100 / float64(stats.TotalInstances)
+
+		requiredPercentage := float64(100)
+		if group.PolicyGateSuccessPercentage.Valid {
+			requiredPercentage = group.PolicyGateSuccessPercentage.Float64
+		}
+		if gateStatus.SuccessPercentage < requiredPercentage {
+			continue
+		}
+
+		// The soak time starts when the upstream group reached the success
+		// percentage required, that is, when the update of the nth instance
+		// to the version succeeded.
+		required := int(math.Ceil(requiredPercentage * float64(stats.TotalInstances) / 100))
+		if required < 1 {
+			required = 1
+		}
+		requiredInstances[group.ID] = required
+		soaking = append(soaking, group)
+	}
+
+	if len(soaking) == 0 {
+		return nil
+	}
+
+	soakEnds, err := api.getGatesSoakEnds(db, soaking, requiredInstances)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, group := range soaking {
+		if soakEndsTs, ok := soakEnds[group.ID]; ok {
+			group.Gate.SoakEndsTs = dat.NullTimeFrom(soakEndsTs)
+			group.Gate.Open = !soakEndsTs.After(now)
+		}
+	}
+
+	return nil
+}
+
+// getGateUpdatesStats returns the number of instances of the upstream groups
+// of the rollouts provided and how many of them have been updated successfully
+// to the rollout's version. Unlike getGroupVersionUpdatesStats, it doesn't need
+// the upstream groups' policies, so it's cheap enough to be used for every
+// group listed or update check processed.
+func (api *API) getGateUpdatesStats(db runner.Connection, rollouts []gateRollout) (map[gateRollout]*UpdatesStats, error) {
+	var values []string
+	var args []interface{}
+	seen := make(map[gateRollout]bool, len(rollouts))
+	for _, rollout := range rollouts {
+		if seen[rollout] {
+			continue
+		}
+		seen[rollout] = true
+
+		values = append(values, fmt.Sprintf("($%d::uuid, $%d)", len(args)+1, len(args)+2))
+		args = append(args, rollout.GroupID, rollout.Version)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT
+		r.group_id,
+		r.version,
+		count(ia.instance_id) total_instances,
+		coalesce(sum(case when ia.update_in_progress = 'false' and ia.last_update_version = r.version and ia.last_update_version = ia.version then 1 else 0 end), 0) updates_to_current_version_succeeded
+	FROM (VALUES %s) AS r(group_id, version)
+	LEFT JOIN instance_application ia ON ia.group_id = r.group_id AND ia.last_check_for_updates > now() at time zone 'utc' - interval '%s'
+	GROUP BY r.group_id, r.version
+	`, strings.Join(values, ", "), validityInterval)
+
+	var rows []*struct {
+		GroupID string `db:"group_id"`
+		Version string `db:"version"`
+		UpdatesStats
+	}
+	if err := db.SQL(query, args...).QueryStructs(&rows); err != nil {
+		return nil, err
+	}
+
+	updatesStats := make(map[gateRollout]*UpdatesStats, len(rows))
+	for _, row := range rows {
+		stats := row.UpdatesStats
+		updatesStats[gateRollout{GroupID: row.GroupID, Version: row.Version}] = &stats
+	}
+
+	return updatesStats, nil
+}
+
+// getGatesSoakEnds returns when the soak time of the rollout gates of the
+// groups provided ends, which is the gate's soak time after the update of the
+// nth instance of the upstream group to the version succeeded (n being the
+// number of instances required to reach the gate's success percentage).
+func (api *API) getGatesSoakEnds(db runner.Connection, groups []*Group, requiredInstances map[string]int) (map[string]time.Time, error) {
+	values := make([]string, len(groups))
+	args := []interface{}{InstanceStatusComplete}
+	for i, group := range groups {
+		soakTime := "0 seconds"
+		if group.PolicyGateSoakTime.Valid {
+			soakTime = group.PolicyGateSoakTime.String
+		}
+
+		n := len(args)
+		values[i] = fmt.Sprintf("($%d::uuid, $%d::uuid, $%d, $%d::integer, $%d::interval)", n+1, n+2, n+3, n+4, n+5)
+		args = append(args, group.ID, group.PolicyGateGroupID.String, group.Channel.Package.Version, requiredInstances[group.ID], soakTime)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT g.group_id, h.created_ts + g.soak_time soak_ends_ts
+	FROM (VALUES %s) AS g(group_id, gate_group_id, version, required_instances, soak_time),
+	LATERAL (
+		SELECT created_ts, row_number() OVER (ORDER BY created_ts ASC) position
+		FROM instance_status_history
+		WHERE group_id = g.gate_group_id AND version = g.version AND status = $1
+	) h
+	WHERE h.position = g.required_instances
+	`, strings.Join(values, ", "))
+
+	var rows []*struct {
+		GroupID    string    `db:"group_id"`
+		SoakEndsTs time.Time `db:"soak_ends_ts"`
+	}
+	if err := db.SQL(query, args...).QueryStructs(&rows); err != nil {
+		return nil, err
+	}
+
+	soakEnds := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		soakEnds[row.GroupID] = row.SoakEndsTs
+	}
+
+	return soakEnds, nil
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestGroupGatePolicy(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tApp2, _ := a.AddApp(&Application{Name: "test_app2", TeamID: tTeam.ID})
+	tGroup1, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes"})
+	tGroup2, _ := a.AddGroup(&Group{Name: "group2", ApplicationID: tApp2.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes"})
+
+	_, err := a.AddGroup(&Group{Name: "group3", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes", PolicyGateGroupID: dat.NullStringFrom(tGroup2.ID)})
+	assert.Equal(t, ErrInvalidGateGroup, err, "Gate group must belong to the same application.")
+
+	_, err = a.AddGroup(&Group{Name: "group3", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes", PolicyGateGroupID: dat.NullStringFrom(tGroup1.ID), PolicyGateSoakTime: dat.NullStringFrom("invalid")})
+	assert.Equal(t, ErrInvalidGatePolicy, err)
+
+	_, err = a.AddGroup(&Group{Name: "group3", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes", PolicyGateGroupID: dat.NullStringFrom(tGroup1.ID), PolicyGateSuccessPercentage: dat.NullFloat64From(120)})
+	assert.Equal(t, ErrInvalidGatePolicy, err)
+
+	tGroup3, err := a.AddGroup(&Group{Name: "group3", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes", PolicyGateGroupID: dat.NullStringFrom(tGroup1.ID), PolicyGateSuccessPercentage: dat.NullFloat64From(50), PolicyGateSoakTime: dat.NullStringFrom("1 hour")})
+	assert.NoError(t, err)
+	group3, _ := a.GetGroup(tGroup3.ID)
+	assert.Equal(t, dat.NullStringFrom(tGroup1.ID), group3.PolicyGateGroupID)
+	assert.Equal(t, dat.NullFloat64From(50), group3.PolicyGateSuccessPercentage)
+	assert.Equal(t, dat.NullStringFrom("1 hour"), group3.PolicyGateSoakTime)
+
+	tGroup1.PolicyGateGroupID = dat.NullStringFrom(tGroup3.ID)
+	err = a.UpdateGroup(tGroup1)
+	assert.Equal(t, ErrInvalidGateGroup, err, "Gates can't create cycles.")
+
+	tGroup1.PolicyGateGroupID = dat.NullStringFrom(tGroup1.ID)
+	err = a.UpdateGroup(tGroup1)
+	assert.Equal(t, ErrInvalidGateGroup, err, "A group can't be gated on itself.")
+}
+
+func TestGetUpdatePackage_RolloutGate(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tCanary, _ := a.AddGroup(&Group{Name: "canary", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+	tProd, _ := a.AddGroup(&Group{Name: "prod", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes", PolicyGateGroupID: dat.NullStringFrom(tCanary.ID), PolicyGateSuccessPercentage: dat.NullFloat64From(50)})
+
+	tInstance1, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tCanary.ID)
+	tInstance2, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tCanary.ID)
+	tInstance3, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tProd.ID)
+
+	_, err := a.GetUpdatePackage(tInstance3.ID, "10.0.0.3", "12.0.0", tApp.ID, tProd.ID)
+	assert.Equal(t, ErrRolloutGateClosed, err)
+
+	group, _ := a.GetGroup(tProd.ID)
+	assert.NotNil(t, group.Gate)
+	assert.Equal(t, tCanary.ID, group.Gate.GroupID)
+	assert.Equal(t, "12.1.0", group.Gate.Version)
+	assert.False(t, group.Gate.Open)
+
+	_, _ = a.GetUpdatePackage(tInstance1.ID, "10.0.0.1", "12.0.0", tApp.ID, tCanary.ID)
+	_ = a.RegisterEvent(tInstance1.ID, tApp.ID, tCanary.ID, EventUpdateComplete, ResultSuccessReboot, "", "")
+
+	group, _ = a.GetGroup(tProd.ID)
+	assert.Equal(t, float64(50), group.Gate.SuccessPercentage)
+	assert.True(t, group.Gate.SoakEndsTs.Valid)
+	assert.True(t, group.Gate.Open)
+
+	_, err = a.GetUpdatePackage(tInstance3.ID, "10.0.0.3", "12.0.0", tApp.ID, tProd.ID)
+	assert.NoError(t, err)
+
+	tProd.PolicyGateSoakTime = dat.NullStringFrom("1 hour")
+	_ = a.UpdateGroup(tProd)
+
+	group, _ = a.GetGroup(tProd.ID)
+	assert.False(t, group.Gate.Open, "Soak time hasn't passed yet.")
+
+	groups, err := a.GetGroups(tApp.ID, 1, 10)
+	assert.NoError(t, err)
+	for _, group := range groups {
+		if group.ID != tProd.ID {
+			assert.Nil(t, group.Gate)
+		} else if assert.NotNil(t, group.Gate) {
+			assert.Equal(t, float64(50), group.Gate.SuccessPercentage)
+			assert.False(t, group.Gate.Open)
+		}
+	}
+
+	_, err = a.GetUpdatePackage(tInstance2.ID, "10.0.0.2", "12.0.0", tApp.ID, tCanary.ID)
+	assert.NoError(t, err, "Upstream group is not gated.")
+}
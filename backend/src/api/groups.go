@@ -3,9 +3,12 @@ package api
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
 )
 
 var (
@@ -14,29 +17,96 @@ var (
 	ErrInvalidChannel = errors.New("coreroller: invalid channel")
 
 	// ErrExpectingValidTimezone error indicates that a valid timezone wasn't
-	// provided when enabling the flag PolicyOfficeHours.
+	// provided when defining update windows for the group.
 	ErrExpectingValidTimezone = errors.New("coreroller: expecting valid timezone")
+
+	// ErrInvalidRolloutPercentage error indicates that the rollout percentage
+	// provided is not within the valid range (0-100).
+	ErrInvalidRolloutPercentage = errors.New("coreroller: invalid rollout percentage")
+
+	// ErrInvalidRolloutStage error indicates that one of the stages of the
+	// rollout ramp schedule provided has an invalid percentage or duration.
+	ErrInvalidRolloutStage = errors.New("coreroller: invalid rollout stage")
+
+	// ErrInvalidUpdateWindow error indicates that one of the update windows
+	// provided has invalid weekdays or start/end times.
+	ErrInvalidUpdateWindow = errors.New("coreroller: invalid update window")
+
+	// ErrInvalidErrorBudget error indicates that the maximum number or
+	// percentage of failed updates provided for the group is not valid.
+	ErrInvalidErrorBudget = errors.New("coreroller: invalid error budget")
+
+	// ErrInvalidUpdatesLimits error indicates that the maximum number of
+	// concurrent or timed out updates provided for the group is not valid.
+	ErrInvalidUpdatesLimits = errors.New("coreroller: invalid updates limits")
 )
 
 // Group represents a CoreRoller application's group.
 type Group struct {
-	ID                        string                   `db:"id" json:"id"`
-	Name                      string                   `db:"name" json:"name"`
-	Description               string                   `db:"description" json:"description"`
-	CreatedTs                 time.Time                `db:"created_ts" json:"created_ts"`
-	RolloutInProgress         bool                     `db:"rollout_in_progress" json:"rollout_in_progress"`
-	ApplicationID             string                   `db:"application_id" json:"application_id"`
-	ChannelID                 dat.NullString           `db:"channel_id" json:"channel_id"`
-	PolicyUpdatesEnabled      bool                     `db:"policy_updates_enabled" json:"policy_updates_enabled"`
-	PolicySafeMode            bool                     `db:"policy_safe_mode" json:"policy_safe_mode"`
-	PolicyOfficeHours         bool                     `db:"policy_office_hours" json:"policy_office_hours"`
-	PolicyTimezone            dat.NullString           `db:"policy_timezone" json:"policy_timezone"`
-	PolicyPeriodInterval      string                   `db:"policy_period_interval" json:"policy_period_interval"`
-	PolicyMaxUpdatesPerPeriod int                      `db:"policy_max_updates_per_period" json:"policy_max_updates_per_period"`
-	PolicyUpdateTimeout       string                   `db:"policy_update_timeout" json:"policy_update_timeout"`
-	VersionBreakdown          []*VersionBreakdownEntry `db:"version_breakdown" json:"version_breakdown,omitempty"`
-	Channel                   *Channel                 `db:"channel" json:"channel,omitempty"`
-	InstancesStats            InstancesStatusStats     `db:"instances_stats" json:"instances_stats,omitempty"`
+	ID                                 string                   `db:"id" json:"id"`
+	Name                               string                   `db:"name" json:"name"`
+	Description                        string                   `db:"description" json:"description"`
+	CreatedTs                          time.Time                `db:"created_ts" json:"created_ts"`
+	RolloutInProgress                  bool                     `db:"rollout_in_progress" json:"rollout_in_progress"`
+	ApplicationID                      string                   `db:"application_id" json:"application_id"`
+	ChannelID                          dat.NullString           `db:"channel_id" json:"channel_id"`
+	PolicyUpdatesEnabled               bool                     `db:"policy_updates_enabled" json:"policy_updates_enabled"`
+	PolicySafeMode                     bool                     `db:"policy_safe_mode" json:"policy_safe_mode"`
+	PolicyTimezone                     dat.NullString           `db:"policy_timezone" json:"policy_timezone"`
+	PolicyUpdateWindows                []*UpdateWindow          `db:"policy_update_windows" json:"policy_update_windows"`
+	PolicyPeriodInterval               string                   `db:"policy_period_interval" json:"policy_period_interval"`
+	PolicyMaxUpdatesPerPeriod          int                      `db:"policy_max_updates_per_period" json:"policy_max_updates_per_period"`
+	PolicyUpdateTimeout                string                   `db:"policy_update_timeout" json:"policy_update_timeout"`
+	PolicyMaxConcurrentUpdates         int                      `db:"policy_max_concurrent_updates" json:"policy_max_concurrent_updates"`
+	PolicyMaxTimedOutUpdates           int                      `db:"policy_max_timed_out_updates" json:"policy_max_timed_out_updates"`
+	PolicyTimedOutMarkFailed           bool                     `db:"policy_timed_out_mark_failed" json:"policy_timed_out_mark_failed"`
+	PolicyCriticalUpdates              bool                     `db:"policy_critical_updates" json:"policy_critical_updates"`
+	PolicyCriticalMaxConcurrentUpdates int                      `db:"policy_critical_max_concurrent_updates" json:"policy_critical_max_concurrent_updates"`
+	PolicyRolloutPercentage            dat.NullFloat64          `db:"policy_rollout_percentage" json:"policy_rollout_percentage"`
+	PolicyRolloutStages                []*RolloutStage          `db:"policy_rollout_stages" json:"policy_rollout_stages"`
+	PolicyMaxFailedUpdates             int                      `db:"policy_max_failed_updates" json:"policy_max_failed_updates"`
+	PolicyMaxFailedUpdatesPercentage   float64                  `db:"policy_max_failed_updates_percentage" json:"policy_max_failed_updates_percentage"`
+	PolicyRollbackOnFailure            bool                     `db:"policy_rollback_on_failure" json:"policy_rollback_on_failure"`
+	PolicyExactVersion                 bool                     `db:"policy_exact_version" json:"policy_exact_version"`
+	PolicyGateGroupID                  dat.NullString           `db:"policy_gate_group_id" json:"policy_gate_group_id"`
+	PolicyGateSuccessPercentage        dat.NullFloat64          `db:"policy_gate_success_percentage" json:"policy_gate_success_percentage"`
+	PolicyGateSoakTime                 dat.NullString           `db:"policy_gate_soak_time" json:"policy_gate_soak_time"`
+	PolicyLabelSelector                []*Label                 `db:"policy_label_selector" json:"policy_label_selector"`
+	VersionScheme                      string                   `db:"version_scheme" json:"version_scheme,omitempty"`
+	RetractedVersions                  []string                 `db:"retracted_versions" json:"retracted_versions,omitempty"`
+	Gate                               *GateStatus              `db:"gate" json:"gate,omitempty"`
+	RolloutStage                       *RolloutStageStatus      `db:"rollout_stage" json:"rollout_stage,omitempty"`
+	VersionBreakdown                   []*VersionBreakdownEntry `db:"version_breakdown" json:"version_breakdown,omitempty"`
+	Channel                            *Channel                 `db:"channel" json:"channel,omitempty"`
+	InstancesStats                     InstancesStatusStats     `db:"instances_stats" json:"instances_stats,omitempty"`
+}
+
+// UpdateWindow represents a recurring period of time in which the instances of
+// a group are allowed to be updated. Weekdays go from 0 (Sunday) to 6
+// (Saturday) and times are interpreted in the group's timezone. When the end
+// time is before the start time, the window ends on the next day.
+type UpdateWindow struct {
+	Weekdays  []int  `db:"weekdays" json:"weekdays"`
+	StartTime string `db:"start_time" json:"start_time"`
+	EndTime   string `db:"end_time" json:"end_time"`
+}
+
+// RolloutStage represents a stage in the rollout ramp schedule of a group. The
+// stage's percentage of instances will be allowed to get the update during the
+// duration of the stage, moving on to the next stage after that.
+type RolloutStage struct {
+	Percentage float64 `db:"percentage" json:"percentage"`
+	Duration   string  `db:"duration" json:"duration"`
+}
+
+// RolloutStageStatus represents the stage of the rollout ramp schedule a group
+// is currently in. The stages start when the group's channel is updated to
+// point to a new package. The last stage doesn't end.
+type RolloutStageStatus struct {
+	Stage      int          `db:"stage" json:"stage"`
+	Percentage float64      `db:"percentage" json:"percentage"`
+	StartedTs  time.Time    `db:"started_ts" json:"started_ts"`
+	EndsTs     dat.NullTime `db:"ends_ts" json:"ends_ts"`
 }
 
 // VersionBreakdownEntry represents the distribution of the versions currently
@@ -59,6 +129,7 @@ type InstancesStatusStats struct {
 	Downloaded    int `db:"downloaded" json:"downloaded"`
 	Downloading   int `db:"downloading" json:"downloading"`
 	OnHold        int `db:"onhold" json:"onhold"`
+	TimedOut      int `db:"timed_out" json:"timed_out"`
 }
 
 // UpdatesStats represents a set of statistics about the status of the updates
@@ -76,8 +147,28 @@ type UpdatesStats struct {
 
 // AddGroup registers the provided group.
 func (api *API) AddGroup(group *Group) (*Group, error) {
-	if group.PolicyOfficeHours && !isTimezoneValid(group.PolicyTimezone.String) {
-		return nil, ErrExpectingValidTimezone
+	if err := validateUpdateWindows(group); err != nil {
+		return nil, err
+	}
+
+	if err := api.validateRolloutPolicy(group); err != nil {
+		return nil, err
+	}
+
+	if err := validateErrorBudget(group); err != nil {
+		return nil, err
+	}
+
+	if err := validateUpdatesLimits(group); err != nil {
+		return nil, err
+	}
+
+	if err := validateLabelSelector(group); err != nil {
+		return nil, err
+	}
+
+	if err := api.validateGatePolicy(group, group.ApplicationID); err != nil {
+		return nil, err
 	}
 
 	if group.ChannelID.String != "" {
@@ -86,22 +177,70 @@ func (api *API) AddGroup(group *Group) (*Group, error) {
 		}
 	}
 
-	err := api.dbR.
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	err = tx.
 		InsertInto("groups").
-		Whitelist("name", "description", "application_id", "channel_id", "policy_updates_enabled", "policy_safe_mode", "policy_office_hours",
-			"policy_timezone", "policy_period_interval", "policy_max_updates_per_period", "policy_update_timeout").
+		Whitelist("name", "description", "application_id", "channel_id", "policy_updates_enabled", "policy_safe_mode", "policy_timezone",
+			"policy_period_interval", "policy_max_updates_per_period", "policy_update_timeout", "policy_rollout_percentage",
+			"policy_max_failed_updates", "policy_max_failed_updates_percentage", "policy_rollback_on_failure",
+			"policy_exact_version", "policy_gate_group_id", "policy_gate_success_percentage", "policy_gate_soak_time",
+			"policy_max_concurrent_updates", "policy_max_timed_out_updates", "policy_timed_out_mark_failed",
+			"policy_critical_updates", "policy_critical_max_concurrent_updates").
 		Record(group).
 		Returning("*").
 		QueryStruct(group)
 
-	return group, err
+	if err != nil {
+		return nil, err
+	}
+
+	if err := api.updateGroupRolloutStages(tx, group); err != nil {
+		return nil, err
+	}
+
+	if err := api.updateGroupUpdateWindows(tx, group); err != nil {
+		return nil, err
+	}
+
+	if err := api.updateGroupLabelSelector(tx, group); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return group, nil
 }
 
 // UpdateGroup updates an existing group using the context of the group
 // provided.
 func (api *API) UpdateGroup(group *Group) error {
-	if group.PolicyOfficeHours && !isTimezoneValid(group.PolicyTimezone.String) {
-		return ErrExpectingValidTimezone
+	if err := validateUpdateWindows(group); err != nil {
+		return err
+	}
+
+	if err := api.validateRolloutPolicy(group); err != nil {
+		return err
+	}
+
+	if err := validateErrorBudget(group); err != nil {
+		return err
+	}
+
+	if err := validateUpdatesLimits(group); err != nil {
+		return err
+	}
+
+	if err := validateLabelSelector(group); err != nil {
+		return err
 	}
 
 	groupBeforeUpdate, err := api.GetGroup(group.ID)
@@ -115,18 +254,52 @@ func (api *API) UpdateGroup(group *Group) error {
 		}
 	}
 
-	result, err := api.dbR.
+	if err := api.validateGatePolicy(group, groupBeforeUpdate.ApplicationID); err != nil {
+		return err
+	}
+
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	result, err := tx.
 		Update("groups").
-		SetWhitelist(group, "name", "description", "channel_id", "policy_updates_enabled", "policy_safe_mode", "policy_office_hours",
-			"policy_timezone", "policy_period_interval", "policy_max_updates_per_period", "policy_update_timeout").
+		SetWhitelist(group, "name", "description", "channel_id", "policy_updates_enabled", "policy_safe_mode", "policy_timezone",
+			"policy_period_interval", "policy_max_updates_per_period", "policy_update_timeout", "policy_rollout_percentage",
+			"policy_max_failed_updates", "policy_max_failed_updates_percentage", "policy_rollback_on_failure",
+			"policy_exact_version", "policy_gate_group_id", "policy_gate_success_percentage", "policy_gate_soak_time",
+			"policy_max_concurrent_updates", "policy_max_timed_out_updates", "policy_timed_out_mark_failed",
+			"policy_critical_updates", "policy_critical_max_concurrent_updates").
 		Where("id = $1", group.ID).
 		Exec()
 
-	if err == nil && result.RowsAffected == 0 {
+	if err != nil {
+		return err
+	} else if result.RowsAffected == 0 {
 		return ErrNoRowsAffected
 	}
 
-	return err
+	if err := api.updateGroupRolloutStages(tx, group); err != nil {
+		return err
+	}
+
+	if err := api.updateGroupUpdateWindows(tx, group); err != nil {
+		return err
+	}
+
+	if err := api.updateGroupLabelSelector(tx, group); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // DeleteGroup removes the group identified by the id provided.
@@ -155,6 +328,10 @@ func (api *API) GetGroup(groupID string) (*Group, error) {
 		return nil, err
 	}
 
+	if err := api.setGroupGateStatus(api.dbR, &group); err != nil {
+		return nil, err
+	}
+
 	return &group, nil
 }
 
@@ -169,7 +346,15 @@ func (api *API) GetGroups(appID string, page, perPage uint64) ([]*Group, error)
 		Paginate(page, perPage).
 		QueryStructs(&groups)
 
-	return groups, err
+	if err != nil {
+		return nil, err
+	}
+
+	if err := api.setGroupsGateStatus(api.dbR, groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
 }
 
 // validateChannel checks if a channel belongs to the application provided.
@@ -184,16 +369,189 @@ func (api *API) validateChannel(channelID, appID string) error {
 	return nil
 }
 
+// validateUpdateWindows checks that the update windows of the group provided
+// are valid and that the group has a valid timezone to interpret them.
+func validateUpdateWindows(group *Group) error {
+	if len(group.PolicyUpdateWindows) == 0 {
+		return nil
+	}
+
+	if !isTimezoneValid(group.PolicyTimezone.String) {
+		return ErrExpectingValidTimezone
+	}
+
+	for _, window := range group.PolicyUpdateWindows {
+		if len(window.Weekdays) == 0 {
+			return ErrInvalidUpdateWindow
+		}
+		for _, weekday := range window.Weekdays {
+			if weekday < int(time.Sunday) || weekday > int(time.Saturday) {
+				return ErrInvalidUpdateWindow
+			}
+		}
+
+		start, err := parseTimeOfDay(window.StartTime)
+		if err != nil {
+			return ErrInvalidUpdateWindow
+		}
+		end, err := parseTimeOfDay(window.EndTime)
+		if err != nil || start == end {
+			return ErrInvalidUpdateWindow
+		}
+	}
+
+	return nil
+}
+
+// updateGroupUpdateWindows replaces the update windows of the group provided
+// with the ones set in the group entry.
+//
+// This method is part of the transaction that adds or updates a group.
+func (api *API) updateGroupUpdateWindows(tx *runner.Tx, group *Group) error {
+	_, err := tx.DeleteFrom("group_update_window").
+		Where("group_id = $1", group.ID).
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	for _, window := range group.PolicyUpdateWindows {
+		weekdays := make([]string, len(window.Weekdays))
+		for i, weekday := range window.Weekdays {
+			weekdays[i] = strconv.Itoa(weekday)
+		}
+
+		_, err := tx.InsertInto("group_update_window").
+			Columns("weekdays", "start_time", "end_time", "group_id").
+			Values("{"+strings.Join(weekdays, ",")+"}", window.StartTime, window.EndTime, group.ID).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateErrorBudget checks that the maximum number and percentage of failed
+// updates allowed for the group provided are within the valid ranges.
+func validateErrorBudget(group *Group) error {
+	if group.PolicyMaxFailedUpdates < 0 {
+		return ErrInvalidErrorBudget
+	}
+
+	if group.PolicyMaxFailedUpdatesPercentage < 0 || group.PolicyMaxFailedUpdatesPercentage > 100 {
+		return ErrInvalidErrorBudget
+	}
+
+	return nil
+}
+
+// validateUpdatesLimits checks that the maximum number of concurrent and timed
+// out updates of the group provided are valid. A zero value means that the
+// maximum number of updates per period is used for the limit (or that there is
+// no limit, for the maximum number of concurrent critical updates).
+func validateUpdatesLimits(group *Group) error {
+	if group.PolicyMaxConcurrentUpdates < 0 || group.PolicyMaxTimedOutUpdates < 0 || group.PolicyCriticalMaxConcurrentUpdates < 0 {
+		return ErrInvalidUpdatesLimits
+	}
+
+	return nil
+}
+
+// validateRolloutPolicy checks that the rollout percentage and the stages of
+// the rollout ramp schedule of the group provided are valid.
+func (api *API) validateRolloutPolicy(group *Group) error {
+	if group.PolicyRolloutPercentage.Valid {
+		if group.PolicyRolloutPercentage.Float64 < 0 || group.PolicyRolloutPercentage.Float64 > 100 {
+			return ErrInvalidRolloutPercentage
+		}
+	}
+
+	if len(group.PolicyRolloutStages) == 0 {
+		return nil
+	}
+
+	// The durations of all stages are checked in a single query.
+	values := make([]string, len(group.PolicyRolloutStages))
+	durations := make([]interface{}, len(group.PolicyRolloutStages))
+	for i, stage := range group.PolicyRolloutStages {
+		if stage == nil || stage.Percentage < 0 || stage.Percentage > 100 {
+			return ErrInvalidRolloutStage
+		}
+		values[i] = fmt.Sprintf("($%d::interval)", i+1)
+		durations[i] = stage.Duration
+	}
+
+	var validDurations bool
+	query := fmt.Sprintf("SELECT bool_and(duration > interval '0') FROM (VALUES %s) AS stages(duration)", strings.Join(values, ", "))
+	err := api.dbR.SQL(query, durations...).QueryScalar(&validDurations)
+	if err != nil || !validDurations {
+		return ErrInvalidRolloutStage
+	}
+
+	return nil
+}
+
+// updateGroupRolloutStages replaces the stages of the rollout ramp schedule of
+// the group provided with the ones set in the group entry.
+//
+// This method is part of the transaction that adds or updates a group.
+func (api *API) updateGroupRolloutStages(tx *runner.Tx, group *Group) error {
+	_, err := tx.DeleteFrom("group_rollout_stage").
+		Where("group_id = $1", group.ID).
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	for i, stage := range group.PolicyRolloutStages {
+		_, err := tx.InsertInto("group_rollout_stage").
+			Columns("position", "percentage", "duration", "group_id").
+			Values(i+1, stage.Percentage, stage.Duration, group.ID).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockGroup locks the group identified by the id provided until the end of the
+// transaction given. It's used to serialize the processing of the updates
+// requests of the instances in the group, which must be granted one at a time
+// for the limits of the rollout policy to hold. The lock doesn't conflict with
+// the ones taken to check foreign keys, so instances can still be registered.
+func (api *API) lockGroup(tx *runner.Tx, groupID string) error {
+	var id string
+
+	return tx.
+		SQL("SELECT id FROM groups WHERE id = $1 FOR NO KEY UPDATE", groupID).
+		QueryScalar(&id)
+}
+
 // getGroupUpdatesStats returns a set of statistics about the distribution of
 // updates and their status in the group provided.
-func (api *API) getGroupUpdatesStats(group *Group) (*UpdatesStats, error) {
-	var updatesStats UpdatesStats
-
+func (api *API) getGroupUpdatesStats(db runner.Connection, group *Group) (*UpdatesStats, error) {
 	packageVersion := ""
 	if group.Channel.Package != nil {
 		packageVersion = group.Channel.Package.Version
 	}
 
+	return api.getGroupVersionUpdatesStats(db, group, packageVersion)
+}
+
+// getGroupVersionUpdatesStats returns a set of statistics about the
+// distribution of updates and their status in the group provided, considering
+// the version given as the current version.
+func (api *API) getGroupVersionUpdatesStats(db runner.Connection, group *Group, packageVersion string) (*UpdatesStats, error) {
+	var updatesStats UpdatesStats
+
 	query := fmt.Sprintf(`
 	SELECT
 		count(*) total_instances,
@@ -203,12 +561,12 @@ func (api *API) getGroupUpdatesStats(group *Group) (*UpdatesStats, error) {
 		sum(case when update_in_progress = 'false' and last_update_version = $1 and last_update_version != version then 1 else 0 end) updates_to_current_version_failed, 
 		sum(case when last_update_granted_ts > now() at time zone 'utc' - interval $2 then 1 else 0 end) updates_granted_in_last_period,
 		sum(case when update_in_progress = 'true' and now() at time zone 'utc' - last_update_granted_ts <= interval $3 then 1 else 0 end) updates_in_progress,
-		sum(case when update_in_progress = 'true' and now() at time zone 'utc' - last_update_granted_ts > interval $4 then 1 else 0 end) updates_timed_out
+		sum(case when (update_in_progress = 'true' and now() at time zone 'utc' - last_update_granted_ts > interval $4) or (status = %d and last_update_version = $1) then 1 else 0 end) updates_timed_out
 	FROM instance_application
 	WHERE group_id=$5 AND last_check_for_updates > now() at time zone 'utc' - interval '%s'
-	`, validityInterval)
+	`, InstanceStatusTimedOut, validityInterval)
 
-	err := api.dbR.SQL(query, packageVersion, group.PolicyPeriodInterval, group.PolicyUpdateTimeout, group.PolicyUpdateTimeout, group.ID).
+	err := db.SQL(query, packageVersion, group.PolicyPeriodInterval, group.PolicyUpdateTimeout, group.PolicyUpdateTimeout, group.ID).
 		QueryStruct(&updatesStats)
 	if err != nil {
 		return nil, err
@@ -232,8 +590,8 @@ func (api *API) disableUpdates(groupID string) error {
 
 // setGroupRolloutInProgress updates the value of the rollout_in_progress flag
 // for a given group, indicating if a rollout is taking place now or not.
-func (api *API) setGroupRolloutInProgress(groupID string, inProgress bool) error {
-	_, err := api.dbR.
+func (api *API) setGroupRolloutInProgress(db runner.Connection, groupID string, inProgress bool) error {
+	_, err := db.
 		Update("groups").
 		Set("rollout_in_progress", inProgress).
 		Where("id = $1", groupID).
@@ -251,11 +609,59 @@ func (api *API) groupsQuery() *dat.SelectDocBuilder {
 		SelectDoc("*").
 		One("instances_stats", api.groupInstancesStatusQuery()).
 		One("channel", api.channelsQuery().Where("id = groups.channel_id")).
+		One("rollout_stage", api.groupRolloutStageQuery()).
+		Many("policy_update_windows", api.groupUpdateWindowsQuery()).
+		Many("policy_rollout_stages", api.groupRolloutStagesQuery()).
+		Many("policy_label_selector", api.groupLabelSelectorQuery()).
 		Many("version_breakdown", api.groupVersionBreakdownQuery()).
 		From("groups").
 		OrderBy("created_ts DESC")
 }
 
+// groupUpdateWindowsQuery returns a SQL query prepared to return the update
+// windows of a given group.
+func (api *API) groupUpdateWindowsQuery() string {
+	return `
+	SELECT weekdays, to_char(start_time, 'HH24:MI') as start_time, to_char(end_time, 'HH24:MI') as end_time
+	FROM group_update_window
+	WHERE group_id = groups.id
+	ORDER BY id ASC
+	`
+}
+
+// groupRolloutStagesQuery returns a SQL query prepared to return the stages of
+// the rollout ramp schedule of a given group.
+func (api *API) groupRolloutStagesQuery() string {
+	return `
+	SELECT percentage, duration
+	FROM group_rollout_stage
+	WHERE group_id = groups.id
+	ORDER BY position ASC
+	`
+}
+
+// groupRolloutStageQuery returns a SQL query prepared to return the stage of
+// the rollout ramp schedule a given group is currently in. Stages are timed
+// from the moment the group's channel started pointing to its package.
+func (api *API) groupRolloutStageQuery() string {
+	return `
+	SELECT stage, percentage, started_ts, ends_ts
+	FROM (
+		SELECT
+			grs.position as stage,
+			grs.percentage,
+			c.package_updated_ts + sum(grs.duration::interval) OVER w - grs.duration::interval as started_ts,
+			CASE WHEN grs.position = max(grs.position) OVER () THEN NULL ELSE c.package_updated_ts + sum(grs.duration::interval) OVER w END as ends_ts
+		FROM group_rollout_stage grs, channel c
+		WHERE grs.group_id = groups.id AND c.id = groups.channel_id
+		WINDOW w AS (ORDER BY grs.position)
+		) stages
+	WHERE started_ts <= now() at time zone 'utc'
+	ORDER BY stage DESC
+	LIMIT 1
+	`
+}
+
 // groupVersionBreakdownQuery returns a SQL query prepared to return the version
 // breakdown of all instances running on a given group.
 func (api *API) groupVersionBreakdownQuery() string {
@@ -267,8 +673,8 @@ func (api *API) groupVersionBreakdownQuery() string {
 		WHERE group_id=groups.id AND last_check_for_updates > now() at time zone 'utc' - interval '%s'
 		) totals
 	WHERE group_id=groups.id AND last_check_for_updates > now() at time zone 'utc' - interval '%s'
-	GROUP BY version, total
-	ORDER BY regexp_matches(version, '(\d+)\.(\d+)\.(\d+)')::int[] DESC
+	GROUP BY version, version_sort_key, total
+	ORDER BY version_sort_key DESC
 	`, validityInterval, validityInterval)
 }
 
@@ -285,9 +691,10 @@ func (api *API) groupInstancesStatusQuery() string {
 		sum(case when status = %d then 1 else 0 end) installed,
 		sum(case when status = %d then 1 else 0 end) downloaded,
 		sum(case when status = %d then 1 else 0 end) downloading,
-		sum(case when status = %d then 1 else 0 end) onhold
+		sum(case when status = %d then 1 else 0 end) onhold,
+		sum(case when status = %d then 1 else 0 end) timed_out
 	FROM instance_application
 	WHERE group_id=groups.id AND last_check_for_updates > now() at time zone 'utc' - interval '%s'`,
 		InstanceStatusError, InstanceStatusUpdateGranted, InstanceStatusComplete, InstanceStatusInstalled,
-		InstanceStatusDownloaded, InstanceStatusDownloading, InstanceStatusOnHold, validityInterval)
+		InstanceStatusDownloaded, InstanceStatusDownloading, InstanceStatusOnHold, InstanceStatusTimedOut, validityInterval)
 }
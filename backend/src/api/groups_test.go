@@ -147,3 +147,136 @@ func TestGetGroups(t *testing.T) {
 	assert.Equal(t, tPkg.ID, groups[1].Channel.PackageID.String)
 	assert.Equal(t, tPkg.Version, groups[1].Channel.Package.Version)
 }
+
+func TestGroupRolloutStages(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+
+	group := &Group{
+		Name:                      "group1",
+		ApplicationID:             tApp.ID,
+		ChannelID:                 dat.NullStringFrom(tChannel.ID),
+		PolicyUpdatesEnabled:      true,
+		PolicyPeriodInterval:      "15 minutes",
+		PolicyMaxUpdatesPerPeriod: 2,
+		PolicyUpdateTimeout:       "60 minutes",
+		PolicyRolloutPercentage:   dat.NullFloat64From(5),
+		PolicyRolloutStages: []*RolloutStage{
+			{Percentage: 1, Duration: "1 hour"},
+			{Percentage: 10, Duration: "1 day"},
+			{Percentage: 100, Duration: "1 day"},
+		},
+	}
+	group, err := a.AddGroup(group)
+	assert.NoError(t, err)
+
+	groupX, err := a.GetGroup(group.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, groupX.PolicyRolloutPercentage.Float64)
+	assert.Equal(t, 3, len(groupX.PolicyRolloutStages))
+	assert.Equal(t, 10.0, groupX.PolicyRolloutStages[1].Percentage)
+	assert.Equal(t, "1 day", groupX.PolicyRolloutStages[1].Duration)
+	assert.Equal(t, 1, groupX.RolloutStage.Stage)
+	assert.Equal(t, 1.0, groupX.RolloutStage.Percentage)
+	assert.True(t, groupX.RolloutStage.EndsTs.Valid)
+
+	groupX.PolicyRolloutStages = []*RolloutStage{{Percentage: 50, Duration: "1 hour"}}
+	err = a.UpdateGroup(groupX)
+	assert.NoError(t, err)
+
+	groupX, _ = a.GetGroup(group.ID)
+	assert.Equal(t, 1, len(groupX.PolicyRolloutStages))
+	assert.Equal(t, 50.0, groupX.RolloutStage.Percentage)
+	assert.False(t, groupX.RolloutStage.EndsTs.Valid)
+
+	groupX.PolicyRolloutStages = []*RolloutStage{{Percentage: 150, Duration: "1 hour"}}
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidRolloutStage, err)
+
+	groupX.PolicyRolloutStages = []*RolloutStage{{Percentage: 50, Duration: "invalid"}}
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidRolloutStage, err)
+
+	groupX.PolicyRolloutStages = nil
+	groupX.PolicyRolloutPercentage = dat.NullFloat64From(101)
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidRolloutPercentage, err)
+}
+
+func TestGroupUpdateWindows(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+
+	group := &Group{
+		Name:                      "group1",
+		ApplicationID:             tApp.ID,
+		PolicyUpdatesEnabled:      true,
+		PolicyPeriodInterval:      "15 minutes",
+		PolicyMaxUpdatesPerPeriod: 2,
+		PolicyUpdateTimeout:       "60 minutes",
+		PolicyUpdateWindows: []*UpdateWindow{
+			{Weekdays: []int{2, 4}, StartTime: "02:00", EndTime: "05:00"},
+			{Weekdays: []int{6}, StartTime: "22:00", EndTime: "01:00"},
+		},
+	}
+	_, err := a.AddGroup(group)
+	assert.Equal(t, ErrExpectingValidTimezone, err)
+
+	group.PolicyTimezone = dat.NullStringFrom("Europe/Madrid")
+	group, err = a.AddGroup(group)
+	assert.NoError(t, err)
+
+	groupX, err := a.GetGroup(group.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(groupX.PolicyUpdateWindows))
+	assert.Equal(t, []int{2, 4}, groupX.PolicyUpdateWindows[0].Weekdays)
+	assert.Equal(t, "02:00", groupX.PolicyUpdateWindows[0].StartTime)
+	assert.Equal(t, "05:00", groupX.PolicyUpdateWindows[0].EndTime)
+	assert.Equal(t, []int{6}, groupX.PolicyUpdateWindows[1].Weekdays)
+
+	groupX.PolicyUpdateWindows = []*UpdateWindow{{Weekdays: []int{7}, StartTime: "02:00", EndTime: "05:00"}}
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidUpdateWindow, err)
+
+	groupX.PolicyUpdateWindows = []*UpdateWindow{{Weekdays: []int{1}, StartTime: "25:00", EndTime: "05:00"}}
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidUpdateWindow, err)
+
+	groupX.PolicyUpdateWindows = nil
+	err = a.UpdateGroup(groupX)
+	assert.NoError(t, err)
+
+	groupX, _ = a.GetGroup(group.ID)
+	assert.Equal(t, 0, len(groupX.PolicyUpdateWindows))
+}
+
+func TestGroupErrorBudget(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+
+	group, err := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, PolicyPeriodInterval: "15 minutes", PolicyUpdateTimeout: "60 minutes", PolicyMaxFailedUpdates: 5, PolicyMaxFailedUpdatesPercentage: 12.5})
+	assert.NoError(t, err)
+	groupX, _ := a.GetGroup(group.ID)
+	assert.Equal(t, 5, groupX.PolicyMaxFailedUpdates)
+	assert.Equal(t, 12.5, groupX.PolicyMaxFailedUpdatesPercentage)
+
+	groupX.PolicyMaxFailedUpdates = -1
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidErrorBudget, err)
+
+	groupX.PolicyMaxFailedUpdates = 0
+	groupX.PolicyMaxFailedUpdatesPercentage = 120
+	err = a.UpdateGroup(groupX)
+	assert.Equal(t, ErrInvalidErrorBudget, err)
+}
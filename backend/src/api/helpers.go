@@ -2,8 +2,6 @@ package api
 
 import (
 	"time"
-
-	"github.com/blang/semver"
 )
 
 const (
@@ -38,11 +36,15 @@ func isTimezoneValid(tz string) bool {
 	return true
 }
 
-// isValidSemver checks if the provided string represents a valid semver
-// version.
-func isValidSemver(version string) bool {
-	if _, err := semver.Make(version); err != nil {
-		return false
+// parseTimeOfDay parses a time of the day in the format HH:MM or HH:MM:SS,
+// returning the time elapsed since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if t, err = time.Parse("15:04:05", s); err != nil {
+			return 0, err
+		}
 	}
-	return true
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
 }
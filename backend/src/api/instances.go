@@ -6,6 +6,7 @@ import (
 
 	"github.com/satori/go.uuid"
 	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
 )
 
 const (
@@ -40,6 +41,10 @@ const (
 	// InstanceStatusOnHold indicates that the instance hasn't been granted an
 	// update because one of the rollout policy limits has been reached.
 	InstanceStatusOnHold
+
+	// InstanceStatusTimedOut indicates that the instance didn't report back
+	// the result of the update granted within the group's update timeout.
+	InstanceStatusTimedOut
 )
 
 const (
@@ -52,6 +57,7 @@ type Instance struct {
 	ID          string              `db:"id" json:"id"`
 	IP          string              `db:"ip" json:"ip"`
 	CreatedTs   time.Time           `db:"created_ts" json:"created_ts"`
+	Labels      []*Label            `db:"labels" json:"labels"`
 	Application InstanceApplication `db:"application" json:"application,omitempty"`
 }
 
@@ -86,23 +92,25 @@ type InstanceStatusHistoryEntry struct {
 // InstancesQueryParams represents a helper structure used to pass a set of
 // parameters when querying instances.
 type InstancesQueryParams struct {
-	ApplicationID string `json:"application_id"`
-	GroupID       string `json:"group_id"`
-	Status        int    `json:"status"`
-	Version       string `json:"version"`
-	Page          uint64 `json:"page"`
-	PerPage       uint64 `json:"perpage"`
+	ApplicationID string            `json:"application_id"`
+	GroupID       string            `json:"group_id"`
+	Status        int               `json:"status"`
+	Version       string            `json:"version"`
+	Labels        map[string]string `json:"labels"`
+	Page          uint64            `json:"page"`
+	PerPage       uint64            `json:"perpage"`
 }
 
 // RegisterInstance registers an instance into CoreRoller.
 func (api *API) RegisterInstance(instanceID, instanceIP, instanceVersion, appID, groupID string) (*Instance, error) {
-	if !isValidSemver(instanceVersion) {
-		return nil, ErrInvalidSemver
+	appID, groupID, group, err := api.validateApplicationAndGroup(appID, groupID)
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
-	if appID, groupID, err = api.validateApplicationAndGroup(appID, groupID); err != nil {
-		return nil, err
+	scheme := group.VersionScheme
+	if !isValidVersion(scheme, instanceVersion) {
+		return nil, ErrInvalidSemver
 	}
 
 	tx, err := api.dbR.Begin()
@@ -126,8 +134,8 @@ func (api *API) RegisterInstance(instanceID, instanceIP, instanceVersion, appID,
 
 	result, err = tx.
 		Upsert("instance_application").
-		Columns("instance_id", "application_id", "group_id", "version", "last_check_for_updates").
-		Values(instanceID, appID, groupID, instanceVersion, nowUTC).
+		Columns("instance_id", "application_id", "group_id", "version", "version_sort_key", "last_check_for_updates").
+		Values(instanceID, appID, groupID, instanceVersion, versionSortKey(scheme, instanceVersion), nowUTC).
 		Where("instance_id = $1 AND application_id = $2", instanceID, appID).
 		Exec()
 
@@ -150,6 +158,7 @@ func (api *API) GetInstance(instanceID, appID string) (*Instance, error) {
 		SelectDoc("*").
 		From("instance").
 		One("application", api.instanceAppQuery(appID)).
+		Many("labels", api.instanceLabelsQuery(), appID).
 		Where("id = $1", instanceID).
 		QueryStruct(&instance)
 
@@ -181,43 +190,55 @@ func (api *API) GetInstances(p InstancesQueryParams) ([]*Instance, error) {
 
 // validateApplicationAndGroup validates if the group provided belongs to the
 // provided application, returning the normalized uuid version of the appID and
-// groupID provided if both are valid and the group belongs to the given
-// application, or an error if something goes wrong.
-func (api *API) validateApplicationAndGroup(appID, groupID string) (string, string, error) {
+// groupID provided along with the group (with the cacheable details needed to
+// process updates requests) if both are valid and the group belongs to the
+// given application, or an error if something goes wrong.
+func (api *API) validateApplicationAndGroup(appID, groupID string) (string, string, *Group, error) {
 	appUUID, err := uuid.FromString(appID)
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 	groupUUID, err := uuid.FromString(groupID)
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
-	group, err := api.GetGroup(groupID)
+	group, err := api.getCachedUpdatesGroup(api.dbR, groupUUID.String())
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
 	if group.ApplicationID != appUUID.String() {
-		return "", "", ErrInvalidApplicationOrGroup
+		return "", "", nil, ErrInvalidApplicationOrGroup
 	}
 
-	return appUUID.String(), groupUUID.String(), nil
+	return appUUID.String(), groupUUID.String(), group, nil
 }
 
 // updateInstanceStatus updates the status for the provided instance in the
 // context of the given application, storing it as well in the instance status
-// history registry.
-func (api *API) updateInstanceStatus(instanceID, appID string, newStatus int) error {
-	instance, err := api.GetInstance(instanceID, appID)
+// history registry. The changes are made using the connection provided, so
+// that they can be part of the transaction granting an update.
+func (api *API) updateInstanceStatus(db runner.Connection, instanceID, appID string, newStatus int) error {
+	var current struct {
+		Status            dat.NullInt64  `db:"status"`
+		LastUpdateVersion dat.NullString `db:"last_update_version"`
+	}
+
+	err := db.
+		Select("status", "last_update_version").
+		From("instance_application").
+		Where("instance_id = $1 AND application_id = $2", instanceID, appID).
+		QueryStruct(&current)
+
 	if err != nil {
 		return err
 	}
-	if instance.Application.Status.Valid && instance.Application.Status.Int64 == int64(newStatus) {
+	if current.Status.Valid && current.Status.Int64 == int64(newStatus) {
 		return nil
 	}
 
-	query := api.dbR.
+	query := db.
 		Update("instance_application").
 		Set("status", newStatus).
 		Where("instance_id = $1 AND application_id = $2", instanceID, appID).
@@ -225,9 +246,16 @@ func (api *API) updateInstanceStatus(instanceID, appID string, newStatus int) er
 
 	if newStatus == InstanceStatusComplete {
 		query.Set("version", dat.UnsafeString("CASE WHEN last_update_version IS NOT NULL THEN last_update_version ELSE version END"))
+		if current.LastUpdateVersion.Valid {
+			scheme, err := api.getVersionScheme(appID)
+			if err != nil {
+				return err
+			}
+			query.Set("version_sort_key", versionSortKey(scheme, current.LastUpdateVersion.String))
+		}
 	}
 
-	if newStatus == InstanceStatusComplete || newStatus == InstanceStatusError {
+	if newStatus == InstanceStatusComplete || newStatus == InstanceStatusError || newStatus == InstanceStatusTimedOut {
 		query.Set("update_in_progress", false)
 	}
 
@@ -237,7 +265,7 @@ func (api *API) updateInstanceStatus(instanceID, appID string, newStatus int) er
 		return err
 	}
 
-	_, err = api.dbR.
+	_, err = db.
 		InsertInto("instance_status_history").
 		Columns("status", "version", "instance_id", "application_id", "group_id").
 		Values(newStatus, lastUpdateVersion, instanceID, appID, groupID).
@@ -276,11 +304,16 @@ func (api *API) instancesQuery(p InstancesQueryParams) *dat.SelectDocBuilder {
 		instancesSubquery.Where("version = $1", p.Version)
 	}
 
+	for key, value := range p.Labels {
+		instancesSubquery.Where("instance_id IN (SELECT instance_id FROM instance_label WHERE application_id = $1 AND key = $2 AND value = $3)", p.ApplicationID, key, value)
+	}
+
 	instancesSubquerySQL, instancesSubqueryParams := instancesSubquery.ToSQL()
 
 	return api.dbR.
 		SelectDoc("*").
 		One("application", api.instanceAppQuery(p.ApplicationID)).
+		Many("labels", api.instanceLabelsQuery(), p.ApplicationID).
 		From("instance").
 		Where(fmt.Sprintf("id IN (%s)", instancesSubquerySQL), instancesSubqueryParams...)
 }
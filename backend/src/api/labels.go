@@ -0,0 +1,204 @@
+package api
+
+import (
+	"errors"
+	"regexp"
+
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
+)
+
+const (
+	maxLabelKeyLength   = 50
+	maxLabelValueLength = 255
+)
+
+var (
+	// ErrInvalidLabel error indicates that one of the labels provided is not
+	// valid (keys can only contain letters, numbers, '.', '_' and '-').
+	ErrInvalidLabel = errors.New("coreroller: invalid label")
+
+	// ErrInvalidLabelSelector error indicates that the label selector provided
+	// for the group is not valid.
+	ErrInvalidLabelSelector = errors.New("coreroller: invalid label selector")
+
+	labelKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+)
+
+// Label represents a key/value pair. Labels are reported by the instances to
+// describe themselves (region, rack, hardware class, etc) and used by groups
+// to select the instances that may receive updates.
+type Label struct {
+	Key   string `db:"key" json:"key"`
+	Value string `db:"value" json:"value"`
+}
+
+// SetInstanceLabels replaces the labels reported by the instance provided for
+// the given application with the ones given, registering the instance if it
+// doesn't exist yet. Nothing is written when the labels haven't changed.
+func (api *API) SetInstanceLabels(instanceID, instanceIP, appID, groupID string, labels map[string]string) error {
+	for key, value := range labels {
+		if !isValidLabel(key, value) {
+			return ErrInvalidLabel
+		}
+	}
+
+	appID, _, _, err := api.validateApplicationAndGroup(appID, groupID)
+	if err != nil {
+		return err
+	}
+
+	var current []*Label
+	err = api.dbR.
+		Select("key", "value").
+		From("instance_label").
+		Where("instance_id = $1 AND application_id = $2", instanceID, appID).
+		QueryStructs(&current)
+	if err != nil {
+		return err
+	}
+	if equalLabels(current, labels) {
+		return nil
+	}
+
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	result, err := tx.
+		Upsert("instance").
+		Columns("id", "ip").
+		Values(instanceID, instanceIP).
+		Where("id = $1", instanceID).
+		Exec()
+
+	if err != nil || result.RowsAffected == 0 {
+		return err
+	}
+
+	_, err = tx.DeleteFrom("instance_label").
+		Where("instance_id = $1 AND application_id = $2", instanceID, appID).
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	for key, value := range labels {
+		_, err := tx.InsertInto("instance_label").
+			Columns("instance_id", "application_id", "key", "value").
+			Values(instanceID, appID, key, value).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// equalLabels checks if the labels stored for an instance are the same as the
+// ones provided.
+func equalLabels(current []*Label, labels map[string]string) bool {
+	if len(current) != len(labels) {
+		return false
+	}
+
+	for _, label := range current {
+		if value, ok := labels[label.Key]; !ok || value != label.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isValidLabel checks if the label key and value provided are valid.
+func isValidLabel(key, value string) bool {
+	return len(key) <= maxLabelKeyLength && labelKeyRegexp.MatchString(key) && len(value) <= maxLabelValueLength
+}
+
+// validateLabelSelector checks that the labels in the selector of the group
+// provided are valid and that each key is used only once.
+func validateLabelSelector(group *Group) error {
+	keys := make(map[string]bool, len(group.PolicyLabelSelector))
+
+	for _, label := range group.PolicyLabelSelector {
+		if label == nil || !isValidLabel(label.Key, label.Value) || keys[label.Key] {
+			return ErrInvalidLabelSelector
+		}
+		keys[label.Key] = true
+	}
+
+	return nil
+}
+
+// matchesLabelSelector checks if the labels provided match all the labels in
+// the selector. An empty selector matches any set of labels.
+func matchesLabelSelector(labels, selector []*Label) bool {
+	instanceLabels := make(map[string]string, len(labels))
+	for _, label := range labels {
+		instanceLabels[label.Key] = label.Value
+	}
+
+	for _, label := range selector {
+		if value, ok := instanceLabels[label.Key]; !ok || value != label.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// updateGroupLabelSelector replaces the label selector of the group provided
+// with the one set in the group entry.
+//
+// This method is part of the transaction that adds or updates a group.
+func (api *API) updateGroupLabelSelector(tx *runner.Tx, group *Group) error {
+	_, err := tx.DeleteFrom("group_label_selector").
+		Where("group_id = $1", group.ID).
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	for _, label := range group.PolicyLabelSelector {
+		_, err := tx.InsertInto("group_label_selector").
+			Columns("key", "value", "group_id").
+			Values(label.Key, label.Value, group.ID).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// instanceLabelsQuery returns a SQL query prepared to return the labels a
+// given instance for the application provided.
+func (api *API) instanceLabelsQuery() string {
+	return `
+	SELECT key, value
+	FROM instance_label
+	WHERE instance_id = instance.id AND application_id = $1
+	ORDER BY key ASC
+	`
+}
+
+// groupLabelSelectorQuery returns a SQL query prepared to return the label
+// selector of a given group.
+func (api *API) groupLabelSelectorQuery() string {
+	return `
+	SELECT key, value
+	FROM group_label_selector
+	WHERE group_id = groups.id
+	ORDER BY id ASC
+	`
+}
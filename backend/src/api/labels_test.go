@@ -0,0 +1,150 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestSetInstanceLabels(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tGroup, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+	instanceID := uuid.NewV4().String()
+
+	err := a.SetInstanceLabels(instanceID, "10.0.0.1", tApp.ID, tGroup.ID, map[string]string{"region": "eu-west-1", "rack": "r12"})
+	assert.NoError(t, err, "Labels can be set before the instance registers for the app.")
+
+	_, err = a.RegisterInstance(instanceID, "10.0.0.1", "1.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	instance, err := a.GetInstance(instanceID, tApp.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, instance.Labels, 2) {
+		assert.Equal(t, Label{Key: "rack", Value: "r12"}, *instance.Labels[0])
+		assert.Equal(t, Label{Key: "region", Value: "eu-west-1"}, *instance.Labels[1])
+	}
+
+	err = a.SetInstanceLabels(instanceID, "10.0.0.1", tApp.ID, tGroup.ID, map[string]string{"region": "us-east-1"})
+	assert.NoError(t, err)
+
+	instance, _ = a.GetInstance(instanceID, tApp.ID)
+	if assert.Len(t, instance.Labels, 1, "Labels are replaced, not merged.") {
+		assert.Equal(t, Label{Key: "region", Value: "us-east-1"}, *instance.Labels[0])
+	}
+
+	err = a.SetInstanceLabels(instanceID, "10.0.0.1", tApp.ID, tGroup.ID, map[string]string{"region": "us-east-1"})
+	assert.NoError(t, err, "Setting the same labels again is a no-op.")
+
+	err = a.SetInstanceLabels(instanceID, "10.0.0.1", tApp.ID, tGroup.ID, map[string]string{"invalid key": "value"})
+	assert.Equal(t, ErrInvalidLabel, err)
+
+	tApp2, _ := a.AddApp(&Application{Name: "test_app2", TeamID: tTeam.ID})
+	err = a.SetInstanceLabels(instanceID, "10.0.0.1", tApp2.ID, tGroup.ID, map[string]string{"region": "us-east-1"})
+	assert.Equal(t, ErrInvalidApplicationOrGroup, err, "Labels are only stored for valid app/group combinations.")
+
+	tGroup2, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp2.ID, PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+	err = a.SetInstanceLabels(instanceID, "10.0.0.1", tApp2.ID, tGroup2.ID, map[string]string{"region": "ap-south-1"})
+	assert.NoError(t, err)
+
+	instance, _ = a.GetInstance(instanceID, tApp.ID)
+	if assert.Len(t, instance.Labels, 1, "Labels are stored per app.") {
+		assert.Equal(t, Label{Key: "region", Value: "us-east-1"}, *instance.Labels[0])
+	}
+}
+
+func TestGetInstancesByLabels(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tGroup, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+	tInstance1, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "1.0.0", tApp.ID, tGroup.ID)
+	tInstance2, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "1.0.0", tApp.ID, tGroup.ID)
+	_, _ = a.RegisterInstance(uuid.NewV4().String(), "10.0.0.3", "1.0.0", tApp.ID, tGroup.ID)
+	_ = a.SetInstanceLabels(tInstance1.ID, "10.0.0.1", tApp.ID, tGroup.ID, map[string]string{"region": "eu-west-1", "hw": "gen2"})
+	_ = a.SetInstanceLabels(tInstance2.ID, "10.0.0.2", tApp.ID, tGroup.ID, map[string]string{"region": "eu-west-1", "hw": "gen3"})
+
+	instances, err := a.GetInstances(InstancesQueryParams{ApplicationID: tApp.ID, GroupID: tGroup.ID, Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(instances))
+
+	instances, err = a.GetInstances(InstancesQueryParams{ApplicationID: tApp.ID, GroupID: tGroup.ID, Labels: map[string]string{"region": "eu-west-1"}, Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(instances))
+
+	instances, err = a.GetInstances(InstancesQueryParams{ApplicationID: tApp.ID, GroupID: tGroup.ID, Labels: map[string]string{"region": "eu-west-1", "hw": "gen3"}, Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(instances)) {
+		assert.Equal(t, tInstance2.ID, instances[0].ID)
+	}
+
+	instances, err = a.GetInstances(InstancesQueryParams{ApplicationID: tApp.ID, GroupID: tGroup.ID, Labels: map[string]string{"region": "us-east-1"}, Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(instances))
+}
+
+func TestGroupLabelSelector(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+
+	_, err := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes", PolicyLabelSelector: []*Label{{Key: "region", Value: "a"}, {Key: "region", Value: "b"}}})
+	assert.Equal(t, ErrInvalidLabelSelector, err, "Each key can be used only once in a selector.")
+
+	_, err = a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes", PolicyLabelSelector: []*Label{{Key: "", Value: "a"}}})
+	assert.Equal(t, ErrInvalidLabelSelector, err)
+
+	tGroup, err := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes", PolicyLabelSelector: []*Label{{Key: "region", Value: "eu-west-1"}}})
+	assert.NoError(t, err)
+
+	group, _ := a.GetGroup(tGroup.ID)
+	if assert.Len(t, group.PolicyLabelSelector, 1) {
+		assert.Equal(t, Label{Key: "region", Value: "eu-west-1"}, *group.PolicyLabelSelector[0])
+	}
+
+	instanceID1 := uuid.NewV4().String()
+	instanceID2 := uuid.NewV4().String()
+	_ = a.SetInstanceLabels(instanceID1, "10.0.0.1", tApp.ID, tGroup.ID, map[string]string{"region": "eu-west-1"})
+	_ = a.SetInstanceLabels(instanceID2, "10.0.0.2", tApp.ID, tGroup.ID, map[string]string{"region": "us-east-1"})
+
+	pkg, err := a.GetUpdatePackage(instanceID1, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg.ID, pkg.ID)
+
+	_, err = a.GetUpdatePackage(instanceID2, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err, "Instances without labels don't match a non empty selector.")
+
+	group.PolicyLabelSelector = nil
+	err = a.UpdateGroup(group)
+	assert.NoError(t, err)
+
+	pkg, err = a.GetUpdatePackage(instanceID2, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg.ID, pkg.ID)
+}
+
+func TestMatchesLabelSelector(t *testing.T) {
+	labels := []*Label{{Key: "region", Value: "eu-west-1"}, {Key: "hw", Value: "gen2"}}
+
+	assert.True(t, matchesLabelSelector(labels, nil))
+	assert.True(t, matchesLabelSelector(nil, nil))
+	assert.True(t, matchesLabelSelector(labels, []*Label{{Key: "region", Value: "eu-west-1"}}))
+	assert.True(t, matchesLabelSelector(labels, []*Label{{Key: "hw", Value: "gen2"}, {Key: "region", Value: "eu-west-1"}}))
+	assert.False(t, matchesLabelSelector(labels, []*Label{{Key: "region", Value: "us-east-1"}}))
+	assert.False(t, matchesLabelSelector(labels, []*Label{{Key: "region", Value: "eu-west-1"}, {Key: "rack", Value: "r12"}}))
+	assert.False(t, matchesLabelSelector(nil, []*Label{{Key: "region", Value: "eu-west-1"}}))
+}
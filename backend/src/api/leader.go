@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+)
+
+const (
+	// leaderLockID is the key of the session level advisory lock held by the
+	// CoreRoller replica acting as leader.
+	leaderLockID = 0x636f7265
+
+	// leaderAppNamePrefix is the prefix of the application name set in the
+	// database session of the leader, which is used to identify it.
+	leaderAppNamePrefix = "coreroller leader "
+
+	// leaderCheckTimeout is the time given to the database session holding
+	// the lock to reply when checking if it's still alive.
+	leaderCheckTimeout = 5 * time.Second
+)
+
+// LeaderElector elects the CoreRoller replica that runs the tasks that must
+// not run concurrently in several replicas (like the syncer), using a
+// Postgresql advisory lock. The lock is held by a dedicated database session,
+// so when the leader dies Postgresql releases it and another replica can take
+// over.
+type LeaderElector struct {
+	sync.Mutex
+	api       *API
+	replicaID string
+	conn      *sql.Conn
+}
+
+// Leader represents the CoreRoller replica currently acting as leader.
+type Leader struct {
+	ReplicaID string `db:"replica_id" json:"replica_id"`
+}
+
+// NewLeaderElector creates a new LeaderElector instance for the replica
+// identified by the id provided.
+func (api *API) NewLeaderElector(replicaID string) *LeaderElector {
+	return &LeaderElector{
+		api:       api,
+		replicaID: replicaID,
+	}
+}
+
+// Elect tries to make the replica the leader, returning if it is. A replica
+// that is already the leader stays so as long as the database session holding
+// the lock is alive.
+func (e *LeaderElector) Elect() (bool, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	ctx := context.Background()
+
+	if e.checkSession() {
+		return true, nil
+	}
+
+	conn, err := e.api.db.DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockID).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return false, err
+	}
+	if !acquired {
+		_ = conn.Close()
+		return false, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", leaderAppNamePrefix+e.replicaID); err != nil {
+		discardConn(conn)
+		_ = conn.Close()
+		return false, err
+	}
+	e.conn = conn
+
+	return true, nil
+}
+
+// IsLeader returns if the replica is the leader. The database session holding
+// the lock is checked, so the tasks run by the leader can use it to confirm it
+// still is before making any changes.
+func (e *LeaderElector) IsLeader() bool {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.checkSession()
+}
+
+// checkSession checks that the database session holding the lock is still
+// alive, dropping it when it's not as the lock is gone with it (and it may be
+// held by another replica already). It must be called with the elector locked.
+func (e *LeaderElector) checkSession() bool {
+	if e.conn == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaderCheckTimeout)
+	defer cancel()
+
+	if _, err := e.conn.ExecContext(ctx, "SELECT 1"); err != nil {
+		discardConn(e.conn)
+		_ = e.conn.Close()
+		e.conn = nil
+		return false
+	}
+
+	return true
+}
+
+// Resign releases the leadership, if the replica holds it, so that another
+// replica can take over. When the lock can't be released, the database session
+// holding it is closed instead of returning it to the pool, which releases it.
+func (e *LeaderElector) Resign() error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	_, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1), set_config('application_name', '', false)", leaderLockID)
+	if err != nil {
+		discardConn(e.conn)
+	}
+	_ = e.conn.Close()
+	e.conn = nil
+
+	return err
+}
+
+// discardConn marks the connection provided as bad, so that it's closed
+// instead of being returned to the pool once released.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+}
+
+// GetLeader returns the CoreRoller replica currently acting as leader, or
+// sql.ErrNoRows if there isn't one.
+func (api *API) GetLeader() (*Leader, error) {
+	var leader Leader
+
+	query := `
+	SELECT substr(a.application_name, $1) as replica_id
+	FROM pg_locks l, pg_stat_activity a
+	WHERE l.pid = a.pid AND
+		l.locktype = 'advisory' AND
+		l.granted AND
+		l.database = (SELECT oid FROM pg_database WHERE datname = current_database()) AND
+		l.classid = 0 AND l.objid = $2 AND l.objsubid = 1
+	`
+	err := api.dbR.SQL(query, len(leaderAppNamePrefix)+1, leaderLockID).QueryStruct(&leader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &leader, nil
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderElection(t *testing.T) {
+	a1, _ := New(OptionInitDB)
+	defer a1.Close()
+	a2, _ := New()
+	defer a2.Close()
+
+	_, err := a1.GetLeader()
+	assert.Equal(t, sql.ErrNoRows, err, "There is no leader yet.")
+
+	elector1 := a1.NewLeaderElector("replica1")
+	elector2 := a2.NewLeaderElector("replica2")
+
+	leader, err := elector1.Elect()
+	assert.NoError(t, err)
+	assert.True(t, leader)
+	assert.True(t, elector1.IsLeader())
+
+	leader, err = elector2.Elect()
+	assert.NoError(t, err)
+	assert.False(t, leader, "Only one replica can be the leader.")
+	assert.False(t, elector2.IsLeader())
+
+	leader, err = elector1.Elect()
+	assert.NoError(t, err)
+	assert.True(t, leader, "The leader must keep the leadership.")
+
+	currentLeader, err := a2.GetLeader()
+	assert.NoError(t, err)
+	assert.Equal(t, "replica1", currentLeader.ReplicaID)
+
+	err = elector1.Resign()
+	assert.NoError(t, err)
+	assert.False(t, elector1.IsLeader())
+
+	leader, err = elector2.Elect()
+	assert.NoError(t, err)
+	assert.True(t, leader, "Another replica must take over when the leader resigns.")
+
+	currentLeader, err = a1.GetLeader()
+	assert.NoError(t, err)
+	assert.Equal(t, "replica2", currentLeader.ReplicaID)
+
+	_, err = a1.dbR.SQL("SELECT pg_terminate_backend(pid) FROM pg_locks WHERE locktype = 'advisory' AND objid = $1", leaderLockID).Exec()
+	assert.NoError(t, err)
+	assert.False(t, elector2.IsLeader(), "The leadership is lost as soon as the database session holding the lock dies.")
+
+	leader, err = elector1.Elect()
+	assert.NoError(t, err)
+	assert.True(t, leader, "Another replica must take over when the leader dies.")
+
+	leader, err = elector2.Elect()
+	assert.NoError(t, err)
+	assert.False(t, leader, "The leadership is lost with the database session holding the lock.")
+	assert.False(t, elector2.IsLeader())
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gopkg.in/mgutz/dat.v1"
+)
+
+var (
+	// ErrInvalidPackageArch error indicates that the architecture payload
+	// provided is not valid (it can't be for the architecture of the package's
+	// main payload).
+	ErrInvalidPackageArch = errors.New("coreroller: invalid package architecture")
+
+	// ErrNoPackageForArch error indicates that the package the instance should
+	// be updated to doesn't have a payload for the instance's architecture.
+	ErrNoPackageForArch = errors.New("coreroller: no package payload for the instance architecture")
+
+	archAliases = map[string]string{
+		"x86_64":  "amd64",
+		"x64":     "amd64",
+		"aarch64": "arm64",
+	}
+)
+
+// PackageArch represents the payload of a package built for a specific
+// architecture. The package's main payload (url, filename, etc) is the one
+// for the package's architecture, or for any architecture when it isn't set.
+type PackageArch struct {
+	ID        string         `db:"id" json:"id"`
+	Arch      string         `db:"arch" json:"arch"`
+	URL       string         `db:"url" json:"url"`
+	Filename  dat.NullString `db:"filename" json:"filename"`
+	Size      dat.NullString `db:"size" json:"size"`
+	Hash      dat.NullString `db:"hash" json:"hash"`
+	Sha256    dat.NullString `db:"sha256" json:"sha256"`
+	CreatedTs time.Time      `db:"created_ts" json:"created_ts"`
+	PackageID string         `db:"package_id" json:"package_id"`
+}
+
+// AddPackageArch registers the provided architecture payload.
+func (api *API) AddPackageArch(pkgArch *PackageArch) (*PackageArch, error) {
+	if err := api.validatePackageArch(pkgArch); err != nil {
+		return nil, err
+	}
+
+	err := api.dbR.
+		InsertInto("package_arch").
+		Whitelist("arch", "url", "filename", "size", "hash", "sha256", "package_id").
+		Record(pkgArch).
+		Returning("*").
+		QueryStruct(pkgArch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pkgArch, nil
+}
+
+// UpdatePackageArch updates an existing architecture payload using the content
+// of the payload provided.
+func (api *API) UpdatePackageArch(pkgArch *PackageArch) error {
+	pkgArchBeforeUpdate, err := api.GetPackageArch(pkgArch.ID)
+	if err != nil {
+		return err
+	}
+	pkgArch.PackageID = pkgArchBeforeUpdate.PackageID
+
+	if err := api.validatePackageArch(pkgArch); err != nil {
+		return err
+	}
+
+	result, err := api.dbR.
+		Update("package_arch").
+		SetWhitelist(pkgArch, "arch", "url", "filename", "size", "hash", "sha256").
+		Where("id = $1", pkgArch.ID).
+		Exec()
+
+	if err == nil && result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return err
+}
+
+// DeletePackageArch removes the architecture payload identified by the id
+// provided.
+func (api *API) DeletePackageArch(pkgArchID string) error {
+	result, err := api.dbR.
+		DeleteFrom("package_arch").
+		Where("id = $1", pkgArchID).
+		Exec()
+
+	if err == nil && result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return err
+}
+
+// GetPackageArch returns the architecture payload identified by the id
+// provided.
+func (api *API) GetPackageArch(pkgArchID string) (*PackageArch, error) {
+	var pkgArch PackageArch
+
+	err := api.dbR.
+		SelectDoc("*").
+		From("package_arch").
+		Where("id = $1", pkgArchID).
+		QueryStruct(&pkgArch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &pkgArch, nil
+}
+
+// GetPackageArches returns all architecture payloads of the package provided.
+func (api *API) GetPackageArches(packageID string) ([]*PackageArch, error) {
+	var pkgArches []*PackageArch
+
+	err := api.dbR.
+		SelectDoc("*").
+		From("package_arch").
+		Where("package_id = $1", packageID).
+		OrderBy("arch ASC").
+		QueryStructs(&pkgArches)
+
+	return pkgArches, err
+}
+
+// ArchPayload returns the payload of the package for the architecture
+// provided. A nil payload means that the package's main payload must be used.
+// It returns false when the package doesn't have a payload for the
+// architecture. Instances that don't report their architecture get the main
+// payload, and so do all of them when the package's architecture isn't set and
+// it doesn't have architecture payloads, as its main payload is considered
+// architecture independent then.
+func (pkg *Package) ArchPayload(arch string) (*PackageArch, bool) {
+	arch = normalizeArch(arch)
+
+	for _, pkgArch := range pkg.Arches {
+		if pkgArch.Arch == arch {
+			return pkgArch, true
+		}
+	}
+
+	if arch == "" || (pkg.Arch.Valid && pkg.Arch.String == arch) {
+		return nil, true
+	}
+	if !pkg.Arch.Valid && len(pkg.Arches) == 0 {
+		return nil, true
+	}
+
+	return nil, false
+}
+
+// hasArchPayload checks if the package has a payload built specifically for
+// the architecture provided.
+func (pkg *Package) hasArchPayload(arch string) bool {
+	for _, pkgArch := range pkg.Arches {
+		if pkgArch.Arch == arch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validatePackageArch checks that the architecture payload provided isn't for
+// the architecture of its package's main payload, normalizing the
+// architecture name.
+func (api *API) validatePackageArch(pkgArch *PackageArch) error {
+	pkgArch.Arch = normalizeArch(pkgArch.Arch)
+	if pkgArch.Arch == "" {
+		return ErrInvalidPackageArch
+	}
+
+	pkg, err := api.GetPackage(pkgArch.PackageID)
+	if err != nil {
+		return err
+	}
+
+	if pkg.Arch.String == pkgArch.Arch {
+		return ErrInvalidPackageArch
+	}
+
+	return nil
+}
+
+// normalizeArch returns the canonical name of the architecture provided, so
+// that the different names clients use for the same architecture (x86_64,
+// amd64, etc) match.
+func normalizeArch(arch string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+
+	if alias, ok := archAliases[arch]; ok {
+		return alias
+	}
+
+	return arch
+}
+
+// packageArchesQuery returns a SQL query prepared to return the architecture
+// payloads of a given package.
+func (api *API) packageArchesQuery() string {
+	return `
+	SELECT *
+	FROM package_arch
+	WHERE package_id = package.id
+	ORDER BY arch ASC
+	`
+}
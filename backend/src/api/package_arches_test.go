@@ -0,0 +1,112 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestAddPackageArch(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", Arch: dat.NullStringFrom("x86_64"), ApplicationID: tApp.ID})
+
+	pkg, _ := a.GetPackage(tPkg.ID)
+	assert.Equal(t, "amd64", pkg.Arch.String, "Architecture names are normalized.")
+
+	pkgArch, err := a.AddPackageArch(&PackageArch{Arch: "aarch64", URL: "http://sample.url/pkg-arm64", Filename: dat.NullStringFrom("pkg-arm64.tgz"), PackageID: tPkg.ID})
+	assert.NoError(t, err)
+
+	pkgArchX, err := a.GetPackageArch(pkgArch.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "arm64", pkgArchX.Arch)
+	assert.Equal(t, "http://sample.url/pkg-arm64", pkgArchX.URL)
+	assert.Equal(t, tPkg.ID, pkgArchX.PackageID)
+
+	_, err = a.AddPackageArch(&PackageArch{Arch: "amd64", URL: "http://sample.url/pkg", PackageID: tPkg.ID})
+	assert.Equal(t, ErrInvalidPackageArch, err, "Main payload architecture can't be added again.")
+
+	_, err = a.AddPackageArch(&PackageArch{Arch: "arm64", URL: "http://sample.url/pkg", PackageID: tPkg.ID})
+	assert.Error(t, err, "Only one payload per architecture is allowed.")
+
+	_, err = a.AddPackageArch(&PackageArch{Arch: "", URL: "http://sample.url/pkg", PackageID: tPkg.ID})
+	assert.Equal(t, ErrInvalidPackageArch, err)
+
+	pkgArchX.URL = "http://sample.url/pkg-arm64-v2"
+	err = a.UpdatePackageArch(pkgArchX)
+	assert.NoError(t, err)
+
+	pkgArches, err := a.GetPackageArches(tPkg.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, pkgArches, 1) {
+		assert.Equal(t, "http://sample.url/pkg-arm64-v2", pkgArches[0].URL)
+	}
+
+	err = a.DeletePackageArch(pkgArch.ID)
+	assert.NoError(t, err)
+
+	err = a.DeletePackageArch(pkgArch.ID)
+	assert.Equal(t, ErrNoRowsAffected, err)
+}
+
+func TestPackageArchPayload(t *testing.T) {
+	arm64 := &PackageArch{Arch: "arm64"}
+	pkg := &Package{Arch: dat.NullStringFrom("amd64"), Arches: []*PackageArch{arm64}}
+
+	payload, ok := pkg.ArchPayload("x86_64")
+	assert.True(t, ok)
+	assert.Nil(t, payload)
+
+	payload, ok = pkg.ArchPayload("aarch64")
+	assert.True(t, ok)
+	assert.Equal(t, arm64, payload)
+
+	payload, ok = pkg.ArchPayload("")
+	assert.True(t, ok, "Instances not reporting their architecture get the main payload.")
+	assert.Nil(t, payload)
+
+	_, ok = pkg.ArchPayload("ppc64le")
+	assert.False(t, ok)
+
+	_, ok = (&Package{}).ArchPayload("ppc64le")
+	assert.True(t, ok, "Packages without architecture are served to any architecture.")
+
+	pkg = &Package{Arches: []*PackageArch{arm64}}
+	payload, ok = pkg.ArchPayload("aarch64")
+	assert.True(t, ok)
+	assert.Equal(t, arm64, payload)
+	_, ok = pkg.ArchPayload("x86_64")
+	assert.False(t, ok, "Packages with architecture payloads are only served to their architectures.")
+	payload, ok = pkg.ArchPayload("")
+	assert.True(t, ok)
+	assert.Nil(t, payload)
+}
+
+func TestGetUpdatePackageForArch(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", Arch: dat.NullStringFrom("amd64"), ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group1", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+
+	instanceID := uuid.NewV4().String()
+	_, err := a.GetUpdatePackageForArch(instanceID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID, "arm64")
+	assert.Equal(t, ErrNoPackageForArch, err)
+
+	instance, _ := a.GetInstance(instanceID, tApp.ID)
+	assert.False(t, instance.Application.UpdateInProgress, "No update is granted when there is no payload for the architecture.")
+
+	_, _ = a.AddPackageArch(&PackageArch{Arch: "arm64", URL: "http://sample.url/pkg-arm64", PackageID: tPkg.ID})
+
+	pkg, err := a.GetUpdatePackageForArch(instanceID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID, "aarch64")
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg.ID, pkg.ID)
+}
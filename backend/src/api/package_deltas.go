@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/mgutz/dat.v1"
+)
+
+var (
+	// ErrInvalidPackageDelta error indicates that the delta payload provided
+	// is not valid (the version it applies to must be a valid version
+	// lower than the package version, and the package must have a payload
+	// for its architecture).
+	ErrInvalidPackageDelta = errors.New("coreroller: invalid package delta")
+)
+
+// PackageDelta represents a payload variant of a package that can only be
+// applied on instances running a specific version (the delta's from version),
+// usually much smaller than the package's full payload. Deltas with an
+// architecture are served instead of the package's payload for that
+// architecture, the ones without it instead of the package's main payload.
+type PackageDelta struct {
+	ID          string         `db:"id" json:"id"`
+	FromVersion string         `db:"from_version" json:"from_version"`
+	Arch        dat.NullString `db:"arch" json:"arch"`
+	URL         string         `db:"url" json:"url"`
+	Filename    dat.NullString `db:"filename" json:"filename"`
+	Size        dat.NullString `db:"size" json:"size"`
+	Hash        dat.NullString `db:"hash" json:"hash"`
+	Sha256      dat.NullString `db:"sha256" json:"sha256"`
+	CreatedTs   time.Time      `db:"created_ts" json:"created_ts"`
+	PackageID   string         `db:"package_id" json:"package_id"`
+}
+
+// AddPackageDelta registers the provided delta payload.
+func (api *API) AddPackageDelta(delta *PackageDelta) (*PackageDelta, error) {
+	if err := api.validatePackageDelta(delta); err != nil {
+		return nil, err
+	}
+
+	err := api.dbR.
+		InsertInto("package_delta").
+		Whitelist("from_version", "arch", "url", "filename", "size", "hash", "sha256", "package_id").
+		Record(delta).
+		Returning("*").
+		QueryStruct(delta)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delta, nil
+}
+
+// UpdatePackageDelta updates an existing delta payload using the content of
+// the delta provided.
+func (api *API) UpdatePackageDelta(delta *PackageDelta) error {
+	deltaBeforeUpdate, err := api.GetPackageDelta(delta.ID)
+	if err != nil {
+		return err
+	}
+	delta.PackageID = deltaBeforeUpdate.PackageID
+
+	if err := api.validatePackageDelta(delta); err != nil {
+		return err
+	}
+
+	result, err := api.dbR.
+		Update("package_delta").
+		SetWhitelist(delta, "from_version", "arch", "url", "filename", "size", "hash", "sha256").
+		Where("id = $1", delta.ID).
+		Exec()
+
+	if err == nil && result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return err
+}
+
+// DeletePackageDelta removes the delta payload identified by the id provided.
+func (api *API) DeletePackageDelta(deltaID string) error {
+	result, err := api.dbR.
+		DeleteFrom("package_delta").
+		Where("id = $1", deltaID).
+		Exec()
+
+	if err == nil && result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return err
+}
+
+// GetPackageDelta returns the delta payload identified by the id provided.
+func (api *API) GetPackageDelta(deltaID string) (*PackageDelta, error) {
+	var delta PackageDelta
+
+	err := api.dbR.
+		SelectDoc("*").
+		From("package_delta").
+		Where("id = $1", deltaID).
+		QueryStruct(&delta)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &delta, nil
+}
+
+// GetPackageDeltas returns all delta payloads of the package provided.
+func (api *API) GetPackageDeltas(packageID string) ([]*PackageDelta, error) {
+	var deltas []*PackageDelta
+
+	err := api.dbR.
+		SelectDoc("*").
+		From("package_delta").
+		Where("package_id = $1", packageID).
+		OrderBy("created_ts DESC").
+		QueryStructs(&deltas)
+
+	return deltas, err
+}
+
+// DeltaFrom returns the delta payload of the package that applies on instances
+// running the version provided, or nil if there isn't any. The architecture
+// is the one of the payload the instance gets (empty for the main payload).
+func (pkg *Package) DeltaFrom(version, arch string) *PackageDelta {
+	for _, delta := range pkg.Deltas {
+		if delta.FromVersion == version && delta.Arch.String == arch {
+			return delta
+		}
+	}
+
+	return nil
+}
+
+// validatePackageDelta checks that the delta payload provided applies on a
+// version lower than its package's version and, when it has an architecture,
+// that the package has a payload for it.
+func (api *API) validatePackageDelta(delta *PackageDelta) error {
+	pkg, err := api.GetPackage(delta.PackageID)
+	if err != nil {
+		return err
+	}
+
+	if delta.Arch.Valid {
+		delta.Arch.String = normalizeArch(delta.Arch.String)
+		if !pkg.hasArchPayload(delta.Arch.String) {
+			return ErrInvalidPackageDelta
+		}
+	}
+
+	scheme, err := api.packageVersionScheme(pkg)
+	if err != nil {
+		return err
+	}
+	if !isValidVersion(scheme, delta.FromVersion) || compareVersions(scheme, delta.FromVersion, pkg.Version) >= 0 {
+		return ErrInvalidPackageDelta
+	}
+
+	return nil
+}
+
+// packageDeltasQuery returns a SQL query prepared to return the delta payloads
+// of a given package.
+func (api *API) packageDeltasQuery() string {
+	return `
+	SELECT *
+	FROM package_delta
+	WHERE package_id = package.id
+	ORDER BY created_ts DESC
+	`
+}
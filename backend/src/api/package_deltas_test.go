@@ -0,0 +1,105 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestAddPackageDelta(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+
+	delta, err := a.AddPackageDelta(&PackageDelta{FromVersion: "12.0.0", URL: "http://sample.url/delta", Filename: dat.NullStringFrom("delta.tgz"), PackageID: tPkg.ID})
+	assert.NoError(t, err)
+
+	deltaX, err := a.GetPackageDelta(delta.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "12.0.0", deltaX.FromVersion)
+	assert.Equal(t, "http://sample.url/delta", deltaX.URL)
+	assert.Equal(t, "delta.tgz", deltaX.Filename.String)
+	assert.Equal(t, tPkg.ID, deltaX.PackageID)
+
+	pkg, _ := a.GetPackage(tPkg.ID)
+	if assert.Len(t, pkg.Deltas, 1) {
+		assert.Equal(t, delta.ID, pkg.Deltas[0].ID)
+	}
+	assert.Equal(t, delta.ID, pkg.DeltaFrom("12.0.0", "").ID)
+	assert.Nil(t, pkg.DeltaFrom("11.0.0", ""))
+
+	_, err = a.AddPackageDelta(&PackageDelta{FromVersion: "12.0.0", URL: "http://sample.url/delta2", PackageID: tPkg.ID})
+	assert.Error(t, err, "Only one delta per source version is allowed.")
+
+	_, err = a.AddPackageDelta(&PackageDelta{FromVersion: "12.1.0", URL: "http://sample.url/delta", PackageID: tPkg.ID})
+	assert.Equal(t, ErrInvalidPackageDelta, err, "Delta must apply on an older version.")
+
+	_, err = a.AddPackageDelta(&PackageDelta{FromVersion: "12", URL: "http://sample.url/delta", PackageID: tPkg.ID})
+	assert.Equal(t, ErrInvalidPackageDelta, err)
+
+	_, err = a.AddPackageDelta(&PackageDelta{FromVersion: "11.0.0", PackageID: tPkg.ID})
+	assert.Error(t, err, "Delta url is required.")
+
+	_, err = a.AddPackageDelta(&PackageDelta{FromVersion: "12.0.0", Arch: dat.NullStringFrom("arm64"), URL: "http://sample.url/delta-arm64", PackageID: tPkg.ID})
+	assert.Equal(t, ErrInvalidPackageDelta, err, "Package must have a payload for the delta arch.")
+
+	_, _ = a.AddPackageArch(&PackageArch{Arch: "arm64", URL: "http://sample.url/pkg-arm64", PackageID: tPkg.ID})
+	deltaArm64, err := a.AddPackageDelta(&PackageDelta{FromVersion: "12.0.0", Arch: dat.NullStringFrom("aarch64"), URL: "http://sample.url/delta-arm64", PackageID: tPkg.ID})
+	assert.NoError(t, err, "Each payload can have its own delta for the same source version.")
+
+	pkg, _ = a.GetPackage(tPkg.ID)
+	assert.Equal(t, delta.ID, pkg.DeltaFrom("12.0.0", "").ID)
+	assert.Equal(t, deltaArm64.ID, pkg.DeltaFrom("12.0.0", "arm64").ID)
+}
+
+func TestUpdatePackageDelta(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	delta, _ := a.AddPackageDelta(&PackageDelta{FromVersion: "12.0.0", URL: "http://sample.url/delta", PackageID: tPkg.ID})
+
+	err := a.UpdatePackageDelta(&PackageDelta{ID: delta.ID, FromVersion: "11.0.0", URL: "http://sample.url/delta-11"})
+	assert.NoError(t, err)
+
+	deltaX, _ := a.GetPackageDelta(delta.ID)
+	assert.Equal(t, "11.0.0", deltaX.FromVersion)
+	assert.Equal(t, "http://sample.url/delta-11", deltaX.URL)
+	assert.Equal(t, tPkg.ID, deltaX.PackageID)
+
+	err = a.UpdatePackageDelta(&PackageDelta{ID: delta.ID, FromVersion: "13.0.0", URL: "http://sample.url/delta"})
+	assert.Equal(t, ErrInvalidPackageDelta, err)
+}
+
+func TestDeletePackageDelta(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	delta, _ := a.AddPackageDelta(&PackageDelta{FromVersion: "12.0.0", URL: "http://sample.url/delta", PackageID: tPkg.ID})
+	_, _ = a.AddPackageDelta(&PackageDelta{FromVersion: "11.0.0", URL: "http://sample.url/delta", PackageID: tPkg.ID})
+
+	deltas, err := a.GetPackageDeltas(tPkg.ID)
+	assert.NoError(t, err)
+	assert.Len(t, deltas, 2)
+
+	err = a.DeletePackageDelta(delta.ID)
+	assert.NoError(t, err)
+
+	_, err = a.GetPackageDelta(delta.ID)
+	assert.Error(t, err, "Trying to get deleted delta.")
+
+	deltas, _ = a.GetPackageDeltas(tPkg.ID)
+	assert.Len(t, deltas, 1)
+
+	err = a.DeletePackageDelta(delta.ID)
+	assert.Equal(t, ErrNoRowsAffected, err)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"time"
 
@@ -27,30 +28,86 @@ var (
 	// ErrBlacklistingChannel error indicates that the channel the package is
 	// trying to blacklist is already pointing to the package.
 	ErrBlacklistingChannel = errors.New("coreroller: channel trying to blacklist is already pointing to the package")
+
+	// ErrInvalidPackageFile error indicates that one of the files of the
+	// package is not valid (names are required and must be unique within each
+	// payload).
+	ErrInvalidPackageFile = errors.New("coreroller: invalid package file")
+
+	// ErrInvalidMinVersion error indicates that the minimum version provided
+	// for the package is not lower than the package version.
+	ErrInvalidMinVersion = errors.New("coreroller: invalid minimum version")
+
+	// ErrRetractedPackage error indicates that the package has been retracted,
+	// so it can't be assigned to channels or retracted again.
+	ErrRetractedPackage = errors.New("coreroller: package retracted")
+
+	// ErrRetractingChannelPackage error indicates that the package trying to
+	// be retracted is the package some channels are pointing to.
+	ErrRetractingChannelPackage = errors.New("coreroller: package trying to retract is pointed by some channels")
 )
 
 // Package represents a CoreRoller application's package.
 type Package struct {
-	ID                string         `db:"id" json:"id"`
-	Type              int            `db:"type" json:"type"`
-	Version           string         `db:"version" json:"version"`
-	URL               string         `db:"url" json:"url"`
-	Filename          dat.NullString `db:"filename" json:"filename"`
-	Description       dat.NullString `db:"description" json:"description"`
-	Size              dat.NullString `db:"size" json:"size"`
-	Hash              dat.NullString `db:"hash" json:"hash"`
-	CreatedTs         time.Time      `db:"created_ts" json:"created_ts"`
-	ChannelsBlacklist []string       `db:"channels_blacklist" json:"channels_blacklist"`
-	ApplicationID     string         `db:"application_id" json:"application_id"`
-	CoreosAction      *CoreosAction  `db:"coreos_action" json:"coreos_action"`
+	ID                string          `db:"id" json:"id"`
+	Type              int             `db:"type" json:"type"`
+	Version           string          `db:"version" json:"version"`
+	VersionSortKey    string          `db:"version_sort_key" json:"-"`
+	URL               string          `db:"url" json:"url"`
+	Filename          dat.NullString  `db:"filename" json:"filename"`
+	Description       dat.NullString  `db:"description" json:"description"`
+	Size              dat.NullString  `db:"size" json:"size"`
+	Hash              dat.NullString  `db:"hash" json:"hash"`
+	MinVersion        dat.NullString  `db:"min_version" json:"min_version"`
+	MinVersionSortKey dat.NullString  `db:"min_version_sort_key" json:"-"`
+	Arch              dat.NullString  `db:"arch" json:"arch"`
+	Critical          bool            `db:"critical" json:"critical"`
+	Retracted         bool            `db:"retracted" json:"retracted"`
+	RetractedTs       dat.NullTime    `db:"retracted_ts" json:"retracted_ts"`
+	CreatedTs         time.Time       `db:"created_ts" json:"created_ts"`
+	ChannelsBlacklist []string        `db:"channels_blacklist" json:"channels_blacklist"`
+	ApplicationID     string          `db:"application_id" json:"application_id"`
+	CoreosAction      *CoreosAction   `db:"coreos_action" json:"coreos_action"`
+	Deltas            []*PackageDelta `db:"deltas" json:"deltas"`
+	Files             []*PackageFile  `db:"files" json:"files"`
+	Arches            []*PackageArch  `db:"arches" json:"arches"`
+}
+
+// PackageFile represents one of the files of a package. When a package has
+// files, they are sent in the Omaha manifest instead of the package's
+// filename, hash and size. Files with an architecture are sent instead of the
+// package's payload for that architecture.
+type PackageFile struct {
+	Name       string         `db:"name" json:"name"`
+	Arch       dat.NullString `db:"arch" json:"arch"`
+	Size       dat.NullString `db:"size" json:"size"`
+	Hash       dat.NullString `db:"hash" json:"hash"`
+	HashSha256 dat.NullString `db:"hash_sha256" json:"hash_sha256"`
+	Required   bool           `db:"required" json:"required"`
 }
 
 // AddPackage registers the provided package.
 func (api *API) AddPackage(pkg *Package) (*Package, error) {
-	if !isValidSemver(pkg.Version) {
+	scheme, err := api.packageVersionScheme(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !isValidVersion(scheme, pkg.Version) {
 		return nil, ErrInvalidSemver
 	}
 
+	if err := validateMinVersion(scheme, pkg); err != nil {
+		return nil, err
+	}
+
+	if err := validatePackageFiles(pkg); err != nil {
+		return nil, err
+	}
+
+	normalizePackageArch(pkg)
+	pkg.VersionSortKey = versionSortKey(scheme, pkg.Version)
+	pkg.MinVersionSortKey = minVersionSortKey(scheme, pkg.MinVersion)
+
 	tx, err := api.dbR.Begin()
 	if err != nil {
 		return nil, err
@@ -60,7 +117,7 @@ func (api *API) AddPackage(pkg *Package) (*Package, error) {
 	}()
 
 	err = tx.InsertInto("package").
-		Whitelist("type", "filename", "description", "size", "hash", "url", "version", "application_id").
+		Whitelist("type", "filename", "description", "size", "hash", "url", "version", "version_sort_key", "min_version", "min_version_sort_key", "arch", "critical", "application_id").
 		Record(pkg).
 		Returning("*").
 		QueryStruct(pkg)
@@ -82,6 +139,10 @@ func (api *API) AddPackage(pkg *Package) (*Package, error) {
 		}
 	}
 
+	if err := api.updatePackageFiles(tx, pkg); err != nil {
+		return nil, err
+	}
+
 	if pkg.Type == PkgTypeCoreos && pkg.CoreosAction != nil {
 		err = tx.InsertInto("coreos_action").
 			Columns("package_id", "sha256").
@@ -104,10 +165,29 @@ func (api *API) AddPackage(pkg *Package) (*Package, error) {
 // UpdatePackage updates an existing package using the content of the package
 // provided.
 func (api *API) UpdatePackage(pkg *Package) error {
-	if !isValidSemver(pkg.Version) {
+	scheme, err := api.packageVersionScheme(pkg)
+	if err == sql.ErrNoRows {
+		return ErrNoRowsAffected
+	}
+	if err != nil {
+		return err
+	}
+	if !isValidVersion(scheme, pkg.Version) {
 		return ErrInvalidSemver
 	}
 
+	if err := validateMinVersion(scheme, pkg); err != nil {
+		return err
+	}
+
+	if err := validatePackageFiles(pkg); err != nil {
+		return err
+	}
+
+	normalizePackageArch(pkg)
+	pkg.VersionSortKey = versionSortKey(scheme, pkg.Version)
+	pkg.MinVersionSortKey = minVersionSortKey(scheme, pkg.MinVersion)
+
 	tx, err := api.dbR.Begin()
 	if err != nil {
 		return err
@@ -118,7 +198,7 @@ func (api *API) UpdatePackage(pkg *Package) error {
 
 	result, err := tx.
 		Update("package").
-		SetWhitelist(pkg, "type", "filename", "description", "size", "hash", "url", "version").
+		SetWhitelist(pkg, "type", "filename", "description", "size", "hash", "url", "version", "version_sort_key", "min_version", "min_version_sort_key", "arch", "critical").
 		Where("id = $1", pkg.ID).
 		Exec()
 
@@ -132,6 +212,10 @@ func (api *API) UpdatePackage(pkg *Package) error {
 		return err
 	}
 
+	if err := api.updatePackageFiles(tx, pkg); err != nil {
+		return err
+	}
+
 	if pkg.Type == PkgTypeCoreos && pkg.CoreosAction != nil {
 		err = tx.Upsert("coreos_action").
 			Columns("package_id", "sha256").
@@ -166,6 +250,92 @@ func (api *API) DeletePackage(pkgID string) error {
 	return err
 }
 
+// RetractPackage marks the package identified by the id provided as retracted.
+// Instances running the package's version will be offered the package of their
+// group's channel as a priority update, even if that means downgrading them.
+// Packages channels are pointing to can't be retracted, and the pending
+// scheduled changes to the package are cancelled. It returns the number of
+// instances running the package's version.
+func (api *API) RetractPackage(pkgID string) (int, error) {
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	var retracted struct {
+		ApplicationID string `db:"application_id"`
+		Version       string `db:"version"`
+	}
+
+	// The package is only retracted if no channel is pointing to it, checked
+	// in the same statement so that it can't race with channels updates.
+	err = tx.
+		Update("package").
+		Set("retracted", true).
+		Set("retracted_ts", nowUTC).
+		Where("id = $1 AND NOT retracted AND NOT EXISTS (SELECT 1 FROM channel WHERE package_id = package.id)", pkgID).
+		Returning("application_id", "version").
+		QueryStruct(&retracted)
+
+	if err == sql.ErrNoRows {
+		pkg, err := api.GetPackage(pkgID)
+		if err != nil {
+			return 0, err
+		}
+		if pkg.Retracted {
+			return 0, ErrRetractedPackage
+		}
+		return 0, ErrRetractingChannelPackage
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.
+		Update("channel_scheduled_change").
+		Set("status", ScheduledChangeCancelled).
+		Set("processed_ts", nowUTC).
+		Where("package_id = $1 AND status = $2", pkgID, ScheduledChangePending).
+		Exec()
+
+	if err != nil {
+		return 0, err
+	}
+
+	var instancesCount int
+	err = tx.
+		Select("count(*)").
+		From("instance_application").
+		Where("application_id = $1 AND version = $2", retracted.ApplicationID, retracted.Version).
+		QueryScalar(&instancesCount)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	_ = api.newPackageActivityEntry(activityPackageRetracted, activityWarning, retracted.Version, retracted.ApplicationID, instancesCount)
+
+	return instancesCount, nil
+}
+
+// isVersionRetracted checks if the version provided belongs to a retracted
+// package of the group's application.
+func isVersionRetracted(group *Group, version string) bool {
+	for _, v := range group.RetractedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // GetPackage returns the package identified by the id provided.
 func (api *API) GetPackage(pkgID string) (*Package, error) {
 	var pkg Package
@@ -211,6 +381,112 @@ func (api *API) GetPackages(appID string, page, perPage uint64) ([]*Package, err
 	return pkgs, err
 }
 
+// getSteppingStonePackage returns the newest package of the application of the
+// package provided that can be installed on an instance running the given
+// version and is older than the package, so that instances running a version
+// lower than the package's minimum version can get to it through intermediate
+// releases. Retracted packages and the ones blacklisted for the channel
+// provided are not considered.
+func (api *API) getSteppingStonePackage(scheme string, pkg *Package, instanceVersion, channelID string) (*Package, error) {
+	var steppingStone Package
+
+	instanceSortKey := versionSortKey(scheme, instanceVersion)
+
+	err := api.packagesQuery().
+		Where("application_id = $1", pkg.ApplicationID).
+		Where("version_sort_key > $1 AND version_sort_key < $2", instanceSortKey, versionSortKey(scheme, pkg.Version)).
+		Where("(min_version IS NULL OR min_version_sort_key <= $1)", instanceSortKey).
+		Where("NOT retracted").
+		Where("package.id NOT IN (SELECT package_id FROM package_channel_blacklist WHERE channel_id = $1)", channelID).
+		Limit(1).
+		QueryStruct(&steppingStone)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNoUpdatePackageAvailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &steppingStone, nil
+}
+
+// canBeInstalledFrom checks if the package provided can be installed on an
+// instance running the given version, based on the package's minimum version.
+func canBeInstalledFrom(scheme string, pkg *Package, instanceVersion string) bool {
+	if !pkg.MinVersion.Valid {
+		return true
+	}
+
+	return compareVersions(scheme, instanceVersion, pkg.MinVersion.String) >= 0
+}
+
+// isBlacklisted checks if the package provided is blacklisted for the given
+// channel.
+func isBlacklisted(pkg *Package, channelID string) bool {
+	for _, blacklistedChannelID := range pkg.ChannelsBlacklist {
+		if blacklistedChannelID == channelID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizePackageArch normalizes the name of the architecture of the package
+// provided. An empty architecture is considered unset.
+func normalizePackageArch(pkg *Package) {
+	arch := normalizeArch(pkg.Arch.String)
+
+	pkg.Arch = dat.NullString{}
+	if arch != "" {
+		pkg.Arch = dat.NullStringFrom(arch)
+	}
+}
+
+// validateMinVersion checks that the minimum version of the package provided,
+// if set, is a valid version in the given scheme lower than the package
+// version. An empty minimum version is considered unset.
+func validateMinVersion(scheme string, pkg *Package) error {
+	if pkg.MinVersion.Valid && pkg.MinVersion.String == "" {
+		pkg.MinVersion = dat.NullString{}
+	}
+
+	if !pkg.MinVersion.Valid {
+		return nil
+	}
+
+	if !isValidVersion(scheme, pkg.MinVersion.String) {
+		return ErrInvalidSemver
+	}
+
+	if compareVersions(scheme, pkg.MinVersion.String, pkg.Version) >= 0 {
+		return ErrInvalidMinVersion
+	}
+
+	return nil
+}
+
+// packageVersionScheme returns the version scheme of the application of the
+// package provided. When the package doesn't include its application (like in
+// updates, which can't change it), the application of the stored package is
+// used.
+func (api *API) packageVersionScheme(pkg *Package) (string, error) {
+	if pkg.ApplicationID != "" {
+		return api.getVersionScheme(pkg.ApplicationID)
+	}
+
+	var scheme string
+
+	err := api.dbR.
+		Select("application.version_scheme").
+		From("package INNER JOIN application ON (package.application_id = application.id)").
+		Where("package.id = $1", pkg.ID).
+		QueryScalar(&scheme)
+
+	return scheme, err
+}
+
 // packagesQuery returns a SelectDocBuilder prepared to return all packages.
 // This query is meant to be extended later in the methods using it to filter
 // by a specific package id, all packages that belong to a given application,
@@ -222,9 +498,12 @@ func (api *API) packagesQuery() *dat.SelectDocBuilder {
 			array_agg(pcb.channel_id) FILTER (WHERE pcb.channel_id IS NOT NULL) as channels_blacklist
 		`).
 		One("coreos_action", "SELECT * FROM coreos_action WHERE package_id = package.id").
+		Many("deltas", api.packageDeltasQuery()).
+		Many("arches", api.packageArchesQuery()).
+		Many("files", "SELECT name, arch, size, hash, hash_sha256, required FROM package_file WHERE package_id = package.id ORDER BY position ASC").
 		From("package LEFT JOIN package_channel_blacklist pcb ON package.id = pcb.package_id").
 		GroupBy("package.id").
-		OrderBy("regexp_matches(version, '(\\d+)\\.(\\d+)\\.(\\d+)')::int[] DESC")
+		OrderBy("version_sort_key DESC")
 }
 
 // updatePackageBlacklistedChannels adds or removes as needed channels to the
@@ -281,3 +560,71 @@ func (api *API) updatePackageBlacklistedChannels(tx *runner.Tx, pkg *Package) er
 
 	return nil
 }
+
+// validatePackageFiles checks that all the files of the package provided have
+// a name and that names are not repeated within the same payload. Files for
+// the package's main architecture must not set it.
+func validatePackageFiles(pkg *Package) error {
+	names := make(map[string]bool, len(pkg.Files))
+
+	for _, file := range pkg.Files {
+		if file == nil || file.Name == "" {
+			return ErrInvalidPackageFile
+		}
+		if file.Arch.Valid {
+			file.Arch.String = normalizeArch(file.Arch.String)
+			if file.Arch.String == "" || file.Arch.String == pkg.Arch.String {
+				return ErrInvalidPackageFile
+			}
+		}
+
+		key := file.Arch.String + "/" + file.Name
+		if names[key] {
+			return ErrInvalidPackageFile
+		}
+		names[key] = true
+	}
+
+	return nil
+}
+
+// PayloadFiles returns the files of the package's payload for the architecture
+// provided (empty for the main payload).
+func (pkg *Package) PayloadFiles(arch string) []*PackageFile {
+	var files []*PackageFile
+
+	for _, file := range pkg.Files {
+		if file.Arch.String == arch {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// updatePackageFiles replaces the files of the package provided with the ones
+// set in the package entry.
+//
+// This method is part of the transaction that adds or updates a package.
+func (api *API) updatePackageFiles(tx *runner.Tx, pkg *Package) error {
+	_, err := tx.DeleteFrom("package_file").
+		Where("package_id = $1", pkg.ID).
+		Exec()
+
+	if err != nil {
+		return err
+	}
+
+	for i, file := range pkg.Files {
+		_, err := tx.InsertInto("package_file").
+			Columns("position", "name", "arch", "size", "hash", "hash_sha256", "required", "package_id").
+			Values(i+1, file.Name, file.Arch, file.Size, file.Hash, file.HashSha256, file.Required, pkg.ID).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
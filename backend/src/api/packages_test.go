@@ -2,6 +2,7 @@ package api
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/mgutz/dat.v1"
 
@@ -235,3 +236,121 @@ func TestGetPackages(t *testing.T) {
 	_, err = a.GetPackages(uuid.NewV4().String(), 0, 0)
 	assert.Error(t, err, "App id used must exist.")
 }
+
+func TestPackageMinVersion(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+
+	_, err := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", MinVersion: dat.NullStringFrom("1.5"), ApplicationID: tApp.ID})
+	assert.Equal(t, ErrInvalidSemver, err)
+
+	_, err = a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", MinVersion: dat.NullStringFrom("2.0.0"), ApplicationID: tApp.ID})
+	assert.Equal(t, ErrInvalidMinVersion, err, "Minimum version must be lower than the package version.")
+
+	pkg, err := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", MinVersion: dat.NullStringFrom("1.5.0"), ApplicationID: tApp.ID})
+	assert.NoError(t, err)
+
+	pkgX, _ := a.GetPackage(pkg.ID)
+	assert.Equal(t, dat.NullStringFrom("1.5.0"), pkgX.MinVersion)
+
+	pkgX.MinVersion = dat.NullStringFrom("")
+	err = a.UpdatePackage(pkgX)
+	assert.NoError(t, err)
+
+	pkgX, _ = a.GetPackage(pkg.ID)
+	assert.False(t, pkgX.MinVersion.Valid, "Empty minimum version is stored as unset.")
+}
+
+func TestPackageFiles(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+
+	_, err := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", ApplicationID: tApp.ID, Files: []*PackageFile{{Name: "app"}, {Name: "app"}}})
+	assert.Equal(t, ErrInvalidPackageFile, err, "File names must be unique.")
+
+	_, err = a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", ApplicationID: tApp.ID, Files: []*PackageFile{{Name: ""}}})
+	assert.Equal(t, ErrInvalidPackageFile, err, "File name is required.")
+
+	pkg, err := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", ApplicationID: tApp.ID, Files: []*PackageFile{
+		{Name: "app", Size: dat.NullStringFrom("1024"), Hash: dat.NullStringFrom("sha1-app"), HashSha256: dat.NullStringFrom("sha256-app"), Required: true},
+		{Name: "assets.tgz", Size: dat.NullStringFrom("2048"), Hash: dat.NullStringFrom("sha1-assets"), Required: true},
+		{Name: "schema.json", Required: false},
+	}})
+	assert.NoError(t, err)
+
+	pkgX, _ := a.GetPackage(pkg.ID)
+	if assert.Len(t, pkgX.Files, 3) {
+		assert.Equal(t, PackageFile{Name: "app", Size: dat.NullStringFrom("1024"), Hash: dat.NullStringFrom("sha1-app"), HashSha256: dat.NullStringFrom("sha256-app"), Required: true}, *pkgX.Files[0])
+		assert.Equal(t, "assets.tgz", pkgX.Files[1].Name)
+		assert.Equal(t, "schema.json", pkgX.Files[2].Name)
+		assert.False(t, pkgX.Files[2].Required)
+	}
+
+	pkgX.Files = pkgX.Files[:1]
+	err = a.UpdatePackage(pkgX)
+	assert.NoError(t, err)
+
+	pkgX, _ = a.GetPackage(pkg.ID)
+	assert.Len(t, pkgX.Files, 1)
+
+	pkgX.Files = append(pkgX.Files, &PackageFile{Name: "app", Arch: dat.NullStringFrom("aarch64"), Required: true})
+	err = a.UpdatePackage(pkgX)
+	assert.NoError(t, err, "File names must be unique within each payload only.")
+
+	pkgX, _ = a.GetPackage(pkg.ID)
+	assert.Len(t, pkgX.PayloadFiles(""), 1)
+	if assert.Len(t, pkgX.PayloadFiles("arm64"), 1) {
+		assert.Equal(t, "arm64", pkgX.PayloadFiles("arm64")[0].Arch.String)
+	}
+}
+
+func TestRetractPackage(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.2.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg1.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+	_, _ = a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "12.2.0", tApp.ID, tGroup.ID)
+	_, _ = a.RegisterInstance(uuid.NewV4().String(), "10.0.0.2", "12.2.0", tApp.ID, tGroup.ID)
+	tChange, _ := a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel.ID, PackageID: tPkg2.ID, CreatedBy: "user1", ScheduledTs: time.Now().Add(time.Hour)})
+
+	_, err := a.RetractPackage(tPkg1.ID)
+	assert.Equal(t, ErrRetractingChannelPackage, err, "Packages channels are pointing to can't be retracted.")
+
+	instancesCount, err := a.RetractPackage(tPkg2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, instancesCount)
+
+	pkg, _ := a.GetPackage(tPkg2.ID)
+	assert.True(t, pkg.Retracted)
+	assert.True(t, pkg.RetractedTs.Valid)
+
+	change, _ := a.GetScheduledChange(tChange.ID)
+	assert.Equal(t, ScheduledChangeCancelled, change.Status, "Pending scheduled changes to retracted packages are cancelled.")
+
+	group, _ := a.getUpdatesGroup(a.dbR, tGroup.ID)
+	assert.Equal(t, []string{"12.2.0"}, group.RetractedVersions)
+
+	_, err = a.RetractPackage(tPkg2.ID)
+	assert.Equal(t, ErrRetractedPackage, err, "Package already retracted.")
+
+	tChannel.PackageID = dat.NullStringFrom(tPkg2.ID)
+	err = a.UpdateChannel(tChannel)
+	assert.Equal(t, ErrRetractedPackage, err, "Channels can't point to retracted packages.")
+
+	activityEntries, _ := a.GetActivity(tTeam.ID, ActivityQueryParams{AppID: tApp.ID, Version: "12.2.0"})
+	if assert.Len(t, activityEntries, 1) {
+		assert.Equal(t, activityPackageRetracted, activityEntries[0].Class)
+		assert.Equal(t, dat.NullInt64From(2), activityEntries[0].InstancesCount)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidPromotionRule error indicates that the source channel of a
+	// channel's promotion rule doesn't belong to the same application, is the
+	// channel itself or would create a cycle, or that the soak time provided
+	// is not valid.
+	ErrInvalidPromotionRule = errors.New("coreroller: invalid promotion rule")
+)
+
+// promotionCandidate represents a package that can be promoted to a channel
+// from the source channel of the channel's promotion rule.
+type promotionCandidate struct {
+	ChannelID      string `db:"channel_id"`
+	PackageID      string `db:"package_id"`
+	PackageVersion string `db:"package_version"`
+	VersionScheme  string `db:"version_scheme"`
+}
+
+// PromoteChannelsPackages evaluates the promotion rules of all channels,
+// updating the channels to point to the package of their promotion source
+// channel when the package has been in the source channel for at least the
+// soak time of the rule and no group using the source channel has recorded
+// failures for it since it was set in the source channel. Packages are only
+// promoted when they are newer than the channel's current package and the
+// channel isn't in their blacklist. A channel that can't be updated doesn't
+// prevent the promotion of the others, the first error found is returned
+// along with the channels that were updated.
+func (api *API) PromoteChannelsPackages() ([]*Channel, error) {
+	var candidates []*promotionCandidate
+
+	query := fmt.Sprintf(`
+	SELECT c.id channel_id, p.id package_id, p.version package_version, a.version_scheme
+	FROM channel c, channel s, package p, application a
+	WHERE c.promotion_source_channel_id = s.id AND
+		c.application_id = a.id AND
+		s.package_id = p.id AND
+		c.package_id IS DISTINCT FROM s.package_id AND
+		s.package_updated_ts + coalesce(c.promotion_soak_time, '0 seconds')::interval <= now() at time zone 'utc' AND
+		NOT EXISTS (
+			SELECT 1 FROM package_channel_blacklist
+			WHERE package_id = p.id AND channel_id = c.id
+		) AND
+		NOT EXISTS (
+			SELECT 1 FROM instance_status_history h, groups g
+			WHERE h.group_id = g.id AND g.channel_id = s.id AND h.version = p.version AND h.status = %d AND
+				h.created_ts >= s.package_updated_ts
+		)
+	`, InstanceStatusError)
+
+	if err := api.dbR.SQL(query).QueryStructs(&candidates); err != nil {
+		return nil, err
+	}
+
+	var promotedChannels []*Channel
+	var firstErr error
+
+	for _, candidate := range candidates {
+		channel, err := api.GetChannel(candidate.ChannelID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if channel.Package != nil {
+			if compareVersions(candidate.VersionScheme, channel.Package.Version, candidate.PackageVersion) >= 0 {
+				continue
+			}
+		}
+
+		channel.PackageID.String = candidate.PackageID
+		channel.PackageID.Valid = true
+		if err := api.UpdateChannel(channel); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		promotedChannels = append(promotedChannels, channel)
+	}
+
+	return promotedChannels, firstErr
+}
+
+// validatePromotionRule checks that the source channel of the promotion rule of
+// the channel provided (if any) belongs to the same application and doesn't
+// create a cycle, and that the soak time of the rule is valid.
+func (api *API) validatePromotionRule(channel *Channel, appID string) error {
+	if !channel.PromotionSourceChannelID.Valid {
+		return nil
+	}
+
+	if channel.PromotionSoakTime.Valid {
+		var validSoakTime bool
+		err := api.dbR.SQL("SELECT $1::interval >= interval '0'", channel.PromotionSoakTime.String).QueryScalar(&validSoakTime)
+		if err != nil || !validSoakTime {
+			return ErrInvalidPromotionRule
+		}
+	}
+
+	visited := map[string]bool{channel.ID: true}
+	sourceChannelID := channel.PromotionSourceChannelID.String
+	for sourceChannelID != "" {
+		if visited[sourceChannelID] {
+			return ErrInvalidPromotionRule
+		}
+		visited[sourceChannelID] = true
+
+		sourceChannel, err := api.GetChannel(sourceChannelID)
+		if err != nil || sourceChannel.ApplicationID != appID {
+			return ErrInvalidPromotionRule
+		}
+		sourceChannelID = sourceChannel.PromotionSourceChannelID.String
+	}
+
+	return nil
+}
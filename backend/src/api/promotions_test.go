@@ -0,0 +1,101 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestChannelPromotionRule(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tApp2, _ := a.AddApp(&Application{Name: "test_app2", TeamID: tTeam.ID})
+	tAlpha, _ := a.AddChannel(&Channel{Name: "alpha", Color: "blue", ApplicationID: tApp.ID})
+	tOther, _ := a.AddChannel(&Channel{Name: "other", Color: "blue", ApplicationID: tApp2.ID})
+
+	_, err := a.AddChannel(&Channel{Name: "beta", Color: "green", ApplicationID: tApp.ID, PromotionSourceChannelID: dat.NullStringFrom(tOther.ID)})
+	assert.Equal(t, ErrInvalidPromotionRule, err, "Source channel must belong to the same application.")
+
+	_, err = a.AddChannel(&Channel{Name: "beta", Color: "green", ApplicationID: tApp.ID, PromotionSourceChannelID: dat.NullStringFrom(tAlpha.ID), PromotionSoakTime: dat.NullStringFrom("invalid")})
+	assert.Equal(t, ErrInvalidPromotionRule, err)
+
+	tBeta, err := a.AddChannel(&Channel{Name: "beta", Color: "green", ApplicationID: tApp.ID, PromotionSourceChannelID: dat.NullStringFrom(tAlpha.ID), PromotionSoakTime: dat.NullStringFrom("72 hours")})
+	assert.NoError(t, err)
+	beta, _ := a.GetChannel(tBeta.ID)
+	assert.Equal(t, dat.NullStringFrom(tAlpha.ID), beta.PromotionSourceChannelID)
+	assert.Equal(t, dat.NullStringFrom("72 hours"), beta.PromotionSoakTime)
+
+	tAlpha.PromotionSourceChannelID = dat.NullStringFrom(tBeta.ID)
+	err = a.UpdateChannel(tAlpha)
+	assert.Equal(t, ErrInvalidPromotionRule, err, "Promotion rules can't create cycles.")
+}
+
+func TestPromoteChannelsPackages(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.0.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tAlpha, _ := a.AddChannel(&Channel{Name: "alpha", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg1.ID)})
+	tBeta, _ := a.AddChannel(&Channel{Name: "beta", Color: "green", ApplicationID: tApp.ID, PromotionSourceChannelID: dat.NullStringFrom(tAlpha.ID)})
+	tStable, _ := a.AddChannel(&Channel{Name: "stable", Color: "red", ApplicationID: tApp.ID, PromotionSourceChannelID: dat.NullStringFrom(tBeta.ID), PromotionSoakTime: dat.NullStringFrom("72 hours")})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tAlpha.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 5, PolicyUpdateTimeout: "60 minutes"})
+
+	promotedChannels, err := a.PromoteChannelsPackages()
+	assert.NoError(t, err)
+	assert.Len(t, promotedChannels, 1)
+	beta, _ := a.GetChannel(tBeta.ID)
+	assert.Equal(t, tPkg1.ID, beta.PackageID.String)
+	stable, _ := a.GetChannel(tStable.ID)
+	assert.False(t, stable.PackageID.Valid, "Soak time hasn't passed yet.")
+
+	tAlpha.PackageID = dat.NullStringFrom(tPkg2.ID)
+	_ = a.UpdateChannel(tAlpha)
+	tInstance, _ := a.RegisterInstance(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	_, _ = a.GetUpdatePackage(tInstance.ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	_ = a.RegisterEvent(tInstance.ID, tApp.ID, tGroup.ID, EventUpdateComplete, ResultFailed, "", "")
+
+	promotedChannels, err = a.PromoteChannelsPackages()
+	assert.NoError(t, err)
+	assert.Len(t, promotedChannels, 0, "Package failed in a group using the source channel.")
+	beta, _ = a.GetChannel(tBeta.ID)
+	assert.Equal(t, tPkg1.ID, beta.PackageID.String)
+
+	tAlpha.PackageID = dat.NullStringFrom(tPkg1.ID)
+	_ = a.UpdateChannel(tAlpha)
+	tAlpha.PackageID = dat.NullStringFrom(tPkg2.ID)
+	_ = a.UpdateChannel(tAlpha)
+
+	promotedChannels, err = a.PromoteChannelsPackages()
+	assert.NoError(t, err)
+	assert.Len(t, promotedChannels, 1, "Failures before the package was set again in the source channel are not considered.")
+	beta, _ = a.GetChannel(tBeta.ID)
+	assert.Equal(t, tPkg2.ID, beta.PackageID.String)
+}
+
+func TestPromoteChannelsPackages_BlacklistedChannel(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.0.0", ApplicationID: tApp.ID})
+	tAlpha, _ := a.AddChannel(&Channel{Name: "alpha", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg1.ID)})
+	tBeta, _ := a.AddChannel(&Channel{Name: "beta", Color: "green", ApplicationID: tApp.ID, PromotionSourceChannelID: dat.NullStringFrom(tAlpha.ID)})
+
+	tPkg1.ChannelsBlacklist = []string{tBeta.ID}
+	_ = a.UpdatePackage(tPkg1)
+
+	promotedChannels, err := a.PromoteChannelsPackages()
+	assert.NoError(t, err)
+	assert.Len(t, promotedChannels, 0)
+	beta, _ := a.GetChannel(tBeta.ID)
+	assert.False(t, beta.PackageID.Valid)
+}
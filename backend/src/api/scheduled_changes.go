@@ -0,0 +1,199 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/mgutz/dat.v1"
+)
+
+const (
+	// ScheduledChangePending indicates that the scheduled change hasn't been
+	// processed yet.
+	ScheduledChangePending int = 1 + iota
+
+	// ScheduledChangeApplied indicates that the channel was updated to point
+	// to the package of the scheduled change.
+	ScheduledChangeApplied
+
+	// ScheduledChangeSkipped indicates that the scheduled change wasn't
+	// applied because the package blacklisted the channel after the change
+	// was scheduled.
+	ScheduledChangeSkipped
+
+	// ScheduledChangeCancelled indicates that the scheduled change was
+	// cancelled before being processed.
+	ScheduledChangeCancelled
+
+	// ScheduledChangeFailed indicates that the scheduled change couldn't be
+	// applied because of an unexpected error updating the channel.
+	ScheduledChangeFailed
+)
+
+var (
+	// ErrInvalidScheduledTime error indicates that the time provided for a
+	// scheduled change is not valid.
+	ErrInvalidScheduledTime = errors.New("coreroller: invalid scheduled time")
+)
+
+// ScheduledChange represents a change of the package a channel points to that
+// will be applied at a given time.
+type ScheduledChange struct {
+	ID          string       `db:"id" json:"id"`
+	ScheduledTs time.Time    `db:"scheduled_ts" json:"scheduled_ts"`
+	CreatedTs   time.Time    `db:"created_ts" json:"created_ts"`
+	CreatedBy   string       `db:"created_by" json:"created_by"`
+	Status      int          `db:"status" json:"status"`
+	ProcessedTs dat.NullTime `db:"processed_ts" json:"processed_ts"`
+	ChannelID   string       `db:"channel_id" json:"channel_id"`
+	PackageID   string       `db:"package_id" json:"package_id"`
+	Package     *Package     `db:"package" json:"package,omitempty"`
+}
+
+// AddScheduledChange registers the provided scheduled change.
+func (api *API) AddScheduledChange(change *ScheduledChange) (*ScheduledChange, error) {
+	if change.ScheduledTs.IsZero() {
+		return nil, ErrInvalidScheduledTime
+	}
+
+	channel, err := api.GetChannel(change.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := api.validatePackage(change.PackageID, channel.ID, channel.ApplicationID); err != nil {
+		return nil, err
+	}
+
+	err = api.dbR.
+		InsertInto("channel_scheduled_change").
+		Whitelist("scheduled_ts", "created_by", "channel_id", "package_id").
+		Record(change).
+		Returning("*").
+		QueryStruct(change)
+
+	return change, err
+}
+
+// CancelScheduledChange cancels the scheduled change of the given channel
+// identified by the id provided, as long as it hasn't been processed yet.
+func (api *API) CancelScheduledChange(channelID, changeID string) error {
+	result, err := api.dbR.
+		Update("channel_scheduled_change").
+		Set("status", ScheduledChangeCancelled).
+		Set("processed_ts", nowUTC).
+		Where("id = $1 AND channel_id = $2 AND status = $3", changeID, channelID, ScheduledChangePending).
+		Exec()
+
+	if err == nil && result.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return err
+}
+
+// GetScheduledChange returns the scheduled change identified by the id
+// provided.
+func (api *API) GetScheduledChange(changeID string) (*ScheduledChange, error) {
+	var change ScheduledChange
+
+	err := api.scheduledChangesQuery().
+		Where("id = $1", changeID).
+		QueryStruct(&change)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// GetScheduledChanges returns all scheduled changes of the channel provided.
+func (api *API) GetScheduledChanges(channelID string, page, perPage uint64) ([]*ScheduledChange, error) {
+	page, perPage = validatePaginationParams(page, perPage)
+
+	var changes []*ScheduledChange
+
+	err := api.scheduledChangesQuery().
+		Where("channel_id = $1", channelID).
+		Paginate(page, perPage).
+		QueryStructs(&changes)
+
+	return changes, err
+}
+
+// ApplyScheduledChanges updates the channels of the pending scheduled changes
+// whose time has come to point to the changes' packages. Changes whose package
+// has blacklisted the channel since they were scheduled are skipped, and the
+// ones that can't be applied because of any other error are marked as failed,
+// so that they don't prevent the rest from being processed. It returns the
+// scheduled changes processed.
+func (api *API) ApplyScheduledChanges() ([]*ScheduledChange, error) {
+	var changes []*ScheduledChange
+
+	err := api.scheduledChangesQuery().
+		Where("status = $1 AND scheduled_ts <= now() at time zone 'utc'", ScheduledChangePending).
+		QueryStructs(&changes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var processedChanges []*ScheduledChange
+
+	// Changes are processed from the oldest to the newest one, so that the
+	// last change scheduled for a channel is the one that prevails.
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		channel, err := api.GetChannel(change.ChannelID)
+		if err != nil {
+			change.Status = ScheduledChangeFailed
+		} else {
+			channel.PackageID = dat.NullStringFrom(change.PackageID)
+			err = api.UpdateChannel(channel)
+
+			switch err {
+			case nil:
+				change.Status = ScheduledChangeApplied
+			case ErrBlacklistedChannel, ErrRetractedPackage:
+				change.Status = ScheduledChangeSkipped
+				_ = api.newChannelActivityEntry(activityScheduledChangeSkipped, activityWarning, change.Package.Version, channel.ApplicationID, channel.ID)
+			default:
+				change.Status = ScheduledChangeFailed
+				_ = api.newChannelActivityEntry(activityScheduledChangeFailed, activityError, change.Package.Version, channel.ApplicationID, channel.ID)
+			}
+		}
+
+		if err := api.setScheduledChangeStatus(change.ID, change.Status); err != nil {
+			return processedChanges, err
+		}
+		processedChanges = append(processedChanges, change)
+	}
+
+	return processedChanges, nil
+}
+
+// setScheduledChangeStatus updates the status of the scheduled change provided,
+// marking it as processed.
+func (api *API) setScheduledChangeStatus(changeID string, status int) error {
+	_, err := api.dbR.
+		Update("channel_scheduled_change").
+		Set("status", status).
+		Set("processed_ts", nowUTC).
+		Where("id = $1", changeID).
+		Exec()
+
+	return err
+}
+
+// scheduledChangesQuery returns a SelectDocBuilder prepared to return all
+// scheduled changes. This query is meant to be extended later in the methods
+// using it to filter by a specific scheduled change id, all scheduled changes
+// of a given channel, specify how to query the rows or their destination.
+func (api *API) scheduledChangesQuery() *dat.SelectDocBuilder {
+	return api.dbR.
+		SelectDoc("*").
+		One("package", api.packagesQuery().Where("package.id = channel_scheduled_change.package_id")).
+		From("channel_scheduled_change").
+		OrderBy("scheduled_ts DESC")
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestAddScheduledChange(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tApp2, _ := a.AddApp(&Application{Name: "test_app2", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp2.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID})
+
+	_, err := a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel.ID, PackageID: tPkg.ID, CreatedBy: "user1"})
+	assert.Equal(t, ErrInvalidScheduledTime, err)
+
+	_, err = a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel.ID, PackageID: tPkg2.ID, CreatedBy: "user1", ScheduledTs: time.Now().Add(time.Hour)})
+	assert.Equal(t, ErrInvalidPackage, err)
+
+	change, err := a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel.ID, PackageID: tPkg.ID, CreatedBy: "user1", ScheduledTs: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+
+	changeX, err := a.GetScheduledChange(change.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", changeX.CreatedBy)
+	assert.Equal(t, ScheduledChangePending, changeX.Status)
+	assert.Equal(t, tPkg.Version, changeX.Package.Version)
+
+	changes, err := a.GetScheduledChanges(tChannel.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+
+	err = a.CancelScheduledChange(tPkg.ID, change.ID)
+	assert.Equal(t, ErrNoRowsAffected, err, "Scheduled changes can only be cancelled through their channel.")
+
+	err = a.CancelScheduledChange(tChannel.ID, change.ID)
+	assert.NoError(t, err)
+	changeX, _ = a.GetScheduledChange(change.ID)
+	assert.Equal(t, ScheduledChangeCancelled, changeX.Status)
+
+	err = a.CancelScheduledChange(tChannel.ID, change.ID)
+	assert.Equal(t, ErrNoRowsAffected, err, "Scheduled change already processed.")
+}
+
+func TestApplyScheduledChanges(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.2.0", ApplicationID: tApp.ID})
+	tChannel1, _ := a.AddChannel(&Channel{Name: "channel1", Color: "blue", ApplicationID: tApp.ID})
+	tChannel2, _ := a.AddChannel(&Channel{Name: "channel2", Color: "blue", ApplicationID: tApp.ID})
+
+	tChange1, _ := a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel1.ID, PackageID: tPkg1.ID, CreatedBy: "user1", ScheduledTs: time.Now().Add(-time.Minute)})
+	tChange2, _ := a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel1.ID, PackageID: tPkg2.ID, CreatedBy: "user1", ScheduledTs: time.Now().Add(time.Hour)})
+	tChange3, _ := a.AddScheduledChange(&ScheduledChange{ChannelID: tChannel2.ID, PackageID: tPkg2.ID, CreatedBy: "user1", ScheduledTs: time.Now().Add(-time.Minute)})
+
+	tPkg2.ChannelsBlacklist = []string{tChannel2.ID}
+	_ = a.UpdatePackage(tPkg2)
+
+	changes, err := a.ApplyScheduledChanges()
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+
+	channel1, _ := a.GetChannel(tChannel1.ID)
+	assert.Equal(t, dat.NullStringFrom(tPkg1.ID), channel1.PackageID)
+	change1, _ := a.GetScheduledChange(tChange1.ID)
+	assert.Equal(t, ScheduledChangeApplied, change1.Status)
+	assert.True(t, change1.ProcessedTs.Valid)
+
+	change2, _ := a.GetScheduledChange(tChange2.ID)
+	assert.Equal(t, ScheduledChangePending, change2.Status, "Scheduled change time hasn't come yet.")
+
+	channel2, _ := a.GetChannel(tChannel2.ID)
+	assert.False(t, channel2.PackageID.Valid)
+	change3, _ := a.GetScheduledChange(tChange3.ID)
+	assert.Equal(t, ScheduledChangeSkipped, change3.Status, "Package has blacklisted the channel.")
+
+	activityEntries, _ := a.GetActivity(tTeam.ID, ActivityQueryParams{AppID: tApp.ID, Severity: activityWarning})
+	assert.Len(t, activityEntries, 1)
+}
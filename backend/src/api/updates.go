@@ -1,10 +1,12 @@
 package api
 
 import (
+	"crypto/sha1"
+	"encoding/binary"
 	"errors"
 	"time"
 
-	"github.com/blang/semver"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
 )
 
 var (
@@ -54,101 +56,119 @@ var (
 // provided. The instance details and the application it's running will be
 // registered in CoreRoller (or updated if it's already registered).
 func (api *API) GetUpdatePackage(instanceID, instanceIP, instanceVersion, appID, groupID string) (*Package, error) {
+	return api.GetUpdatePackageForArch(instanceID, instanceIP, instanceVersion, appID, groupID, "")
+}
+
+// GetUpdatePackageForArch works like GetUpdatePackage, but also checks that
+// the package has a payload for the architecture of the instance provided.
+func (api *API) GetUpdatePackageForArch(instanceID, instanceIP, instanceVersion, appID, groupID, arch string) (*Package, error) {
 	instance, err := api.RegisterInstance(instanceID, instanceIP, instanceVersion, appID, groupID)
 	if err != nil {
 		return nil, ErrRegisterInstanceFailed
 	}
 
-	if instance.Application.Status.Valid {
-		switch int(instance.Application.Status.Int64) {
-		case InstanceStatusUpdateGranted, InstanceStatusDownloading, InstanceStatusDownloaded, InstanceStatusInstalled:
-			return nil, ErrUpdateInProgressOnInstance
-		}
-	}
-
-	group, err := api.GetGroup(groupID)
+	// The group is locked while the decision is made and the update granted,
+	// so that concurrent requests see the updates granted by each other and
+	// the limits of the rollout policy can't be exceeded.
+	tx, err := api.dbR.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
 
-	if group.Channel == nil || group.Channel.Package == nil {
-		_ = api.newGroupActivityEntry(activityPackageNotFound, activityWarning, "0.0.0", appID, groupID)
-		return nil, ErrNoPackageFound
-	}
-
-	for _, blacklistedChannelID := range group.Channel.Package.ChannelsBlacklist {
-		if blacklistedChannelID == group.Channel.ID {
-			return nil, ErrNoUpdatePackageAvailable
-		}
-	}
-
-	instanceSemver, _ := semver.Make(instanceVersion)
-	packageSemver, _ := semver.Make(group.Channel.Package.Version)
-	if !instanceSemver.LT(packageSemver) {
-		return nil, ErrNoUpdatePackageAvailable
+	if err := api.lockGroup(tx, groupID); err != nil {
+		return nil, err
 	}
 
-	updatesStats, err := api.getGroupUpdatesStats(group)
+	group, err := api.getUpdatesGroup(tx, groupID)
 	if err != nil {
-		return nil, ErrGetUpdatesStatsFailed
-	}
-
-	if err := api.enforceRolloutPolicy(instance, group, updatesStats); err != nil {
 		return nil, err
 	}
 
-	if err := api.grantUpdate(instance.ID, appID, group.Channel.Package.Version); err != nil {
-		return nil, ErrGrantingUpdate
-	}
+	decision := api.decideUpdate(tx, instance, group, arch, time.Now())
 
-	if updatesStats.UpdatesToCurrentVersionGranted == 0 {
-		_ = api.newGroupActivityEntry(activityRolloutStarted, activityInfo, group.Channel.Package.Version, appID, group.ID)
+	criticalRecorded := false
+	if decision.err == nil {
+		if err := api.grantUpdate(tx, instance.ID, appID, decision.Package.Version); err != nil {
+			return nil, ErrGrantingUpdate
+		}
+		if err := api.updateInstanceStatus(tx, instance.ID, appID, InstanceStatusUpdateGranted); err != nil {
+			return nil, ErrGrantingUpdate
+		}
+		if !group.RolloutInProgress {
+			if err := api.setGroupRolloutInProgress(tx, groupID, true); err != nil {
+				return nil, ErrGrantingUpdate
+			}
+		}
+		if decision.critical {
+			criticalRecorded, _ = api.newGroupActivityEntryOnce(tx, activityCriticalUpdatePolicyApplied, activityWarning, decision.Package.Version, appID, groupID)
+		}
 	}
 
-	if !group.RolloutInProgress {
-		_ = api.setGroupRolloutInProgress(groupID, true)
+	if err := tx.Commit(); err != nil {
+		return nil, ErrGrantingUpdate
 	}
 
-	_ = api.updateInstanceStatus(instance.ID, appID, InstanceStatusUpdateGranted)
-
-	return group.Channel.Package, nil
-}
+	switch decision.err {
+	case nil:
+	case ErrNoPackageFound:
+		_ = api.newGroupActivityEntry(activityPackageNotFound, activityWarning, "0.0.0", appID, groupID)
+		return nil, decision.err
+	case ErrMaxUpdatesPerPeriodLimitReached, ErrMaxConcurrentUpdatesLimitReached:
+		_ = api.updateInstanceStatus(api.dbR, instance.ID, appID, InstanceStatusOnHold)
+		return nil, decision.err
+	case ErrMaxTimedOutUpdatesLimitReached:
+		if group.PolicyTimedOutMarkFailed {
+			_ = api.markTimedOutUpdatesFailed(group)
+		} else if group.PolicyUpdatesEnabled {
+			_ = api.disableUpdates(group.ID)
+		}
+		_ = api.updateInstanceStatus(api.dbR, instance.ID, appID, InstanceStatusOnHold)
+		return nil, decision.err
+	default:
+		return nil, decision.err
+	}
 
-// enforceRolloutPolicy validates if an update should be provided to the
-// requesting instance based on the group rollout policy and the current status
-// of the updates taking place in the group.
-func (api *API) enforceRolloutPolicy(instance *Instance, group *Group, updatesStats *UpdatesStats) error {
-	appID := instance.Application.ApplicationID
+	pkg := decision.Package
 
-	if !group.PolicyUpdatesEnabled {
-		return ErrUpdatesDisabled
+	if pkg.ID == group.Channel.Package.ID && decision.updatesStats.UpdatesToCurrentVersionGranted == 0 {
+		_ = api.newGroupActivityEntry(activityRolloutStarted, activityInfo, pkg.Version, appID, group.ID)
 	}
 
-	if group.PolicyOfficeHours && !inOfficeHoursNow(group.PolicyTimezone.String) {
-		return ErrUpdatesDisabled
+	if criticalRecorded {
+		go api.postHipchat(activityCriticalUpdatePolicyApplied, activityWarning, pkg.Version, &activityContext{appID: appID, groupID: groupID})
 	}
 
-	effectiveMaxUpdates := group.PolicyMaxUpdatesPerPeriod
-	if group.PolicySafeMode && updatesStats.UpdatesToCurrentVersionAttempted == 0 {
-		effectiveMaxUpdates = 1
-	}
+	return pkg, nil
+}
 
-	if updatesStats.UpdatesGrantedInLastPeriod >= effectiveMaxUpdates {
-		_ = api.updateInstanceStatus(instance.ID, appID, InstanceStatusOnHold)
-		return ErrMaxUpdatesPerPeriodLimitReached
+// markTimedOutUpdatesFailed marks as failed the updates that timed out in the
+// group provided (including the ones already reaped), so that they don't count
+// as timed out updates anymore.
+func (api *API) markTimedOutUpdatesFailed(group *Group) error {
+	var timedOutUpdates []*struct {
+		InstanceID        string `db:"instance_id"`
+		ApplicationID     string `db:"application_id"`
+		LastUpdateVersion string `db:"last_update_version"`
 	}
 
-	if updatesStats.UpdatesInProgress >= effectiveMaxUpdates {
-		_ = api.updateInstanceStatus(instance.ID, appID, InstanceStatusOnHold)
-		return ErrMaxConcurrentUpdatesLimitReached
+	query := `
+	SELECT instance_id, application_id, last_update_version
+	FROM instance_application
+	WHERE group_id = $1 AND
+		((update_in_progress = 'true' AND now() at time zone 'utc' - last_update_granted_ts > interval $2) OR status = $3)
+	`
+	if err := api.dbR.SQL(query, group.ID, group.PolicyUpdateTimeout, InstanceStatusTimedOut).QueryStructs(&timedOutUpdates); err != nil {
+		return err
 	}
 
-	if updatesStats.UpdatesTimedOut >= effectiveMaxUpdates {
-		if group.PolicyUpdatesEnabled {
-			_ = api.disableUpdates(group.ID)
+	for _, u := range timedOutUpdates {
+		if err := api.updateInstanceStatus(api.dbR, u.InstanceID, u.ApplicationID, InstanceStatusError); err != nil {
+			return err
 		}
-		_ = api.updateInstanceStatus(instance.ID, appID, InstanceStatusOnHold)
-		return ErrMaxTimedOutUpdatesLimitReached
+		_ = api.newInstanceActivityEntry(activityInstanceUpdateFailed, activityError, u.LastUpdateVersion, u.ApplicationID, group.ID, u.InstanceID)
 	}
 
 	return nil
@@ -156,8 +176,11 @@ func (api *API) enforceRolloutPolicy(instance *Instance, group *Group, updatesSt
 
 // grantUpdate grants an update for the provided instance in the context of the
 // given application.
-func (api *API) grantUpdate(instanceID, appID, version string) error {
-	_, err := api.dbR.
+//
+// This method is part of the transaction that locks the instance's group while
+// deciding if the update should be granted.
+func (api *API) grantUpdate(tx *runner.Tx, instanceID, appID, version string) error {
+	_, err := tx.
 		Update("instance_application").
 		Set("last_update_granted_ts", nowUTC).
 		Set("last_update_version", version).
@@ -168,8 +191,56 @@ func (api *API) grantUpdate(instanceID, appID, version string) error {
 	return err
 }
 
-// inOfficeHoursNow checks if the provided timezone is now in office hours.
-func inOfficeHoursNow(tz string) bool {
+// isUpdateAvailable checks if the package version provided should be offered to
+// an instance running the given version, compared using the version scheme
+// given. By default only newer versions are offered, but when the exact
+// version mode is enabled the package version is considered the desired
+// version for the instance, so it will be offered to any instance running a
+// different one (including newer ones).
+func isUpdateAvailable(scheme, instanceVersion, packageVersion string, exactVersion bool) bool {
+	c := compareVersions(scheme, instanceVersion, packageVersion)
+
+	if exactVersion {
+		return c != 0
+	}
+
+	return c < 0
+}
+
+// rolloutPercentage returns the percentage of the group's instances that are
+// allowed to get the package the group's channel is pointing to, based on the
+// rollout ramp schedule stage the group is in or its rollout percentage.
+func rolloutPercentage(group *Group) float64 {
+	if group.RolloutStage != nil {
+		return group.RolloutStage.Percentage
+	}
+
+	if group.PolicyRolloutPercentage.Valid {
+		return group.PolicyRolloutPercentage.Float64
+	}
+
+	return 100
+}
+
+// inRolloutCohort checks if the instance provided belongs to the cohort of
+// instances allowed to get the version provided when only a percentage of the
+// instances can get it. The instance id is hashed with the version, so the same
+// instances will stay in the cohort for the whole rollout of that version as
+// the percentage increases.
+func inRolloutCohort(instanceID, version string, percentage float64) bool {
+	if percentage >= 100 {
+		return true
+	}
+
+	hash := sha1.Sum([]byte(instanceID + "@" + version))
+	bucket := binary.BigEndian.Uint64(hash[:8]) % 10000
+
+	return float64(bucket) < percentage*100
+}
+
+// inUpdateWindow checks if the time provided falls within any of the update
+// windows given, interpreting them in the provided timezone.
+func inUpdateWindow(windows []*UpdateWindow, tz string, t time.Time) bool {
 	if tz == "" {
 		return false
 	}
@@ -179,13 +250,88 @@ func inOfficeHoursNow(tz string) bool {
 		return false
 	}
 
-	now := time.Now().In(location)
-	if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
-		return false
+	t = t.In(location)
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	for _, window := range windows {
+		start, err := parseTimeOfDay(window.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := parseTimeOfDay(window.EndTime)
+		if err != nil {
+			continue
+		}
+
+		if start < end {
+			if hasWeekday(window, today) && sinceMidnight >= start && sinceMidnight < end {
+				return true
+			}
+			continue
+		}
+
+		if hasWeekday(window, today) && sinceMidnight >= start {
+			return true
+		}
+		if hasWeekday(window, yesterday) && sinceMidnight < end {
+			return true
+		}
 	}
-	if now.Hour() < 9 || now.Hour() >= 17 {
-		return false
+
+	return false
+}
+
+// hasWeekday checks if the update window provided includes the given weekday.
+func hasWeekday(window *UpdateWindow, weekday time.Weekday) bool {
+	for _, wd := range window.Weekdays {
+		if wd == int(weekday) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReapTimedOutUpdates looks for updates in progress that have been granted for
+// longer than their group's update timeout, setting the status of the
+// instances to timed out. This way the instances are allowed to request the
+// update again, according to the group's rollout policy. It returns the number
+// of updates reaped.
+func (api *API) ReapTimedOutUpdates() (int, error) {
+	var timedOutUpdates []*struct {
+		InstanceID        string `db:"instance_id"`
+		ApplicationID     string `db:"application_id"`
+		GroupID           string `db:"group_id"`
+		LastUpdateVersion string `db:"last_update_version"`
+	}
+
+	// The updates are reaped (and their status history recorded) in a single
+	// statement, so that an update reported back while reaping can't be
+	// marked as timed out after having completed.
+	query := `
+	WITH reaped AS (
+		UPDATE instance_application ia
+		SET status = $1, update_in_progress = 'false'
+		FROM groups g
+		WHERE ia.group_id = g.id AND
+			ia.update_in_progress = 'true' AND
+			now() at time zone 'utc' - ia.last_update_granted_ts > g.policy_update_timeout::interval
+		RETURNING ia.instance_id, ia.application_id, ia.group_id, ia.last_update_version
+	), history AS (
+		INSERT INTO instance_status_history (status, version, instance_id, application_id, group_id)
+		SELECT $1, last_update_version, instance_id, application_id, group_id FROM reaped
+	)
+	SELECT instance_id, application_id, group_id, last_update_version FROM reaped
+	`
+	if err := api.dbR.SQL(query, InstanceStatusTimedOut).QueryStructs(&timedOutUpdates); err != nil {
+		return 0, err
+	}
+
+	for _, u := range timedOutUpdates {
+		_ = api.newInstanceActivityEntry(activityInstanceUpdateTimedOut, activityWarning, u.LastUpdateVersion, u.ApplicationID, u.GroupID, u.InstanceID)
 	}
 
-	return true
+	return len(timedOutUpdates), nil
 }
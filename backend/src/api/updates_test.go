@@ -1,6 +1,7 @@
 package api
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -145,7 +146,7 @@ func TestGetUpdatePackage_MaxUpdatesLimitsReached(t *testing.T) {
 	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
 	assert.Equal(t, ErrMaxConcurrentUpdatesLimitReached, err, "Period interval is over, but there are still two updates not completed or failed.")
 
-	_ = a.updateInstanceStatus(newInstance1ID, tApp.ID, InstanceStatusComplete)
+	_ = a.updateInstanceStatus(a.dbR, newInstance1ID, tApp.ID, InstanceStatusComplete)
 
 	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
 	assert.NoError(t, err)
@@ -282,3 +283,427 @@ func TestGetUpdatePackage_InstanceStatusHistory(t *testing.T) {
 	assert.Equal(t, InstanceStatusUpdateGranted, instanceStatusHistory[2].Status)
 	assert.Equal(t, tPkg.Version, instanceStatusHistory[2].Version)
 }
+
+func TestGetUpdatePackage_RolloutPercentage(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 100, PolicyUpdateTimeout: "60 minutes", PolicyRolloutPercentage: dat.NullFloat64From(0)})
+
+	_, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err, "Rollout percentage is 0, no instance is in the cohort.")
+
+	var inCohort, notInCohort string
+	for inCohort == "" || notInCohort == "" {
+		instanceID := uuid.NewV4().String()
+		if inRolloutCohort(instanceID, tPkg.Version, 50) {
+			inCohort = instanceID
+		} else {
+			notInCohort = instanceID
+		}
+	}
+
+	tGroup.PolicyRolloutPercentage = dat.NullFloat64From(50)
+	_ = a.UpdateGroup(tGroup)
+
+	_, err = a.GetUpdatePackage(notInCohort, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err, "Instance is not in the rollout cohort.")
+
+	_, err = a.GetUpdatePackage(inCohort, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	tGroup.PolicyRolloutPercentage = dat.NullFloat64{}
+	tGroup.PolicyRolloutStages = []*RolloutStage{{Percentage: 100, Duration: "1 hour"}}
+	_ = a.UpdateGroup(tGroup)
+
+	_, err = a.GetUpdatePackage(notInCohort, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+}
+
+func TestInRolloutCohort(t *testing.T) {
+	instancesInCohort := 0
+	for i := 0; i < 10000; i++ {
+		instanceID := uuid.NewV4().String()
+		inCohort := inRolloutCohort(instanceID, "1.0.0", 10)
+		if inCohort {
+			instancesInCohort++
+		}
+		assert.Equal(t, inCohort, inRolloutCohort(instanceID, "1.0.0", 10))
+		if inCohort {
+			assert.True(t, inRolloutCohort(instanceID, "1.0.0", 50), "Instances in the cohort must stay in it when the percentage increases.")
+		}
+	}
+	assert.InDelta(t, 1000, instancesInCohort, 200)
+
+	assert.True(t, inRolloutCohort(uuid.NewV4().String(), "1.0.0", 100))
+	assert.False(t, inRolloutCohort(uuid.NewV4().String(), "1.0.0", 0))
+}
+
+func TestGetUpdatePackage_OutsideUpdateWindows(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	now := time.Now().UTC()
+	tomorrow := int((now.Weekday() + 1) % 7)
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyTimezone: dat.NullStringFrom("UTC"), PolicyUpdateWindows: []*UpdateWindow{{Weekdays: []int{tomorrow}, StartTime: "00:00", EndTime: "23:59"}}, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+
+	_, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrUpdatesDisabled, err, "Updates are only allowed tomorrow.")
+
+	tGroup.PolicyUpdateWindows = []*UpdateWindow{{Weekdays: []int{0, 1, 2, 3, 4, 5, 6}, StartTime: "00:00", EndTime: "00:00:01"}, {Weekdays: []int{0, 1, 2, 3, 4, 5, 6}, StartTime: "00:00:01", EndTime: "00:00"}}
+	_ = a.UpdateGroup(tGroup)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+}
+
+func TestInUpdateWindow(t *testing.T) {
+	windows := []*UpdateWindow{
+		{Weekdays: []int{int(time.Tuesday), int(time.Thursday)}, StartTime: "02:00", EndTime: "05:00"},
+		{Weekdays: []int{int(time.Saturday)}, StartTime: "22:00", EndTime: "01:30"},
+	}
+
+	testCases := []struct {
+		time     string
+		expected bool
+	}{
+		{"2017-06-06T01:59:59Z", false}, // Tuesday
+		{"2017-06-06T02:00:00Z", true},
+		{"2017-06-06T04:59:59Z", true},
+		{"2017-06-06T05:00:00Z", false},
+		{"2017-06-07T03:00:00Z", false}, // Wednesday
+		{"2017-06-08T03:00:00Z", true},  // Thursday
+		{"2017-06-10T21:59:59Z", false}, // Saturday
+		{"2017-06-10T23:00:00Z", true},
+		{"2017-06-11T01:00:00Z", true}, // Sunday
+		{"2017-06-11T01:30:00Z", false},
+		{"2017-06-11T23:00:00Z", false},
+	}
+
+	for _, tc := range testCases {
+		tt, _ := time.Parse(time.RFC3339, tc.time)
+		assert.Equal(t, tc.expected, inUpdateWindow(windows, "UTC", tt), tc.time)
+	}
+
+	tt, _ := time.Parse(time.RFC3339, "2017-06-06T03:00:00Z")
+	assert.True(t, inUpdateWindow(windows, "Europe/Madrid", tt.Add(-2*time.Hour)), "03:00 in Madrid is 01:00 UTC in summer.")
+	assert.False(t, inUpdateWindow(windows, "", tt))
+	assert.False(t, inUpdateWindow(windows, "Invalid/Timezone", tt))
+}
+
+func TestGetUpdatePackage_ExactVersion(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 1, PolicyUpdateTimeout: "60 minutes"})
+
+	_, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "13.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err, "Downgrades not allowed by default.")
+
+	tGroup.PolicyExactVersion = true
+	_ = a.UpdateGroup(tGroup)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.1.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err, "Instance already running the channel's version.")
+
+	pkg, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "13.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "12.1.0", pkg.Version)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.2", "13.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrMaxUpdatesPerPeriodLimitReached, err, "Downgrades are subject to the rollout policy.")
+}
+
+func TestIsUpdateAvailable(t *testing.T) {
+	assert.True(t, isUpdateAvailable(VersionSchemeSemver, "1.0.0", "1.1.0", false))
+	assert.False(t, isUpdateAvailable(VersionSchemeSemver, "1.1.0", "1.1.0", false))
+	assert.False(t, isUpdateAvailable(VersionSchemeSemver, "1.2.0", "1.1.0", false))
+
+	assert.True(t, isUpdateAvailable(VersionSchemeSemver, "1.0.0", "1.1.0", true))
+	assert.False(t, isUpdateAvailable(VersionSchemeSemver, "1.1.0", "1.1.0", true))
+	assert.True(t, isUpdateAvailable(VersionSchemeSemver, "1.2.0", "1.1.0", true))
+}
+
+func TestGetUpdatePackage_MaxConcurrentUpdatesLimitReached(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "1 hour", PolicyMaxUpdatesPerPeriod: 10, PolicyMaxConcurrentUpdates: 2, PolicyUpdateTimeout: "60 minutes"})
+
+	newInstance1ID := uuid.NewV4().String()
+
+	_, err := a.GetUpdatePackage(newInstance1ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrMaxConcurrentUpdatesLimitReached, err, "Only two updates can be in progress at the same time.")
+
+	_ = a.updateInstanceStatus(a.dbR, newInstance1ID, tApp.ID, InstanceStatusComplete)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	tGroup.PolicyMaxConcurrentUpdates = -1
+	err = a.UpdateGroup(tGroup)
+	assert.Equal(t, ErrInvalidUpdatesLimits, err)
+}
+
+func TestGetUpdatePackage_MaxTimedOutUpdatesLimitReached_MarkFailed(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "100 milliseconds", PolicyMaxUpdatesPerPeriod: 5, PolicyMaxTimedOutUpdates: 1, PolicyTimedOutMarkFailed: true, PolicyUpdateTimeout: "100 milliseconds"})
+
+	newInstance1ID := uuid.NewV4().String()
+
+	_, err := a.GetUpdatePackage(newInstance1ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrMaxTimedOutUpdatesLimitReached, err)
+
+	group, _ := a.GetGroup(tGroup.ID)
+	assert.True(t, group.PolicyUpdatesEnabled, "Timed out updates are marked as failed instead of disabling updates.")
+	instance, _ := a.GetInstance(newInstance1ID, tApp.ID)
+	assert.Equal(t, dat.NullInt64From(int64(InstanceStatusError)), instance.Application.Status)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+}
+
+func TestReapTimedOutUpdates(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "100 milliseconds", PolicyMaxUpdatesPerPeriod: 5, PolicyUpdateTimeout: "100 milliseconds"})
+
+	newInstance1ID := uuid.NewV4().String()
+	newInstance2ID := uuid.NewV4().String()
+
+	_, err := a.GetUpdatePackage(newInstance1ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	reaped, err := a.ReapTimedOutUpdates()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, reaped, "Update hasn't timed out yet.")
+
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = a.GetUpdatePackage(newInstance2ID, "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	_, err = a.GetUpdatePackage(newInstance1ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrUpdateInProgressOnInstance, err)
+
+	reaped, err = a.ReapTimedOutUpdates()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reaped)
+
+	instance, _ := a.GetInstance(newInstance1ID, tApp.ID)
+	assert.Equal(t, dat.NullInt64From(int64(InstanceStatusTimedOut)), instance.Application.Status)
+	assert.False(t, instance.Application.UpdateInProgress)
+
+	history, _ := a.GetInstanceStatusHistory(newInstance1ID, tApp.ID, tGroup.ID, 10)
+	assert.Equal(t, InstanceStatusTimedOut, history[0].Status)
+
+	group, _ := a.GetGroup(tGroup.ID)
+	assert.Equal(t, 1, group.InstancesStats.TimedOut)
+
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = a.GetUpdatePackage(newInstance1ID, "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err, "Timed out instances are allowed to request the update again.")
+}
+
+func TestGetUpdatePackage_SteppingStone(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg130, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "1.3.0", ApplicationID: tApp.ID})
+	tPkg150, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "1.5.0", ApplicationID: tApp.ID})
+	_, _ = a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "1.8.0", MinVersion: dat.NullStringFrom("1.4.0"), ApplicationID: tApp.ID})
+	tPkg200, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "2.0.0", MinVersion: dat.NullStringFrom("1.5.0"), ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg200.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "test_group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+
+	pkg, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "1.2.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg150.ID, pkg.ID, "1.8.0 can't be installed from 1.2.0, so 1.5.0 is the newest stepping stone.")
+
+	pkg, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.2", "1.5.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg200.ID, pkg.ID)
+
+	pkg, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "1.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg150.ID, pkg.ID)
+
+	tPkg150.ChannelsBlacklist = []string{tChannel.ID}
+	_ = a.UpdatePackage(tPkg150)
+
+	pkg, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.4", "1.2.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg130.ID, pkg.ID, "Packages blacklisted for the channel aren't used as stepping stones.")
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.5", "1.3.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err)
+}
+
+func TestCanBeInstalledFrom(t *testing.T) {
+	assert.True(t, canBeInstalledFrom(VersionSchemeSemver, &Package{Version: "2.0.0"}, "1.0.0"))
+	assert.True(t, canBeInstalledFrom(VersionSchemeSemver, &Package{Version: "2.0.0", MinVersion: dat.NullStringFrom("1.5.0")}, "1.5.0"))
+	assert.True(t, canBeInstalledFrom(VersionSchemeSemver, &Package{Version: "2.0.0", MinVersion: dat.NullStringFrom("1.5.0")}, "1.6.1"))
+	assert.False(t, canBeInstalledFrom(VersionSchemeSemver, &Package{Version: "2.0.0", MinVersion: dat.NullStringFrom("1.5.0")}, "1.4.9"))
+}
+
+func TestGetUpdatePackage_RetractedVersion(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg1, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.2.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg1.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 1, PolicyUpdateTimeout: "60 minutes"})
+
+	_, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.2.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrNoUpdatePackageAvailable, err, "Downgrades not allowed by default.")
+
+	_, err = a.RetractPackage(tPkg2.ID)
+	assert.NoError(t, err)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrMaxUpdatesPerPeriodLimitReached, err)
+
+	pkg, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.4", "12.2.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err, "Instances running a retracted version bypass the per-period limit.")
+	assert.Equal(t, tPkg1.ID, pkg.ID, "Instances running a retracted version are downgraded to the channel's package.")
+}
+
+func TestGetUpdatePackage_CriticalUpdate(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	now := time.Now().UTC()
+	tomorrow := int((now.Weekday() + 1) % 7)
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", Critical: true, ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyTimezone: dat.NullStringFrom("UTC"), PolicyUpdateWindows: []*UpdateWindow{{Weekdays: []int{tomorrow}, StartTime: "00:00", EndTime: "23:59"}}, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 1, PolicyUpdateTimeout: "60 minutes"})
+
+	_, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrUpdatesDisabled, err, "Critical updates policy not enabled in the group, updates are only allowed tomorrow.")
+
+	tGroup.PolicyCriticalMaxConcurrentUpdates = -1
+	err = a.UpdateGroup(tGroup)
+	assert.Equal(t, ErrInvalidUpdatesLimits, err)
+
+	tGroup.PolicyCriticalUpdates = true
+	tGroup.PolicyCriticalMaxConcurrentUpdates = 2
+	err = a.UpdateGroup(tGroup)
+	assert.NoError(t, err)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err, "Critical updates ignore the update windows.")
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.2", "12.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err, "Critical updates ignore safe mode and the updates per period limit.")
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.3", "12.0.0", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrMaxConcurrentUpdatesLimitReached, err, "Critical updates have their own concurrent updates limit.")
+
+	activityEntries, _ := a.GetActivity(tTeam.ID, ActivityQueryParams{GroupID: tGroup.ID, Version: "12.1.0"})
+	criticalEntries := 0
+	for _, entry := range activityEntries {
+		if entry.Class == activityCriticalUpdatePolicyApplied {
+			criticalEntries++
+		}
+	}
+	assert.Equal(t, 1, criticalEntries, "The critical updates policy override is recorded once.")
+}
+
+func TestGetUpdatePackage_ConcurrentRequests(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	maxUpdatesPerPeriod := 3
+	concurrentRequests := 30
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "12.1.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: maxUpdatesPerPeriod, PolicyUpdateTimeout: "60 minutes"})
+
+	var wg sync.WaitGroup
+	results := make(chan error, concurrentRequests)
+
+	for i := 0; i < concurrentRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "12.0.0", tApp.ID, tGroup.ID)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	granted, limitReached := 0, 0
+	for err := range results {
+		switch err {
+		case nil:
+			granted++
+		case ErrMaxUpdatesPerPeriodLimitReached:
+			limitReached++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	assert.Equal(t, maxUpdatesPerPeriod, granted, "The updates per period limit holds under concurrent requests.")
+	assert.Equal(t, concurrentRequests-maxUpdatesPerPeriod, limitReached)
+
+	group, _ := a.GetGroup(tGroup.ID)
+	updatesStats, err := a.getGroupUpdatesStats(a.dbR, group)
+	assert.NoError(t, err)
+	assert.Equal(t, maxUpdatesPerPeriod, updatesStats.UpdatesGrantedInLastPeriod)
+}
@@ -0,0 +1,422 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"gopkg.in/mgutz/dat.v1"
+	"gopkg.in/mgutz/dat.v1/sqlx-runner"
+)
+
+const (
+	// VersionSchemeSemver indicates that the versions of the application
+	// follow the semantic versioning spec (i.e. 1.2.3, 2.0.0-rc.1).
+	VersionSchemeSemver = "semver"
+
+	// VersionSchemeFourPart indicates that the versions of the application are
+	// made of four numeric parts (i.e. 1235.9.0.0, 10.0.19041.1).
+	VersionSchemeFourPart = "fourpart"
+
+	// VersionSchemeCalendar indicates that the versions of the application are
+	// release dates, optionally followed by a release number for that day
+	// (i.e. 2016.03.15, 2016.03.15.2).
+	VersionSchemeCalendar = "calendar"
+
+	// sortKeyNumberWidth is the width numbers are padded to in the versions
+	// sort keys, enough for any uint64.
+	sortKeyNumberWidth = 20
+
+	calendarVersionLayout = "2006.1.2"
+
+	// versionSortKeysLockID is the key of the advisory lock held while
+	// backfilling the versions sort keys, so that replicas starting at the
+	// same time don't do it concurrently.
+	versionSortKeysLockID = 0x76736b
+)
+
+var (
+	// ErrInvalidVersionScheme error indicates that the version scheme provided
+	// is not one of the supported ones.
+	ErrInvalidVersionScheme = errors.New("coreroller: invalid version scheme")
+
+	// ErrIncompatibleVersionScheme error indicates that the version scheme of
+	// the application can't be changed because some of its packages have
+	// versions that are not valid in the new scheme.
+	ErrIncompatibleVersionScheme = errors.New("coreroller: packages versions not valid in the version scheme")
+)
+
+// version represents a parsed version of any of the supported schemes, as a
+// list of numeric parts optionally followed by prerelease identifiers.
+type version struct {
+	numbers []uint64
+	pre     []semver.PRVersion
+}
+
+// isValidVersionScheme checks if the version scheme provided is supported.
+func isValidVersionScheme(scheme string) bool {
+	switch scheme {
+	case VersionSchemeSemver, VersionSchemeFourPart, VersionSchemeCalendar:
+		return true
+	}
+	return false
+}
+
+// parseVersion parses the version provided using the given version scheme.
+func parseVersion(scheme, v string) (*version, error) {
+	switch scheme {
+	case VersionSchemeFourPart:
+		return parseNumericVersion(v, 4)
+
+	case VersionSchemeCalendar:
+		parts := strings.SplitN(v, ".", 4)
+		if len(parts) < 3 {
+			return nil, ErrInvalidSemver
+		}
+		date, err := time.Parse(calendarVersionLayout, strings.Join(parts[:3], "."))
+		if err != nil {
+			return nil, ErrInvalidSemver
+		}
+		var release uint64
+		if len(parts) == 4 {
+			if release, err = parseVersionNumber(parts[3]); err != nil {
+				return nil, err
+			}
+		}
+		return &version{numbers: []uint64{uint64(date.Year()), uint64(date.Month()), uint64(date.Day()), release}}, nil
+
+	default:
+		sv, err := semver.Make(v)
+		if err != nil {
+			return nil, ErrInvalidSemver
+		}
+		return &version{numbers: []uint64{sv.Major, sv.Minor, sv.Patch}, pre: sv.Pre}, nil
+	}
+}
+
+// parseNumericVersion parses a version made of the given number of numeric
+// parts separated by dots.
+func parseNumericVersion(v string, size int) (*version, error) {
+	parts := strings.Split(v, ".")
+	if len(parts) != size {
+		return nil, ErrInvalidSemver
+	}
+
+	numbers := make([]uint64, size)
+	for i, part := range parts {
+		n, err := parseVersionNumber(part)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = n
+	}
+
+	return &version{numbers: numbers}, nil
+}
+
+// parseVersionNumber parses a numeric part of a version, which can't have
+// leading zeros.
+func parseVersionNumber(part string) (uint64, error) {
+	if len(part) > 1 && part[0] == '0' {
+		return 0, ErrInvalidSemver
+	}
+	n, err := strconv.ParseUint(part, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSemver
+	}
+	return n, nil
+}
+
+// compare returns 0 if both versions are equal, -1 if v is lower than o and 1
+// if v is greater than o. Versions with prerelease identifiers are lower than
+// the same version without them.
+func (v *version) compare(o *version) int {
+	for i := 0; i < len(v.numbers) || i < len(o.numbers); i++ {
+		var a, b uint64
+		if i < len(v.numbers) {
+			a = v.numbers[i]
+		}
+		if i < len(o.numbers) {
+			b = o.numbers[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		if c := v.pre[i].Compare(o.pre[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(v.pre) < len(o.pre):
+		return -1
+	case len(v.pre) > len(o.pre):
+		return 1
+	}
+	return 0
+}
+
+// sortKey returns a key for the version that, compared bytewise, sorts
+// versions of the same scheme the same way compare does. Prerelease numeric
+// identifiers sort numerically and before alphanumeric ones and a larger set
+// of prerelease identifiers sorts after a smaller one when all the preceding
+// ones are equal.
+func (v *version) sortKey() string {
+	numbers := make([]string, len(v.numbers))
+	for i, n := range v.numbers {
+		numbers[i] = fmt.Sprintf("%0*d", sortKeyNumberWidth, n)
+	}
+	key := strings.Join(numbers, ".")
+
+	if len(v.pre) == 0 {
+		return key + "~"
+	}
+
+	parts := make([]string, len(v.pre))
+	for i, pre := range v.pre {
+		if pre.IsNum {
+			parts[i] = fmt.Sprintf("0%0*d", sortKeyNumberWidth, pre.VersionNum)
+		} else {
+			parts[i] = "1" + pre.VersionStr
+		}
+	}
+
+	// The separator must sort before any character allowed in the prerelease
+	// identifiers, so that shorter identifiers sort first.
+	return key + "-" + strings.Join(parts, " ")
+}
+
+// isValidVersion checks if the version provided is valid in the given version
+// scheme.
+func isValidVersion(scheme, v string) bool {
+	_, err := parseVersion(scheme, v)
+	return err == nil
+}
+
+// compareVersions compares the versions provided using the given version
+// scheme (see version.compare). Invalid versions are considered the lowest
+// possible version.
+func compareVersions(scheme, a, b string) int {
+	va, err := parseVersion(scheme, a)
+	if err != nil {
+		va = &version{}
+	}
+	vb, err := parseVersion(scheme, b)
+	if err != nil {
+		vb = &version{}
+	}
+
+	return va.compare(vb)
+}
+
+// versionSortKey returns a key for the version provided that, compared
+// bytewise, sorts versions of the given scheme the same way compareVersions
+// does (build metadata is ignored). Invalid versions get an empty key.
+func versionSortKey(scheme, v string) string {
+	parsed, err := parseVersion(scheme, v)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.sortKey()
+}
+
+// minVersionSortKey returns the sort key of the minimum version provided, which
+// is unset when the package doesn't have a minimum version.
+func minVersionSortKey(scheme string, minVersion dat.NullString) dat.NullString {
+	if !minVersion.Valid {
+		return dat.NullString{}
+	}
+
+	return dat.NullStringFrom(versionSortKey(scheme, minVersion.String))
+}
+
+// getVersionScheme returns the version scheme of the application identified
+// by the id provided.
+func (api *API) getVersionScheme(appID string) (string, error) {
+	var scheme string
+
+	err := api.dbR.
+		Select("version_scheme").
+		From("application").
+		Where("id = $1", appID).
+		QueryScalar(&scheme)
+
+	return scheme, err
+}
+
+// backfillVersionSortKeys sets the sort key of the versions (and minimum
+// versions) of packages and instances that don't have one yet (rows created
+// before sort keys existed).
+// It runs on every startup, but it only does some work the first time, as
+// checking if there are rows left to backfill is cheap.
+func (api *API) backfillVersionSortKeys() error {
+	var pending bool
+
+	err := api.dbR.
+		SQL(`SELECT EXISTS (SELECT 1 FROM package WHERE version_sort_key IS NULL) OR
+			EXISTS (SELECT 1 FROM package WHERE min_version IS NOT NULL AND min_version_sort_key IS NULL) OR
+			EXISTS (SELECT 1 FROM instance_application WHERE version_sort_key IS NULL)`).
+		QueryScalar(&pending)
+
+	if err != nil || !pending {
+		return err
+	}
+
+	tx, err := api.dbR.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.AutoRollback()
+	}()
+
+	if _, err := tx.SQL("SELECT pg_advisory_xact_lock($1)", versionSortKeysLockID).Exec(); err != nil {
+		return err
+	}
+
+	var pkgs []*struct {
+		ID            string         `db:"id"`
+		Version       string         `db:"version"`
+		MinVersion    dat.NullString `db:"min_version"`
+		VersionScheme string         `db:"version_scheme"`
+	}
+
+	err = tx.
+		Select("package.id", "package.version", "package.min_version", "application.version_scheme").
+		From("package INNER JOIN application ON (package.application_id = application.id)").
+		Where("package.version_sort_key IS NULL OR (package.min_version IS NOT NULL AND package.min_version_sort_key IS NULL)").
+		QueryStructs(&pkgs)
+
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		_, err := tx.
+			Update("package").
+			Set("version_sort_key", versionSortKey(pkg.VersionScheme, pkg.Version)).
+			Set("min_version_sort_key", minVersionSortKey(pkg.VersionScheme, pkg.MinVersion)).
+			Where("id = $1", pkg.ID).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	var versions []*struct {
+		ApplicationID string `db:"application_id"`
+		Version       string `db:"version"`
+		VersionScheme string `db:"version_scheme"`
+	}
+
+	err = tx.
+		Select("DISTINCT instance_application.application_id", "instance_application.version", "application.version_scheme").
+		From("instance_application INNER JOIN application ON (instance_application.application_id = application.id)").
+		Where("instance_application.version_sort_key IS NULL").
+		QueryStructs(&versions)
+
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		_, err := tx.
+			Update("instance_application").
+			Set("version_sort_key", versionSortKey(v.VersionScheme, v.Version)).
+			Where("application_id = $1 AND version = $2 AND version_sort_key IS NULL", v.ApplicationID, v.Version).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// updateVersionSortKeys recomputes the sort keys of the versions of the
+// packages and instances of the application provided using the given version
+// scheme, failing if any of its packages has a version that is not valid in
+// that scheme.
+//
+// This method is part of the transaction that updates an application.
+func (api *API) updateVersionSortKeys(tx *runner.Tx, appID, scheme string) error {
+	var pkgs []*struct {
+		ID         string         `db:"id"`
+		Version    string         `db:"version"`
+		MinVersion dat.NullString `db:"min_version"`
+	}
+
+	err := tx.
+		Select("id", "version", "min_version").
+		From("package").
+		Where("application_id = $1", appID).
+		QueryStructs(&pkgs)
+
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		if !isValidVersion(scheme, pkg.Version) || (pkg.MinVersion.Valid && !isValidVersion(scheme, pkg.MinVersion.String)) {
+			return ErrIncompatibleVersionScheme
+		}
+
+		_, err := tx.
+			Update("package").
+			Set("version_sort_key", versionSortKey(scheme, pkg.Version)).
+			Set("min_version_sort_key", minVersionSortKey(scheme, pkg.MinVersion)).
+			Where("id = $1", pkg.ID).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	var versions []string
+
+	err = tx.
+		Select("DISTINCT version").
+		From("instance_application").
+		Where("application_id = $1", appID).
+		QuerySlice(&versions)
+
+	if err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		_, err := tx.
+			Update("instance_application").
+			Set("version_sort_key", versionSortKey(scheme, v)).
+			Where("application_id = $1 AND version = $2", appID, v).
+			Exec()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+func TestVersionSortKey(t *testing.T) {
+	versions := []string{
+		"2.0.0",
+		"1.0.0-alpha",
+		"1.0.0",
+		"1.0.0-beta.11",
+		"1.0.0-alpha.beta",
+		"1.0.0-rc.1",
+		"10.0.0",
+		"1.0.0-alpha.1",
+		"1.0.0-beta.2",
+		"1.0.0-beta",
+		"1.0.0-alpha-x",
+		"2.0.0-rc.1",
+		"1.10.0",
+		"1.9.0",
+		"1.0.0-1",
+		"1.0.0-2.a",
+	}
+
+	byKey := append([]string(nil), versions...)
+	sort.SliceStable(byKey, func(i, j int) bool {
+		return versionSortKey(VersionSchemeSemver, byKey[i]) < versionSortKey(VersionSchemeSemver, byKey[j])
+	})
+
+	bySemver := append([]string(nil), versions...)
+	sort.SliceStable(bySemver, func(i, j int) bool { return semver.MustParse(bySemver[i]).LT(semver.MustParse(bySemver[j])) })
+
+	assert.Equal(t, bySemver, byKey)
+
+	assert.Equal(t, versionSortKey(VersionSchemeSemver, "1.0.0+build.1"), versionSortKey(VersionSchemeSemver, "1.0.0+build.2"), "Build metadata is ignored.")
+	assert.Equal(t, "", versionSortKey(VersionSchemeSemver, "1.0"))
+
+	assert.True(t, versionSortKey(VersionSchemeFourPart, "1235.9.0.0") < versionSortKey(VersionSchemeFourPart, "1235.10.0.0"))
+	assert.True(t, versionSortKey(VersionSchemeCalendar, "2016.03.15") < versionSortKey(VersionSchemeCalendar, "2016.03.15.1"))
+	assert.True(t, versionSortKey(VersionSchemeCalendar, "2016.9.30") < versionSortKey(VersionSchemeCalendar, "2016.10.01"))
+}
+
+func TestParseVersion(t *testing.T) {
+	assert.True(t, isValidVersion(VersionSchemeSemver, "1.2.3-rc.1"))
+	assert.False(t, isValidVersion(VersionSchemeSemver, "1235.9.0.0"))
+
+	assert.True(t, isValidVersion(VersionSchemeFourPart, "1235.9.0.0"))
+	assert.True(t, isValidVersion(VersionSchemeFourPart, "10.0.19041.1"))
+	assert.False(t, isValidVersion(VersionSchemeFourPart, "1.2.3"))
+	assert.False(t, isValidVersion(VersionSchemeFourPart, "1.2.3.4.5"))
+	assert.False(t, isValidVersion(VersionSchemeFourPart, "1.02.3.4"))
+	assert.False(t, isValidVersion(VersionSchemeFourPart, "1.2.3.a"))
+
+	assert.True(t, isValidVersion(VersionSchemeCalendar, "2016.03.15"))
+	assert.True(t, isValidVersion(VersionSchemeCalendar, "2016.3.15.2"))
+	assert.False(t, isValidVersion(VersionSchemeCalendar, "2016.13.01"))
+	assert.False(t, isValidVersion(VersionSchemeCalendar, "2016.02.30"))
+	assert.False(t, isValidVersion(VersionSchemeCalendar, "2016.03"))
+	assert.False(t, isValidVersion(VersionSchemeCalendar, "2016.03.15.rc1"))
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, -1, compareVersions(VersionSchemeSemver, "2.0.0-rc.1", "2.0.0"))
+	assert.Equal(t, 1, compareVersions(VersionSchemeSemver, "1.10.0", "1.9.0"))
+	assert.Equal(t, 0, compareVersions(VersionSchemeSemver, "1.0.0+a", "1.0.0+b"))
+
+	assert.Equal(t, -1, compareVersions(VersionSchemeFourPart, "1235.9.0.0", "1235.9.0.1"))
+	assert.Equal(t, 1, compareVersions(VersionSchemeFourPart, "1235.10.0.0", "1235.9.0.9"))
+
+	assert.Equal(t, 0, compareVersions(VersionSchemeCalendar, "2016.03.15", "2016.3.15.0"))
+	assert.Equal(t, -1, compareVersions(VersionSchemeCalendar, "2016.03.15", "2016.03.15.1"))
+	assert.Equal(t, 1, compareVersions(VersionSchemeCalendar, "2017.01.01", "2016.12.31.9"))
+}
+
+func TestApplicationVersionScheme(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+
+	_, err := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID, VersionScheme: "invalid"})
+	assert.Equal(t, ErrInvalidVersionScheme, err)
+
+	tApp, err := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID, VersionScheme: VersionSchemeFourPart})
+	assert.NoError(t, err)
+	assert.Equal(t, VersionSchemeFourPart, tApp.VersionScheme)
+
+	_, err = a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "1.2.3", ApplicationID: tApp.ID})
+	assert.Equal(t, ErrInvalidSemver, err, "Package version must be valid in the application's version scheme.")
+
+	tPkg1, err := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "1235.9.0.0", ApplicationID: tApp.ID})
+	assert.NoError(t, err)
+	tPkg2, _ := a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: "1235.10.0.0", MinVersion: dat.NullStringFrom("1235.9.0.0"), ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg2.ID)})
+	tGroup, _ := a.AddGroup(&Group{Name: "group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: false, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+
+	pkgs, _ := a.GetPackages(tApp.ID, 0, 0)
+	assert.Equal(t, tPkg2.ID, pkgs[0].ID, "Packages are sorted using the application's version scheme.")
+	assert.Equal(t, tPkg1.ID, pkgs[1].ID)
+
+	_, err = a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "1.2.3", tApp.ID, tGroup.ID)
+	assert.Equal(t, ErrRegisterInstanceFailed, err, "Instance version must be valid in the application's version scheme.")
+
+	pkg, err := a.GetUpdatePackage(uuid.NewV4().String(), "10.0.0.1", "1235.9.0.0", tApp.ID, tGroup.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tPkg2.ID, pkg.ID)
+
+	tApp.VersionScheme = VersionSchemeCalendar
+	err = a.UpdateApp(tApp)
+	assert.Equal(t, ErrIncompatibleVersionScheme, err, "Packages versions must be valid in the new version scheme.")
+
+	tApp.VersionScheme = ""
+	err = a.UpdateApp(tApp)
+	assert.NoError(t, err)
+	app, _ := a.GetApp(tApp.ID)
+	assert.Equal(t, VersionSchemeFourPart, app.VersionScheme, "An empty version scheme keeps the current one.")
+}
+
+func TestGetPackagesVersionOrder(t *testing.T) {
+	a, _ := New(OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&Application{Name: "test_app", TeamID: tTeam.ID})
+	for _, version := range []string{"2.0.0-rc.1", "1.10.0", "2.0.0", "1.9.0", "2.0.0-rc.2"} {
+		_, _ = a.AddPackage(&Package{Type: PkgTypeOther, URL: "http://sample.url/pkg", Version: version, ApplicationID: tApp.ID})
+	}
+
+	pkgs, err := a.GetPackages(tApp.ID, 0, 0)
+	assert.NoError(t, err)
+
+	versions := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		versions[i] = pkg.Version
+	}
+	assert.Equal(t, []string{"2.0.0", "2.0.0-rc.2", "2.0.0-rc.1", "1.10.0", "1.9.0"}, versions)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
 	"strconv"
@@ -10,59 +11,230 @@ import (
 	"time"
 
 	"api"
+	"importer"
 	"omaha"
 	"syncer"
 
 	"github.com/zenazn/goji/web"
 )
 
+const (
+	leaderElectionFrequency        = 10 * time.Second
+	promotionsCheckFrequency       = 5 * time.Minute
+	scheduledChangesCheckFrequency = 1 * time.Minute
+	timedOutUpdatesCheckFrequency  = 1 * time.Minute
+)
+
+// errNotLeader error indicates that a task run by the leader was skipped as
+// the replica isn't the leader anymore.
+var errNotLeader = errors.New("not the leader anymore")
+
 type controller struct {
-	api          *api.API
-	omahaHandler *omaha.Handler
-	syncer       *syncer.Syncer
+	api            *api.API
+	omahaHandler   *omaha.Handler
+	conf           *controllerConfig
+	leaderElector  *api.LeaderElector
+	leaderElection *periodicTask
+	leading        bool
+	syncer         *syncer.Syncer
+	importer       *importer.Importer
+	tasks          []*periodicTask
 }
 
 type controllerConfig struct {
+	replicaID          string
+	enableGroupsCache  bool
 	enableSyncer       bool
 	hostCoreosPackages bool
 	coreosPackagesPath string
 	corerollerURL      string
+	syncerArches       []string
+	syncerSources      []*syncer.Source
+	importerPath       string
 }
 
 func newController(conf *controllerConfig) (*controller, error) {
-	api, err := api.New()
+	api, err := newAPI(conf.enableGroupsCache)
 	if err != nil {
 		return nil, err
 	}
 
 	c := &controller{
-		api:          api,
-		omahaHandler: omaha.NewHandler(api),
+		api:           api,
+		omahaHandler:  omaha.NewHandler(api),
+		conf:          conf,
+		leaderElector: api.NewLeaderElector(conf.replicaID),
+	}
+
+	// The syncer and the background tasks run only in the replica elected as
+	// leader, another replica takes over when it dies.
+	_ = c.electLeader()
+	c.leaderElection = newPeriodicTask("leader_election", leaderElectionFrequency, c.electLeader)
+	go c.leaderElection.start()
+
+	return c, nil
+}
+
+// newAPI creates the API instance used by the controller. When the groups
+// cache is enabled but the database changes notifications can't be listened
+// to (i.e. behind a connection pooler in transaction mode), the cache is
+// disabled.
+func newAPI(enableGroupsCache bool) (*api.API, error) {
+	if enableGroupsCache {
+		a, err := api.New(api.OptionCache)
+		if err == nil {
+			return a, nil
+		}
+		logger.Warn("newAPI - groups cache disabled, listening for database changes failed", "error", err.Error())
+	}
+
+	return api.New()
+}
+
+func (ctl *controller) close() {
+	ctl.leaderElection.stop()
+	ctl.stopLeaderTasks()
+	_ = ctl.leaderElector.Resign()
+	ctl.api.Close()
+}
+
+// ----------------------------------------------------------------------------
+// Leader election
+//
+
+// electLeader tries to make this replica the leader, starting the tasks run
+// by the leader when it becomes the leader and stopping them when it loses
+// the leadership. Meanwhile, the tasks confirm that the replica is still the
+// leader before making any changes, as the leadership may be lost at any time.
+func (ctl *controller) electLeader() error {
+	isLeader, err := ctl.leaderElector.Elect()
+
+	switch {
+	case isLeader && !ctl.leading:
+		logger.Info("electLeader - leadership acquired", "replica", ctl.conf.replicaID)
+		ctl.startLeaderTasks()
+	case !isLeader && ctl.leading:
+		logger.Warn("electLeader - leadership lost", "replica", ctl.conf.replicaID)
+		ctl.stopLeaderTasks()
+	}
+	ctl.leading = isLeader
+
+	return err
+}
+
+// leaderOnly wraps the leader task provided so that it only runs while the
+// replica is still the leader.
+func (ctl *controller) leaderOnly(run func() error) func() error {
+	return func() error {
+		if !ctl.leaderElector.IsLeader() {
+			return errNotLeader
+		}
+
+		return run()
 	}
+}
 
-	if conf.enableSyncer {
+// startLeaderTasks starts the syncer and the packages importer (when enabled)
+// and the background tasks run by the leader.
+func (ctl *controller) startLeaderTasks() {
+	if ctl.conf.enableSyncer {
 		syncerConf := &syncer.Config{
-			Api:          api,
-			HostPackages: conf.hostCoreosPackages,
-			PackagesPath: conf.coreosPackagesPath,
-			PackagesURL:  conf.corerollerURL + coreosPkgsRouterPrefix,
+			Api:          ctl.api,
+			HostPackages: ctl.conf.hostCoreosPackages,
+			PackagesPath: ctl.conf.coreosPackagesPath,
+			PackagesURL:  ctl.conf.corerollerURL + coreosPkgsRouterPrefix,
+			Arches:       ctl.conf.syncerArches,
+			Sources:      ctl.conf.syncerSources,
+			IsLeader:     ctl.leaderElector.IsLeader,
 		}
 		syncer, err := syncer.New(syncerConf)
 		if err != nil {
-			return nil, err
+			logger.Error("startLeaderTasks - creating syncer", "error", err.Error())
+		} else {
+			ctl.syncer = syncer
+			go syncer.Start()
 		}
-		c.syncer = syncer
-		go syncer.Start()
 	}
 
-	return c, nil
+	if ctl.conf.importerPath != "" {
+		importerConf := &importer.Config{
+			Api:          ctl.api,
+			Path:         ctl.conf.importerPath,
+			PackagesPath: ctl.conf.coreosPackagesPath,
+			PackagesURL:  ctl.conf.corerollerURL + coreosPkgsRouterPrefix,
+			IsLeader:     ctl.leaderElector.IsLeader,
+		}
+		importer, err := importer.New(importerConf)
+		if err != nil {
+			logger.Error("startLeaderTasks - creating importer", "error", err.Error())
+		} else {
+			ctl.importer = importer
+			go importer.Start()
+		}
+	}
+
+	ctl.tasks = append(ctl.tasks, newPeriodicTask("promotions", promotionsCheckFrequency, ctl.leaderOnly(ctl.promoteChannelsPackages)))
+	ctl.tasks = append(ctl.tasks, newPeriodicTask("scheduled_changes", scheduledChangesCheckFrequency, ctl.leaderOnly(ctl.applyScheduledChanges)))
+	ctl.tasks = append(ctl.tasks, newPeriodicTask("timed_out_updates", timedOutUpdatesCheckFrequency, ctl.leaderOnly(ctl.reapTimedOutUpdates)))
+	for _, task := range ctl.tasks {
+		go task.start()
+	}
 }
 
-func (ctl *controller) close() {
+// stopLeaderTasks stops the syncer, the packages importer and the background
+// tasks run by the leader, if they are running.
+func (ctl *controller) stopLeaderTasks() {
+	for _, task := range ctl.tasks {
+		task.stop()
+	}
+	ctl.tasks = nil
+
 	if ctl.syncer != nil {
 		ctl.syncer.Stop()
+		ctl.syncer = nil
+	}
+
+	if ctl.importer != nil {
+		ctl.importer.Stop()
+		ctl.importer = nil
 	}
-	ctl.api.Close()
+}
+
+// ----------------------------------------------------------------------------
+// Background tasks
+//
+
+// promoteChannelsPackages evaluates the channels promotion rules, promoting
+// packages between channels as needed.
+func (ctl *controller) promoteChannelsPackages() error {
+	channels, err := ctl.api.PromoteChannelsPackages()
+	for _, channel := range channels {
+		logger.Info("promoteChannelsPackages - channel package promoted", "channel", channel.ID, "package", channel.PackageID.String)
+	}
+
+	return err
+}
+
+// applyScheduledChanges applies the channels scheduled changes whose time has
+// come.
+func (ctl *controller) applyScheduledChanges() error {
+	changes, err := ctl.api.ApplyScheduledChanges()
+	for _, change := range changes {
+		logger.Info("applyScheduledChanges - scheduled change processed", "change", change.ID, "channel", change.ChannelID, "package", change.PackageID, "status", change.Status)
+	}
+
+	return err
+}
+
+// reapTimedOutUpdates sets the status of the instances whose updates timed out
+// to timed out, allowing them to request the update again.
+func (ctl *controller) reapTimedOutUpdates() error {
+	reaped, err := ctl.api.ReapTimedOutUpdates()
+	if reaped > 0 {
+		logger.Info("reapTimedOutUpdates - timed out updates reaped", "count", reaped)
+	}
+
+	return err
 }
 
 // ----------------------------------------------------------------------------
@@ -458,6 +630,71 @@ func (ctl *controller) getChannels(c web.C, w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// ----------------------------------------------------------------------------
+// API: channels scheduled changes
+//
+
+func (ctl *controller) addScheduledChange(c web.C, w http.ResponseWriter, r *http.Request) {
+	change := &api.ScheduledChange{}
+	if err := json.NewDecoder(r.Body).Decode(change); err != nil {
+		logger.Error("addScheduledChange", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	change.ChannelID = c.URLParams["channel_id"]
+	change.CreatedBy, _ = c.Env["username"].(string)
+
+	_, err := ctl.api.AddScheduledChange(change)
+	if err != nil {
+		logger.Error("addScheduledChange", "error", err.Error(), "change", change)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	addedChange, err := ctl.api.GetScheduledChange(change.ID)
+	if err != nil {
+		logger.Error("addScheduledChange", "error", err.Error(), "changeID", change.ID)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(addedChange); err != nil {
+		logger.Error("addScheduledChange - encoding scheduled change", "error", err.Error(), "changeID", change.ID)
+	}
+}
+
+func (ctl *controller) cancelScheduledChange(c web.C, w http.ResponseWriter, r *http.Request) {
+	channelID := c.URLParams["channel_id"]
+	changeID := c.URLParams["change_id"]
+
+	err := ctl.api.CancelScheduledChange(channelID, changeID)
+	switch err {
+	case nil:
+		http.Error(w, http.StatusText(http.StatusNoContent), http.StatusNoContent)
+	default:
+		logger.Error("cancelScheduledChange", "error", err.Error(), "channelID", channelID, "changeID", changeID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
+func (ctl *controller) getScheduledChanges(c web.C, w http.ResponseWriter, r *http.Request) {
+	channelID := c.URLParams["channel_id"]
+	page, _ := strconv.ParseUint(r.URL.Query().Get("page"), 10, 64)
+	perPage, _ := strconv.ParseUint(r.URL.Query().Get("perpage"), 10, 64)
+
+	changes, err := ctl.api.GetScheduledChanges(channelID, page, perPage)
+	switch err {
+	case nil:
+		if err := json.NewEncoder(w).Encode(changes); err != nil {
+			logger.Error("getScheduledChanges - encoding scheduled changes", "error", err.Error(), "channelID", channelID)
+		}
+	case sql.ErrNoRows:
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		logger.Error("getScheduledChanges - getting scheduled changes", "error", err.Error(), "channelID", channelID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
 // ----------------------------------------------------------------------------
 // API: packages CRUD
 //
@@ -547,6 +784,33 @@ func (ctl *controller) getPackage(c web.C, w http.ResponseWriter, r *http.Reques
 	}
 }
 
+func (ctl *controller) retractPackage(c web.C, w http.ResponseWriter, r *http.Request) {
+	packageID := c.URLParams["package_id"]
+
+	instancesCount, err := ctl.api.RetractPackage(packageID)
+	switch err {
+	case nil:
+		pkg, err := ctl.api.GetPackage(packageID)
+		if err != nil {
+			logger.Error("retractPackage - getting retracted package", "error", err.Error(), "packageID", packageID)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		retraction := struct {
+			Package        *api.Package `json:"package"`
+			InstancesCount int          `json:"instances_count"`
+		}{pkg, instancesCount}
+		if err := json.NewEncoder(w).Encode(retraction); err != nil {
+			logger.Error("retractPackage - encoding package retraction", "error", err.Error(), "packageID", packageID)
+		}
+	case sql.ErrNoRows:
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		logger.Error("retractPackage", "error", err.Error(), "packageID", packageID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
 func (ctl *controller) getPackages(c web.C, w http.ResponseWriter, r *http.Request) {
 	appID := c.URLParams["app_id"]
 	page, _ := strconv.ParseUint(r.URL.Query().Get("page"), 10, 64)
@@ -566,6 +830,170 @@ func (ctl *controller) getPackages(c web.C, w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// ----------------------------------------------------------------------------
+// API: packages delta payloads
+//
+
+func (ctl *controller) addPackageDelta(c web.C, w http.ResponseWriter, r *http.Request) {
+	delta := &api.PackageDelta{}
+	if err := json.NewDecoder(r.Body).Decode(delta); err != nil {
+		logger.Error("addPackageDelta - decoding payload", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	delta.PackageID = c.URLParams["package_id"]
+
+	_, err := ctl.api.AddPackageDelta(delta)
+	if err != nil {
+		logger.Error("addPackageDelta - adding package delta", "error", err.Error(), "delta", delta)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(delta); err != nil {
+		logger.Error("addPackageDelta - encoding package delta", "error", err.Error(), "deltaID", delta.ID)
+	}
+}
+
+func (ctl *controller) updatePackageDelta(c web.C, w http.ResponseWriter, r *http.Request) {
+	delta := &api.PackageDelta{}
+	if err := json.NewDecoder(r.Body).Decode(delta); err != nil {
+		logger.Error("updatePackageDelta - decoding payload", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	delta.ID = c.URLParams["delta_id"]
+
+	err := ctl.api.UpdatePackageDelta(delta)
+	if err != nil {
+		logger.Error("updatePackageDelta - updating package delta", "error", err.Error(), "delta", delta)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	delta, err = ctl.api.GetPackageDelta(delta.ID)
+	if err != nil {
+		logger.Error("updatePackageDelta - getting updated package delta", "error", err.Error(), "deltaID", c.URLParams["delta_id"])
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(delta); err != nil {
+		logger.Error("updatePackageDelta - encoding package delta", "error", err.Error(), "deltaID", delta.ID)
+	}
+}
+
+func (ctl *controller) deletePackageDelta(c web.C, w http.ResponseWriter, r *http.Request) {
+	deltaID := c.URLParams["delta_id"]
+
+	err := ctl.api.DeletePackageDelta(deltaID)
+	switch err {
+	case nil:
+		http.Error(w, http.StatusText(http.StatusNoContent), http.StatusNoContent)
+	default:
+		logger.Error("deletePackageDelta", "error", err.Error(), "deltaID", deltaID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
+func (ctl *controller) getPackageDeltas(c web.C, w http.ResponseWriter, r *http.Request) {
+	packageID := c.URLParams["package_id"]
+
+	deltas, err := ctl.api.GetPackageDeltas(packageID)
+	switch err {
+	case nil:
+		if err := json.NewEncoder(w).Encode(deltas); err != nil {
+			logger.Error("getPackageDeltas - encoding package deltas", "error", err.Error(), "packageID", packageID)
+		}
+	case sql.ErrNoRows:
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		logger.Error("getPackageDeltas - getting package deltas", "error", err.Error(), "packageID", packageID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// API: packages architecture payloads
+//
+
+func (ctl *controller) addPackageArch(c web.C, w http.ResponseWriter, r *http.Request) {
+	pkgArch := &api.PackageArch{}
+	if err := json.NewDecoder(r.Body).Decode(pkgArch); err != nil {
+		logger.Error("addPackageArch - decoding payload", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	pkgArch.PackageID = c.URLParams["package_id"]
+
+	_, err := ctl.api.AddPackageArch(pkgArch)
+	if err != nil {
+		logger.Error("addPackageArch - adding package arch", "error", err.Error(), "pkgArch", pkgArch)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(pkgArch); err != nil {
+		logger.Error("addPackageArch - encoding package arch", "error", err.Error(), "pkgArchID", pkgArch.ID)
+	}
+}
+
+func (ctl *controller) updatePackageArch(c web.C, w http.ResponseWriter, r *http.Request) {
+	pkgArch := &api.PackageArch{}
+	if err := json.NewDecoder(r.Body).Decode(pkgArch); err != nil {
+		logger.Error("updatePackageArch - decoding payload", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	pkgArch.ID = c.URLParams["arch_id"]
+
+	err := ctl.api.UpdatePackageArch(pkgArch)
+	if err != nil {
+		logger.Error("updatePackageArch - updating package arch", "error", err.Error(), "pkgArch", pkgArch)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	pkgArch, err = ctl.api.GetPackageArch(pkgArch.ID)
+	if err != nil {
+		logger.Error("updatePackageArch - getting updated package arch", "error", err.Error(), "pkgArchID", c.URLParams["arch_id"])
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(pkgArch); err != nil {
+		logger.Error("updatePackageArch - encoding package arch", "error", err.Error(), "pkgArchID", pkgArch.ID)
+	}
+}
+
+func (ctl *controller) deletePackageArch(c web.C, w http.ResponseWriter, r *http.Request) {
+	pkgArchID := c.URLParams["arch_id"]
+
+	err := ctl.api.DeletePackageArch(pkgArchID)
+	switch err {
+	case nil:
+		http.Error(w, http.StatusText(http.StatusNoContent), http.StatusNoContent)
+	default:
+		logger.Error("deletePackageArch", "error", err.Error(), "pkgArchID", pkgArchID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
+func (ctl *controller) getPackageArches(c web.C, w http.ResponseWriter, r *http.Request) {
+	packageID := c.URLParams["package_id"]
+
+	pkgArches, err := ctl.api.GetPackageArches(packageID)
+	switch err {
+	case nil:
+		if err := json.NewEncoder(w).Encode(pkgArches); err != nil {
+			logger.Error("getPackageArches - encoding package arches", "error", err.Error(), "packageID", packageID)
+		}
+	case sql.ErrNoRows:
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		logger.Error("getPackageArches - getting package arches", "error", err.Error(), "packageID", packageID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
 // ----------------------------------------------------------------------------
 // API: instances
 //
@@ -590,6 +1018,26 @@ func (ctl *controller) getInstanceStatusHistory(c web.C, w http.ResponseWriter,
 	}
 }
 
+func (ctl *controller) explainUpdateDecision(c web.C, w http.ResponseWriter, r *http.Request) {
+	appID := c.URLParams["app_id"]
+	groupID := c.URLParams["group_id"]
+	instanceID := c.URLParams["instance_id"]
+	arch := r.URL.Query().Get("arch")
+
+	decision, err := ctl.api.ExplainUpdateDecision(instanceID, appID, groupID, arch)
+	switch err {
+	case nil:
+		if err := json.NewEncoder(w).Encode(decision); err != nil {
+			logger.Error("explainUpdateDecision - encoding decision", "error", err.Error(), "appID", appID, "groupID", groupID, "instanceID", instanceID)
+		}
+	case sql.ErrNoRows:
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	default:
+		logger.Error("explainUpdateDecision - explaining decision", "error", err.Error(), "appID", appID, "groupID", groupID, "instanceID", instanceID)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+}
+
 func (ctl *controller) getInstances(c web.C, w http.ResponseWriter, r *http.Request) {
 	appID := c.URLParams["app_id"]
 	groupID := c.URLParams["group_id"]
@@ -603,6 +1051,16 @@ func (ctl *controller) getInstances(c web.C, w http.ResponseWriter, r *http.Requ
 	p.Page, _ = strconv.ParseUint(r.URL.Query().Get("page"), 10, 64)
 	p.PerPage, _ = strconv.ParseUint(r.URL.Query().Get("perpage"), 10, 64)
 
+	// Label filters are provided as label.<key>=<value> query parameters.
+	for param, values := range r.URL.Query() {
+		if key := strings.TrimPrefix(param, "label."); key != param && key != "" {
+			if p.Labels == nil {
+				p.Labels = make(map[string]string)
+			}
+			p.Labels[key] = values[0]
+		}
+	}
+
 	instances, err := ctl.api.GetInstances(p)
 	switch err {
 	case nil:
@@ -651,6 +1109,36 @@ func (ctl *controller) getActivity(c web.C, w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// ----------------------------------------------------------------------------
+// API: status
+//
+
+func (ctl *controller) getStatus(c web.C, w http.ResponseWriter, r *http.Request) {
+	status := struct {
+		ReplicaID string      `json:"replica_id"`
+		IsLeader  bool        `json:"is_leader"`
+		Leader    *api.Leader `json:"leader"`
+	}{
+		ReplicaID: ctl.conf.replicaID,
+		IsLeader:  ctl.leaderElector.IsLeader(),
+	}
+
+	leader, err := ctl.api.GetLeader()
+	switch err {
+	case nil:
+		status.Leader = leader
+	case sql.ErrNoRows:
+	default:
+		logger.Error("getStatus - getting leader", "error", err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logger.Error("getStatus - encoding status", "error", err.Error())
+	}
+}
+
 // ----------------------------------------------------------------------------
 // OMAHA server
 //
@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+
+	"syncer"
 
 	"github.com/mgutz/logxi/v1"
 	"github.com/zenazn/goji"
@@ -23,6 +26,11 @@ var (
 	hostCoreosPackages = flag.Bool("host-coreos-packages", false, "Host CoreOS packages in CoreRoller")
 	coreosPackagesPath = flag.String("coreos-packages-path", "", "Path where CoreOS packages files are stored")
 	corerollerURL      = flag.String("coreroller-url", "", "CoreRoller URL (http://host:port - required when hosting CoreOS packages in CoreRoller)")
+	syncerArches       = flag.String("syncer-arches", "", "Comma separated list of CoreOS architectures tracked by the syncer (amd64, arm64), the first one is used to update the channels (defaults to amd64, creating packages without architecture)")
+	importerPath       = flag.String("importer-path", "", "Path of the drop folders (one per application, named after its id) watched by the packages importer, requires hosting packages in CoreRoller")
+	syncerSources      = flag.String("syncer-sources", "", "Path to a JSON file with the upstream sources mirrored by the syncer (defaults to the official CoreOS channels)")
+	replicaID          = flag.String("replica-id", "", "Id of this CoreRoller replica used in the leader election (defaults to the hostname)")
+	enableGroupsCache  = flag.Bool("enable-groups-cache", true, "Enable the in-memory cache of the groups used to process updates requests (requires listening for database notifications)")
 	httpLog            = flag.Bool("http-log", false, "Enable http requests logging")
 	httpStaticDir      = flag.String("http-static-dir", "../frontend/built", "Path to frontend static files")
 	logger             = log.New("rollerd")
@@ -36,11 +44,36 @@ func main() {
 		os.Exit(1)
 	}
 
+	sources, err := readSyncerSources()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	var arches []string
+	if *syncerArches != "" {
+		arches = strings.Split(*syncerArches, ",")
+	}
+	if *enableSyncer {
+		if err := syncer.ValidateSources(sources, arches); err != nil {
+			logger.Error("Invalid syncer configuration: " + err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *replicaID == "" {
+		*replicaID, _ = os.Hostname()
+	}
+
 	conf := &controllerConfig{
+		replicaID:          *replicaID,
+		enableGroupsCache:  *enableGroupsCache,
 		enableSyncer:       *enableSyncer,
 		hostCoreosPackages: *hostCoreosPackages,
 		coreosPackagesPath: *coreosPackagesPath,
 		corerollerURL:      *corerollerURL,
+		syncerArches:       arches,
+		syncerSources:      sources,
+		importerPath:       *importerPath,
 	}
 	ctl, err := newController(conf)
 	if err != nil {
@@ -73,9 +106,39 @@ func checkArgs() error {
 		}
 	}
 
+	if *importerPath != "" {
+		if !*hostCoreosPackages {
+			return errors.New("The packages importer requires hosting packages in CoreRoller. Please enable it using -host-coreos-packages")
+		}
+		if fi, err := os.Stat(*importerPath); err != nil || !fi.IsDir() {
+			return errors.New("Invalid importer path. Please ensure you provide a valid path using -importer-path")
+		}
+	}
+
 	return nil
 }
 
+// readSyncerSources reads the upstream sources mirrored by the syncer from the
+// file provided using the -syncer-sources flag, if any.
+func readSyncerSources() ([]*syncer.Source, error) {
+	if *syncerSources == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(*syncerSources)
+	if err != nil {
+		return nil, errors.New("Invalid syncer sources file: " + err.Error())
+	}
+	defer f.Close()
+
+	sources, err := syncer.ReadSources(f)
+	if err != nil {
+		return nil, errors.New("Invalid syncer sources file: " + err.Error())
+	}
+
+	return sources, nil
+}
+
 func setupRoutes(ctl *controller) {
 	// API router setup
 	apiRouter := web.New()
@@ -108,20 +171,42 @@ func setupRoutes(ctl *controller) {
 	apiRouter.Get("/api/apps/:app_id/channels/:channel_id", ctl.getChannel)
 	apiRouter.Get("/api/apps/:app_id/channels", ctl.getChannels)
 
+	// Channels scheduled changes
+	apiRouter.Post("/api/apps/:app_id/channels/:channel_id/scheduled_changes", ctl.addScheduledChange)
+	apiRouter.Delete("/api/apps/:app_id/channels/:channel_id/scheduled_changes/:change_id", ctl.cancelScheduledChange)
+	apiRouter.Get("/api/apps/:app_id/channels/:channel_id/scheduled_changes", ctl.getScheduledChanges)
+
 	// Packages
 	apiRouter.Post("/api/apps/:app_id/packages", ctl.addPackage)
 	apiRouter.Put("/api/apps/:app_id/packages/:package_id", ctl.updatePackage)
 	apiRouter.Delete("/api/apps/:app_id/packages/:package_id", ctl.deletePackage)
+	apiRouter.Post("/api/apps/:app_id/packages/:package_id/retract", ctl.retractPackage)
 	apiRouter.Get("/api/apps/:app_id/packages/:package_id", ctl.getPackage)
 	apiRouter.Get("/api/apps/:app_id/packages", ctl.getPackages)
 
+	// Packages delta payloads
+	apiRouter.Post("/api/apps/:app_id/packages/:package_id/deltas", ctl.addPackageDelta)
+	apiRouter.Put("/api/apps/:app_id/packages/:package_id/deltas/:delta_id", ctl.updatePackageDelta)
+	apiRouter.Delete("/api/apps/:app_id/packages/:package_id/deltas/:delta_id", ctl.deletePackageDelta)
+	apiRouter.Get("/api/apps/:app_id/packages/:package_id/deltas", ctl.getPackageDeltas)
+
+	// Packages architecture payloads
+	apiRouter.Post("/api/apps/:app_id/packages/:package_id/arches", ctl.addPackageArch)
+	apiRouter.Put("/api/apps/:app_id/packages/:package_id/arches/:arch_id", ctl.updatePackageArch)
+	apiRouter.Delete("/api/apps/:app_id/packages/:package_id/arches/:arch_id", ctl.deletePackageArch)
+	apiRouter.Get("/api/apps/:app_id/packages/:package_id/arches", ctl.getPackageArches)
+
 	// Instances
 	apiRouter.Get("/api/apps/:app_id/groups/:group_id/instances/:instance_id/status_history", ctl.getInstanceStatusHistory)
+	apiRouter.Get("/api/apps/:app_id/groups/:group_id/instances/:instance_id/explain", ctl.explainUpdateDecision)
 	apiRouter.Get("/api/apps/:app_id/groups/:group_id/instances", ctl.getInstances)
 
 	// Activity
 	apiRouter.Get("/api/activity", ctl.getActivity)
 
+	// Status
+	apiRouter.Get("/api/status", ctl.getStatus)
+
 	// Omaha server router setup
 	omahaRouter := web.New()
 	omahaRouter.Use(middleware.SubRouter)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// periodicTask represents a background task that rollerd runs every interval
+// until it's asked to stop.
+type periodicTask struct {
+	name     string
+	interval time.Duration
+	run      func() error
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// newPeriodicTask creates a new periodicTask instance.
+func newPeriodicTask(name string, interval time.Duration, run func() error) *periodicTask {
+	return &periodicTask{
+		name:     name,
+		interval: interval,
+		run:      run,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// start makes the task run every interval until it's asked to stop.
+func (t *periodicTask) start() {
+	logger.Debug("periodic task ready!", "task", t.name)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.run(); err != nil {
+				logger.Warn("periodic task failed", "task", t.name, "error", err.Error())
+			}
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// stop stops the task. It doesn't wait for the current run of the task to
+// finish, if any, the task stops once it's done.
+func (t *periodicTask) stop() {
+	logger.Debug("stopping periodic task..", "task", t.name)
+	t.stopOnce.Do(func() {
+		close(t.stopCh)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriodicTask(t *testing.T) {
+	runs := make(chan struct{}, 10)
+	task := newPeriodicTask("test", 10*time.Millisecond, func() error {
+		runs <- struct{}{}
+		return errors.New("task error")
+	})
+	go task.start()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("task didn't run")
+		}
+	}
+
+	task.stop()
+	for len(runs) > 0 {
+		<-runs
+	}
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 0, len(runs), "Task shouldn't run after being stopped.")
+}
+
+func TestPeriodicTaskStopWhileRunning(t *testing.T) {
+	running, release := make(chan struct{}, 1), make(chan struct{})
+	task := newPeriodicTask("test", 10*time.Millisecond, func() error {
+		select {
+		case running <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	})
+	go task.start()
+	<-running
+
+	stopped := make(chan struct{})
+	go func() {
+		task.stop()
+		task.stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stopping a task must not wait for its current run.")
+	}
+	close(release)
+}
@@ -0,0 +1,334 @@
+package importer
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"api"
+
+	"github.com/mgutz/logxi/v1"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+const (
+	checkFrequency = 30 * time.Second
+
+	// metadataSuffix is the suffix of the metadata sidecar files, which are
+	// named after the file they describe.
+	metadataSuffix = ".meta"
+
+	// failedSuffix is appended to the name of the metadata sidecar files whose
+	// import failed, so that they are not processed again.
+	failedSuffix = ".failed"
+)
+
+var (
+	logger = log.New("importer")
+
+	// ErrInvalidAPIInstance error indicates that no valid api instance was
+	// provided to the importer constructor.
+	ErrInvalidAPIInstance = errors.New("invalid api instance")
+
+	// ErrInvalidPath error indicates that the path the importer was asked to
+	// watch or the path where packages are stored is not a valid directory.
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrInvalidMetadata error indicates that the metadata sidecar of a file
+	// doesn't include the package version or that it's not valid (it's used
+	// as the name of the directory where the package file is stored, so it
+	// can't contain path separators).
+	ErrInvalidMetadata = errors.New("invalid metadata")
+
+	// ErrChannelNotFound error indicates that the channel the package should
+	// be assigned to doesn't exist in the application.
+	ErrChannelNotFound = errors.New("channel not found")
+)
+
+// Importer represents a process in charge of watching a drop folder per
+// application, turning the files dropped into packages of the application in
+// CoreRoller. The drop folders are the subdirectories of path named after the
+// applications ids.
+//
+// A file is imported when its metadata sidecar (a JSON file named after it
+// with the .meta suffix) is found, so the sidecar must be written once the
+// file is complete. The file is moved into packagesPath, where it's served by
+// CoreRoller from packagesURL, and its size and hashes are computed while
+// doing it. The importer only runs in the leader replica, so packagesPath must
+// be shared by all replicas serving packages.
+type Importer struct {
+	api          *api.API
+	path         string
+	packagesPath string
+	packagesURL  string
+	isLeader     func() bool
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+}
+
+// Config represents the configuration used to create a new Importer instance.
+type Config struct {
+	Api          *api.API
+	Path         string
+	PackagesPath string
+	PackagesURL  string
+
+	// IsLeader, when provided, is used to confirm that the importer can still
+	// make changes (i.e. it runs in the replica acting as leader) before
+	// importing each file.
+	IsLeader func() bool
+}
+
+// Metadata represents the content of the metadata sidecar of the files dropped
+// in the drop folders.
+type Metadata struct {
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	Channel     string `json:"channel"`
+}
+
+// New creates a new Importer instance.
+func New(conf *Config) (*Importer, error) {
+	if conf.Api == nil {
+		return nil, ErrInvalidAPIInstance
+	}
+
+	for _, path := range []string{conf.Path, conf.PackagesPath} {
+		if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+			return nil, ErrInvalidPath
+		}
+	}
+
+	i := &Importer{
+		api:          conf.Api,
+		path:         conf.Path,
+		packagesPath: conf.PackagesPath,
+		packagesURL:  conf.PackagesURL,
+		isLeader:     conf.IsLeader,
+		stopCh:       make(chan struct{}),
+	}
+
+	return i, nil
+}
+
+// Start makes the importer start working. It will check for new files in the
+// drop folders every checkFrequency until it's asked to stop.
+func (i *Importer) Start() {
+	logger.Debug("importer ready!")
+	ticker := time.NewTicker(checkFrequency)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = i.importFiles()
+		case <-i.stopCh:
+			return
+		}
+	}
+}
+
+// Stop stops the watching of the drop folders.
+func (i *Importer) Stop() {
+	logger.Debug("stopping importer..")
+	i.stopOnce.Do(func() {
+		close(i.stopCh)
+	})
+}
+
+// importFiles imports the files whose metadata sidecar is found in the drop
+// folders. Files that can't be imported are skipped, renaming their metadata
+// sidecar so that they are not processed again.
+func (i *Importer) importFiles() error {
+	metadataFiles, err := filepath.Glob(filepath.Join(i.path, "*", "*"+metadataSuffix))
+	if err != nil {
+		return err
+	}
+
+	for _, metadataFile := range metadataFiles {
+		appID := filepath.Base(filepath.Dir(metadataFile))
+		file := strings.TrimSuffix(metadataFile, metadataSuffix)
+
+		if _, err := os.Stat(file); err != nil {
+			logger.Debug("importFiles, file not found yet", "file", file)
+			continue
+		}
+
+		if i.isLeader != nil && !i.isLeader() {
+			logger.Warn("importFiles, not the leader anymore, files not imported")
+			return nil
+		}
+
+		pkg, err := i.importFile(appID, file, metadataFile)
+		if err != nil {
+			logger.Error("importFiles, importing file", "error", err, "file", file, "appID", appID)
+			_ = os.Rename(metadataFile, metadataFile+failedSuffix)
+			continue
+		}
+		logger.Info("importFiles, package imported", "file", file, "appID", appID, "package", pkg.ID, "version", pkg.Version)
+	}
+
+	return nil
+}
+
+// importFile creates a package in the application provided for the file given,
+// using the details in its metadata sidecar, and assigns it to a channel when
+// requested. The file and its metadata sidecar are removed from the drop
+// folder once imported. When the channel can't be updated the package is
+// deleted, so the file can be imported again.
+func (i *Importer) importFile(appID, file, metadataFile string) (*api.Package, error) {
+	metadata, err := readMetadata(metadataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := i.api.GetApp(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	var channel *api.Channel
+	if metadata.Channel != "" {
+		for _, c := range app.Channels {
+			if c.Name == metadata.Channel {
+				channel = c
+				break
+			}
+		}
+		if channel == nil {
+			return nil, ErrChannelNotFound
+		}
+	}
+
+	filename := filepath.Base(file)
+	pkgPath := filepath.Join(i.packagesPath, app.ID, metadata.Version)
+	payload, err := storePayload(file, pkgPath, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &api.Package{
+		Type:          api.PkgTypeOther,
+		Version:       metadata.Version,
+		URL:           i.packagesURL + app.ID + "/" + metadata.Version + "/",
+		Filename:      dat.NullStringFrom(filename),
+		Size:          dat.NullStringFrom(payload.size),
+		Hash:          dat.NullStringFrom(payload.sha1),
+		ApplicationID: app.ID,
+		Files: []*api.PackageFile{
+			{
+				Name:       filename,
+				Size:       dat.NullStringFrom(payload.size),
+				Hash:       dat.NullStringFrom(payload.sha1),
+				HashSha256: dat.NullStringFrom(payload.sha256),
+				Required:   true,
+			},
+		},
+	}
+	if metadata.Description != "" {
+		pkg.Description = dat.NullStringFrom(metadata.Description)
+	}
+	if _, err := i.api.AddPackage(pkg); err != nil {
+		_ = os.Remove(filepath.Join(pkgPath, filename))
+		return nil, err
+	}
+
+	if channel != nil {
+		channel.PackageID = dat.NullStringFrom(pkg.ID)
+		if err := i.api.UpdateChannel(channel); err != nil {
+			_ = i.api.DeletePackage(pkg.ID)
+			_ = os.Remove(filepath.Join(pkgPath, filename))
+			return nil, err
+		}
+	}
+
+	_ = os.Remove(file)
+	_ = os.Remove(metadataFile)
+
+	return pkg, nil
+}
+
+// readMetadata reads the metadata sidecar file provided.
+func readMetadata(metadataFile string) (*Metadata, error) {
+	data, err := ioutil.ReadFile(metadataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &Metadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Version == "" || metadata.Version == "." || metadata.Version == ".." || strings.ContainsAny(metadata.Version, `/\`) {
+		return nil, ErrInvalidMetadata
+	}
+
+	return metadata, nil
+}
+
+// payloadInfo represents the size and hashes of a package payload, formatted
+// as they are sent in the Omaha manifests (sha1 base64 encoded, sha256 hex
+// encoded).
+type payloadInfo struct {
+	size   string
+	sha1   string
+	sha256 string
+}
+
+// storePayload copies the file provided into the given directory using the
+// filename given, computing its size and hashes while doing it. The file is
+// copied (instead of moved) because the drop folders may be in a different
+// filesystem.
+func storePayload(file, dir, filename string) (*payloadInfo, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	src, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	tmpFile, err := ioutil.TempFile(dir, "tmp_pkg_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hashSha1, hashSha256 := sha1.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hashSha1, hashSha256), src)
+	if err != nil {
+		_ = tmpFile.Close()
+		return nil, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return nil, err
+	}
+
+	dst := filepath.Join(dir, filename)
+	if _, err := os.Stat(dst); err == nil {
+		return nil, fmt.Errorf("package file already exists (%s)", dst)
+	}
+	if err := os.Rename(tmpFile.Name(), dst); err != nil {
+		return nil, err
+	}
+
+	return &payloadInfo{
+		size:   strconv.FormatInt(size, 10),
+		sha1:   base64.StdEncoding.EncodeToString(hashSha1.Sum(nil)),
+		sha256: hex.EncodeToString(hashSha256.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"api"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgutz/dat.v1"
+)
+
+const (
+	testsDbURL string = "postgres://postgres@127.0.0.1:5432/coreroller_tests?sslmode=disable&connect_timeout=10"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("COREROLLER_DB_URL", testsDbURL)
+
+	a, err := api.New(api.OptionInitDB)
+	if err != nil {
+		log.Println("These tests require PostgreSQL running and a tests database created, please adjust testsDbUrl as needed.")
+		log.Println("Default: postgres://postgres@127.0.0.1:5432/coreroller_tests?sslmode=disable")
+		log.Println(err)
+		os.Exit(1)
+	}
+	a.Close()
+
+	os.Exit(m.Run())
+}
+
+func TestImportFiles(t *testing.T) {
+	a, _ := api.New(api.OptionInitDB)
+	defer a.Close()
+
+	tTeam, _ := a.AddTeam(&api.Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&api.Application{Name: "test_app", TeamID: tTeam.ID})
+	tChannel, _ := a.AddChannel(&api.Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID})
+
+	dropPath, _ := ioutil.TempDir("", "importer_drop_")
+	defer os.RemoveAll(dropPath)
+	packagesPath, _ := ioutil.TempDir("", "importer_packages_")
+	defer os.RemoveAll(packagesPath)
+
+	_, err := New(&Config{Api: a, Path: filepath.Join(dropPath, "missing"), PackagesPath: packagesPath})
+	assert.Equal(t, ErrInvalidPath, err)
+
+	i, err := New(&Config{Api: a, Path: dropPath, PackagesPath: packagesPath, PackagesURL: "http://coreroller.host/coreos/"})
+	assert.NoError(t, err)
+
+	appPath := filepath.Join(dropPath, tApp.ID)
+	_ = os.Mkdir(appPath, 0755)
+
+	// Files without metadata sidecar are not imported yet
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app.tgz"), []byte("hello"), 0644)
+	assert.NoError(t, i.importFiles())
+	_, err = a.GetPackageByVersion(tApp.ID, "1.0.0")
+	assert.Error(t, err)
+
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app.tgz.meta"), []byte(`{"version": "1.0.0", "description": "release 1.0.0", "channel": "test_channel"}`), 0644)
+	assert.NoError(t, i.importFiles())
+
+	pkg, err := a.GetPackageByVersion(tApp.ID, "1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://coreroller.host/coreos/"+tApp.ID+"/1.0.0/", pkg.URL)
+	assert.Equal(t, dat.NullStringFrom("app.tgz"), pkg.Filename)
+	assert.Equal(t, dat.NullStringFrom("release 1.0.0"), pkg.Description)
+	assert.Equal(t, dat.NullStringFrom("5"), pkg.Size)
+	assert.Equal(t, dat.NullStringFrom("qvTGHdzF6KLavt4PO0gs2a6pQ00="), pkg.Hash)
+	if assert.Len(t, pkg.Files, 1) {
+		assert.Equal(t, dat.NullStringFrom("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"), pkg.Files[0].HashSha256)
+	}
+
+	channel, _ := a.GetChannel(tChannel.ID)
+	assert.Equal(t, dat.NullStringFrom(pkg.ID), channel.PackageID)
+
+	payload, err := ioutil.ReadFile(filepath.Join(packagesPath, tApp.ID, "1.0.0", "app.tgz"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(payload))
+	_, err = os.Stat(filepath.Join(appPath, "app.tgz"))
+	assert.True(t, os.IsNotExist(err), "Imported files must be removed from the drop folder.")
+	_, err = os.Stat(filepath.Join(appPath, "app.tgz.meta"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Files that can't be imported are not processed again
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app2.tgz"), []byte("hello"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app2.tgz.meta"), []byte(`{"version": "2.0.0", "channel": "missing_channel"}`), 0644)
+	assert.NoError(t, i.importFiles())
+	_, err = a.GetPackageByVersion(tApp.ID, "2.0.0")
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(appPath, "app2.tgz.meta.failed"))
+	assert.NoError(t, err)
+
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app3.tgz"), []byte("hello"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app3.tgz.meta"), []byte(`{"description": "no version"}`), 0644)
+	assert.NoError(t, i.importFiles())
+	_, err = os.Stat(filepath.Join(appPath, "app3.tgz.meta.failed"))
+	assert.NoError(t, err)
+
+	// Versions can't be used to store files outside the packages path
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app4.tgz"), []byte("hello"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(appPath, "app4.tgz.meta"), []byte(`{"version": "../../4.0.0"}`), 0644)
+	assert.NoError(t, i.importFiles())
+	_, err = os.Stat(filepath.Join(appPath, "app4.tgz.meta.failed"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(packagesPath, "..", "4.0.0"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadMetadata(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "importer_metadata_")
+	defer os.RemoveAll(dir)
+	metadataFile := filepath.Join(dir, "app.tgz.meta")
+
+	_ = ioutil.WriteFile(metadataFile, []byte(`{"version": "1.0.0", "channel": "stable"}`), 0644)
+	metadata, err := readMetadata(metadataFile)
+	assert.NoError(t, err)
+	assert.Equal(t, &Metadata{Version: "1.0.0", Channel: "stable"}, metadata)
+
+	for _, version := range []string{"", ".", "..", "../1.0.0", "1.0.0/..", `..\1.0.0`} {
+		data, _ := json.Marshal(&Metadata{Version: version})
+		_ = ioutil.WriteFile(metadataFile, data, 0644)
+		_, err := readMetadata(metadataFile)
+		assert.Equal(t, ErrInvalidMetadata, err, version)
+	}
+}
@@ -1,10 +1,13 @@
 package omaha
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"io"
+	"io/ioutil"
 	"strconv"
+	"strings"
 
 	"api"
 
@@ -32,6 +35,129 @@ var (
 	ErrMalformedResponse = errors.New("omaha: response is malformed")
 )
 
+const (
+	// labelAttrPrefix is the prefix of the attributes of the app element of
+	// an Omaha request used by the instances to report their labels (i.e.
+	// label-region="eu-west-1").
+	labelAttrPrefix = "label-"
+)
+
+// labelsRequest represents the parts of an Omaha request used to extract the
+// labels reported by the instance for each app, which aren't part of the
+// Omaha spec. Besides the prefixed attributes of the app element, labels can
+// be reported as the attributes of a labels element inside it (i.e.
+// <labels region="eu-west-1"/>), which allows instances to report that they
+// have no labels anymore sending an empty one.
+type labelsRequest struct {
+	Apps []struct {
+		Attrs  []xml.Attr `xml:",any,attr"`
+		Labels *struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"labels"`
+	} `xml:"app"`
+}
+
+// appLabels returns the labels reported for the app at the index provided. It
+// returns false when the app didn't report any labels, in which case the ones
+// stored are kept. The labels are replaced when the labels element is present,
+// even if it's empty, so that instances can remove all their labels.
+func (r *labelsRequest) appLabels(i int) (map[string]string, bool) {
+	if i >= len(r.Apps) {
+		return nil, false
+	}
+
+	labels := make(map[string]string)
+	for _, attr := range r.Apps[i].Attrs {
+		if strings.HasPrefix(attr.Name.Local, labelAttrPrefix) {
+			labels[strings.TrimPrefix(attr.Name.Local, labelAttrPrefix)] = attr.Value
+		}
+	}
+	if r.Apps[i].Labels != nil {
+		for _, attr := range r.Apps[i].Labels.Attrs {
+			labels[attr.Name.Local] = attr.Value
+		}
+	} else if len(labels) == 0 {
+		return nil, false
+	}
+
+	return labels, true
+}
+
+// response wraps the Omaha response of the library, extending the packages of
+// the update manifests with the sha256 hash of their payload (hash_sha256
+// attribute), which the library doesn't support.
+type response struct {
+	*omahaSpec.Response
+
+	// packagesSha256 maps the sha1 hash of the packages payloads to their
+	// sha256 hash.
+	packagesSha256 map[string]string
+}
+
+// newResponse creates a new response instance.
+func newResponse() *response {
+	return &response{
+		Response:       omahaSpec.NewResponse("coreroller"),
+		packagesSha256: make(map[string]string),
+	}
+}
+
+// addPackagesSha256 registers the sha256 hash of the files of the package
+// provided, so that it's sent for them if they're part of an update manifest.
+func (r *response) addPackagesSha256(pkg *api.Package) {
+	if pkg == nil {
+		return
+	}
+
+	for _, file := range pkg.Files {
+		if file.Hash.Valid && file.HashSha256.Valid {
+			r.packagesSha256[file.Hash.String] = file.HashSha256.String
+		}
+	}
+}
+
+// encode writes the XML encoding of the response to the writer provided,
+// adding the hash_sha256 attribute to the package elements whose sha256 hash
+// is known.
+func (r *response) encode(w io.Writer) error {
+	if len(r.packagesSha256) == 0 {
+		return xml.NewEncoder(w).Encode(r.Response)
+	}
+
+	data, err := xml.Marshal(r.Response)
+	if err != nil {
+		return err
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	e := xml.NewEncoder(w)
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if se, ok := token.(xml.StartElement); ok && se.Name.Local == "package" {
+			for _, attr := range se.Attr {
+				if hashSha256, ok := r.packagesSha256[attr.Value]; ok && attr.Name.Local == "hash" {
+					se.Attr = append(se.Attr, xml.Attr{Name: xml.Name{Local: "hash_sha256"}, Value: hashSha256})
+					token = se
+					break
+				}
+			}
+		}
+
+		if err := e.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+
+	return e.Flush()
+}
+
 // Handler represents a component capable of processing Omaha requests. It uses
 // the CoreRoller API to get packages updates, process events, etc.
 type Handler struct {
@@ -48,27 +174,38 @@ func NewHandler(crApi *api.API) *Handler {
 // Handle is in charge of processing an Omaha request.
 func (h *Handler) Handle(rawReq io.Reader, respWriter io.Writer, ip string) error {
 	var omahaReq *omahaSpec.Request
+	var labelsReq labelsRequest
 
-	if err := xml.NewDecoder(rawReq).Decode(&omahaReq); err != nil {
+	data, err := ioutil.ReadAll(rawReq)
+	if err != nil {
+		logger.Warn("Handle - error reading omaha request", "error", err.Error())
+		return ErrMalformedRequest
+	}
+	if err := xml.Unmarshal(data, &omahaReq); err != nil {
+		logger.Warn("Handle - malformed omaha request", "error", err.Error())
+		return ErrMalformedRequest
+	}
+	if err := xml.Unmarshal(data, &labelsReq); err != nil {
 		logger.Warn("Handle - malformed omaha request", "error", err.Error())
 		return ErrMalformedRequest
 	}
 	trace(omahaReq)
 
-	omahaResp, err := h.buildOmahaResponse(omahaReq, ip)
+	omahaResp, err := h.buildOmahaResponse(omahaReq, &labelsReq, ip)
 	if err != nil {
 		logger.Warn("Handle - error building omaha response", "error", err.Error())
 		return ErrMalformedResponse
 	}
 	trace(omahaResp)
 
-	return xml.NewEncoder(respWriter).Encode(omahaResp)
+	return omahaResp.encode(respWriter)
 }
 
-func (h *Handler) buildOmahaResponse(omahaReq *omahaSpec.Request, ip string) (*omahaSpec.Response, error) {
-	omahaResp := omahaSpec.NewResponse("coreroller")
+func (h *Handler) buildOmahaResponse(omahaReq *omahaSpec.Request, labelsReq *labelsRequest, ip string) (*response, error) {
+	omahaResp := newResponse()
+	arch := requestArch(omahaReq)
 
-	for _, reqApp := range omahaReq.Apps {
+	for i, reqApp := range omahaReq.Apps {
 		respApp := omahaResp.AddApp(reqApp.Id)
 		respApp.Status = "ok"
 		respApp.Track = reqApp.Track
@@ -85,6 +222,14 @@ func (h *Handler) buildOmahaResponse(omahaReq *omahaSpec.Request, ip string) (*o
 			}
 		}
 
+		// Labels are stored before checking for updates, as the group's label
+		// selector may depend on them.
+		if labels, ok := labelsReq.appLabels(i); ok {
+			if err := h.crApi.SetInstanceLabels(reqApp.MachineID, ip, reqApp.Id, group, labels); err != nil {
+				logger.Warn("processLabels", "error", err.Error(), "machineID", reqApp.MachineID)
+			}
+		}
+
 		if reqApp.Events != nil {
 			for _, event := range reqApp.Events {
 				if err := h.processEvent(reqApp.MachineID, reqApp.Id, group, event); err != nil {
@@ -104,11 +249,16 @@ func (h *Handler) buildOmahaResponse(omahaReq *omahaSpec.Request, ip string) (*o
 		}
 
 		if reqApp.UpdateCheck != nil {
-			pkg, err := h.crApi.GetUpdatePackage(reqApp.MachineID, ip, reqApp.Version, reqApp.Id, group)
-			if err != nil && err != api.ErrNoUpdatePackageAvailable {
+			pkg, err := h.crApi.GetUpdatePackageForArch(reqApp.MachineID, ip, reqApp.Version, reqApp.Id, group, arch)
+			switch err {
+			case nil, api.ErrNoUpdatePackageAvailable:
+				respApp.UpdateCheck = h.prepareUpdateCheck(pkg, reqApp.Version, arch)
+				omahaResp.addPackagesSha256(pkg)
+			case api.ErrNoPackageForArch:
+				respApp.Status = h.getStatusMessage(err)
+				respApp.UpdateCheck = h.prepareUpdateCheck(nil, reqApp.Version, arch)
+			default:
 				respApp.Status = h.getStatusMessage(err)
-			} else {
-				respApp.UpdateCheck = h.prepareUpdateCheck(pkg)
 			}
 		}
 	}
@@ -116,6 +266,21 @@ func (h *Handler) buildOmahaResponse(omahaReq *omahaSpec.Request, ip string) (*o
 	return omahaResp, nil
 }
 
+// requestArch returns the architecture reported in the Omaha request provided.
+// When the os element doesn't include it, the architecture is taken from the
+// service pack suffix (i.e. 1068.9.0_x86_64), as the CoreOS updater does.
+func requestArch(omahaReq *omahaSpec.Request) string {
+	if omahaReq.Os.Arch != "" {
+		return omahaReq.Os.Arch
+	}
+
+	if i := strings.LastIndex(omahaReq.Os.Sp, "_"); i != -1 {
+		return omahaReq.Os.Sp[i+1:]
+	}
+
+	return ""
+}
+
 func (h *Handler) processEvent(machineID string, appID string, group string, event *omahaSpec.Event) error {
 	logger.Info("processEvent", "appID", appID, "group", group, "event", event.Type+"."+event.Result, "eventError", event.ErrorCode, "previousVersion", event.PreviousVersion)
 
@@ -147,6 +312,10 @@ func (h *Handler) getStatusMessage(crErr error) string {
 		return "error-maxTimedOutUpdatesLimitReached"
 	case api.ErrUpdatesDisabled:
 		return "error-updatesDisabled"
+	case api.ErrRolloutGateClosed:
+		return "error-rolloutGateClosed"
+	case api.ErrNoPackageForArch:
+		return "error-noPackageForArch"
 	case api.ErrGetUpdatesStatsFailed:
 		return "error-couldNotCheckUpdatesStats"
 	case api.ErrUpdateInProgressOnInstance:
@@ -158,7 +327,13 @@ func (h *Handler) getStatusMessage(crErr error) string {
 	return "error-failedToRetrieveUpdatePackageInfo"
 }
 
-func (h *Handler) prepareUpdateCheck(pkg *api.Package) *omahaSpec.UpdateCheck {
+// prepareUpdateCheck prepares the update check response for the package
+// provided. When the package has a payload built for the instance's
+// architecture, it's served instead of the package's main payload. When that
+// payload has a delta that applies on the version the instance is running,
+// the delta is served instead of the full payload, which is made of all the
+// payload's files if it has any.
+func (h *Handler) prepareUpdateCheck(pkg *api.Package, instanceVersion, arch string) *omahaSpec.UpdateCheck {
 	updateCheck := &omahaSpec.UpdateCheck{}
 
 	if pkg == nil {
@@ -166,9 +341,28 @@ func (h *Handler) prepareUpdateCheck(pkg *api.Package) *omahaSpec.UpdateCheck {
 		return updateCheck
 	}
 
+	pkgArch, _ := pkg.ArchPayload(arch)
+	payloadArch := ""
+	if pkgArch != nil {
+		payloadArch = pkgArch.Arch
+	}
+	delta := pkg.DeltaFrom(instanceVersion, payloadArch)
+	files := pkg.PayloadFiles(payloadArch)
+
 	// Create a manifest, but do not add it to UpdateCheck until it's successful
 	manifest := &omahaSpec.Manifest{Version: pkg.Version}
-	manifest.AddPackage(pkg.Hash.String, pkg.Filename.String, pkg.Size.String, true)
+	switch {
+	case delta != nil:
+		manifest.AddPackage(delta.Hash.String, delta.Filename.String, delta.Size.String, true)
+	case len(files) > 0:
+		for _, file := range files {
+			manifest.AddPackage(file.Hash.String, file.Name, file.Size.String, file.Required)
+		}
+	case pkgArch != nil:
+		manifest.AddPackage(pkgArch.Hash.String, pkgArch.Filename.String, pkgArch.Size.String, true)
+	default:
+		manifest.AddPackage(pkg.Hash.String, pkg.Filename.String, pkg.Size.String, true)
+	}
 
 	switch pkg.Type {
 	case api.PkgTypeCoreos:
@@ -186,11 +380,25 @@ func (h *Handler) prepareUpdateCheck(pkg *api.Package) *omahaSpec.UpdateCheck {
 		a.MetadataSignatureRsa = cra.MetadataSignatureRsa
 		a.MetadataSize = cra.MetadataSize
 		a.Deadline = cra.Deadline
+		switch {
+		case delta != nil:
+			a.Sha256 = delta.Sha256.String
+			a.IsDelta = true
+		case pkgArch != nil:
+			a.Sha256 = pkgArch.Sha256.String
+		}
 	}
 
 	updateCheck.Status = "ok"
 	updateCheck.Manifest = manifest
-	updateCheck.AddUrl(pkg.URL)
+	switch {
+	case delta != nil:
+		updateCheck.AddUrl(delta.URL)
+	case pkgArch != nil:
+		updateCheck.AddUrl(pkgArch.URL)
+	default:
+		updateCheck.AddUrl(pkg.URL)
+	}
 
 	return updateCheck
 }
@@ -323,3 +323,212 @@ func checkOmahaCoreosAction(t *testing.T, c *api.CoreosAction, r *omahaSpec.Acti
 	assert.Equal(t, c.NeedsAdmin, r.NeedsAdmin)
 	assert.Equal(t, c.MetadataSignatureRsa, r.MetadataSignatureRsa)
 }
+
+func TestAppUpdateForGatedGroup(t *testing.T) {
+	a, _ := api.New(api.OptionInitDB)
+	defer a.Close()
+	h := NewHandler(a)
+
+	tTeam, _ := a.AddTeam(&api.Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&api.Application{Name: "test_app", Description: "Test app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&api.Package{Type: api.PkgTypeOther, URL: "http://sample.url/pkg", Version: "640.0.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&api.Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tCanary, _ := a.AddGroup(&api.Group{Name: "canary", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes"})
+	tGroup, _ := a.AddGroup(&api.Group{Name: "prod", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicySafeMode: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 2, PolicyUpdateTimeout: "60 minutes", PolicyGateGroupID: dat.NullStringFrom(tCanary.ID)})
+
+	omahaResp := doOmahaRequest(t, h, tApp.ID, "600.0.0", "gated-machine-id", tGroup.ID, "10.0.0.1", false, true, "", "", "")
+	checkOmahaResponse(t, omahaResp, tApp.ID, "error-rolloutGateClosed")
+}
+
+func TestAppUpdateForLabeledInstances(t *testing.T) {
+	a, _ := api.New(api.OptionInitDB)
+	defer a.Close()
+	h := NewHandler(a)
+
+	tTeam, _ := a.AddTeam(&api.Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&api.Application{Name: "test_app", Description: "Test app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&api.Package{Type: api.PkgTypeOther, URL: "http://sample.url/pkg", Version: "640.0.0", ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&api.Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&api.Group{Name: "eu", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes", PolicyLabelSelector: []*api.Label{{Key: "region", Value: "eu-west-1"}}})
+
+	omahaReq := omahaSpec.NewRequest(reqVersion, reqPlatform, reqSp, reqArch)
+	app := omahaReq.AddApp(tApp.ID, "600.0.0")
+	app.MachineID = "labeled-machine-id"
+	app.Track = tGroup.ID
+	app.AddUpdateCheck()
+
+	omahaReqXML, err := xml.Marshal(omahaReq)
+	assert.NoError(t, err)
+	omahaReqXML = bytes.Replace(omahaReqXML, []byte("<app "), []byte(`<app label-region="eu-west-1" label-rack="r12" `), 1)
+
+	omahaRespXML := new(bytes.Buffer)
+	err = h.Handle(bytes.NewReader(omahaReqXML), omahaRespXML, "10.0.0.1")
+	assert.NoError(t, err)
+
+	var omahaResp *omahaSpec.Response
+	err = xml.NewDecoder(omahaRespXML).Decode(&omahaResp)
+	assert.NoError(t, err)
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, tPkg.Filename.String, tPkg.URL, "ok")
+
+	instance, err := a.GetInstance("labeled-machine-id", tApp.ID)
+	assert.NoError(t, err)
+	assert.Len(t, instance.Labels, 2)
+
+	// An empty labels element removes all the instance's labels
+	omahaReqXML, err = xml.Marshal(omahaReq)
+	assert.NoError(t, err)
+	omahaReqXML = bytes.Replace(omahaReqXML, []byte("<updatecheck"), []byte("<labels></labels><updatecheck"), 1)
+	err = h.Handle(bytes.NewReader(omahaReqXML), new(bytes.Buffer), "10.0.0.1")
+	assert.NoError(t, err)
+	instance, _ = a.GetInstance("labeled-machine-id", tApp.ID)
+	assert.Len(t, instance.Labels, 0)
+
+	omahaResp = doOmahaRequest(t, h, tApp.ID, "600.0.0", "unlabeled-machine-id", tGroup.ID, "10.0.0.2", false, true, "", "", "")
+	checkOmahaUpdateResponse(t, omahaResp, "600.0.0", "", "", "noupdate")
+}
+
+func TestAppUpdateWithDeltaPayload(t *testing.T) {
+	a, _ := api.New(api.OptionInitDB)
+	defer a.Close()
+	h := NewHandler(a)
+
+	tAppCoreos, _ := a.GetApp(coreosAppID)
+	tPkgCoreos, _ := a.AddPackage(&api.Package{Type: api.PkgTypeCoreos, URL: "http://sample.url/pkg", Filename: dat.NullStringFrom("coreosupdate.tgz"), Version: "99650.0.0", ApplicationID: tAppCoreos.ID})
+	tChannel, _ := a.AddChannel(&api.Channel{Name: "mychannel", Color: "white", ApplicationID: tAppCoreos.ID, PackageID: dat.NullStringFrom(tPkgCoreos.ID)})
+	tGroup, _ := a.AddGroup(&api.Group{Name: "Production", ApplicationID: tAppCoreos.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+	coreosAction, _ := a.AddCoreosAction(&api.CoreosAction{Event: "postinstall", Sha256: "full-sha256", PackageID: tPkgCoreos.ID})
+	_, _ = a.AddPackageDelta(&api.PackageDelta{FromVersion: "640.0.0", URL: "http://sample.url/delta", Filename: dat.NullStringFrom("coreosupdate-delta.tgz"), Sha256: dat.NullStringFrom("delta-sha256"), PackageID: tPkgCoreos.ID})
+
+	omahaResp := doOmahaRequest(t, h, tAppCoreos.ID, "640.0.0", "delta-machine-id", tGroup.ID, "10.0.0.1", false, true, "", "", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkgCoreos.Version, "coreosupdate-delta.tgz", "http://sample.url/delta", "ok")
+	action := omahaResp.Apps[0].UpdateCheck.Manifest.Actions.Actions[0]
+	assert.Equal(t, "delta-sha256", action.Sha256)
+	assert.True(t, action.IsDelta)
+
+	omahaResp = doOmahaRequest(t, h, tAppCoreos.ID, "600.0.0", "full-machine-id", tGroup.ID, "10.0.0.2", false, true, "", "", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkgCoreos.Version, "coreosupdate.tgz", tPkgCoreos.URL, "ok")
+	checkOmahaCoreosAction(t, coreosAction, omahaResp.Apps[0].UpdateCheck.Manifest.Actions.Actions[0])
+}
+
+func TestAppUpdateWithMultipleFiles(t *testing.T) {
+	a, _ := api.New(api.OptionInitDB)
+	defer a.Close()
+	h := NewHandler(a)
+
+	tTeam, _ := a.AddTeam(&api.Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&api.Application{Name: "test_app", Description: "Test app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&api.Package{Type: api.PkgTypeOther, URL: "http://sample.url/pkg/", Version: "640.0.0", ApplicationID: tApp.ID, Files: []*api.PackageFile{
+		{Name: "app", Size: dat.NullStringFrom("1024"), Hash: dat.NullStringFrom("sha1-app"), HashSha256: dat.NullStringFrom("sha256-app"), Required: true},
+		{Name: "schema.json", Size: dat.NullStringFrom("64"), Hash: dat.NullStringFrom("sha1-schema"), Required: false},
+	}})
+	tChannel, _ := a.AddChannel(&api.Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&api.Group{Name: "test_group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+
+	omahaReq := omahaSpec.NewRequest(reqVersion, reqPlatform, reqSp, reqArch)
+	app := omahaReq.AddApp(tApp.ID, "600.0.0")
+	app.MachineID = "multifile-machine-id"
+	app.Track = tGroup.ID
+	app.AddUpdateCheck()
+
+	omahaReqXML, err := xml.Marshal(omahaReq)
+	assert.NoError(t, err)
+	omahaRespXML := new(bytes.Buffer)
+	err = h.Handle(bytes.NewReader(omahaReqXML), omahaRespXML, "10.0.0.1")
+	assert.NoError(t, err)
+
+	var omahaResp *omahaSpec.Response
+	err = xml.Unmarshal(omahaRespXML.Bytes(), &omahaResp)
+	assert.NoError(t, err)
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "app", tPkg.URL, "ok")
+
+	packages := omahaResp.Apps[0].UpdateCheck.Manifest.Packages.Packages
+	if assert.Len(t, packages, 2) {
+		assert.Equal(t, "sha1-app", packages[0].Hash)
+		assert.Equal(t, "1024", packages[0].Size)
+		assert.True(t, packages[0].Required)
+		assert.Equal(t, "schema.json", packages[1].Name)
+		assert.False(t, packages[1].Required)
+	}
+
+	// The sha256 hashes aren't supported by the Omaha library types
+	var manifestResp struct {
+		Packages []struct {
+			Name       string `xml:"name,attr"`
+			HashSha256 string `xml:"hash_sha256,attr"`
+		} `xml:"app>updatecheck>manifest>packages>package"`
+	}
+	err = xml.Unmarshal(omahaRespXML.Bytes(), &manifestResp)
+	assert.NoError(t, err)
+	if assert.Len(t, manifestResp.Packages, 2) {
+		assert.Equal(t, "sha256-app", manifestResp.Packages[0].HashSha256)
+		assert.Equal(t, "", manifestResp.Packages[1].HashSha256)
+	}
+}
+
+func TestAppUpdateForArch(t *testing.T) {
+	a, _ := api.New(api.OptionInitDB)
+	defer a.Close()
+	h := NewHandler(a)
+
+	tTeam, _ := a.AddTeam(&api.Team{Name: "test_team"})
+	tApp, _ := a.AddApp(&api.Application{Name: "test_app", Description: "Test app", TeamID: tTeam.ID})
+	tPkg, _ := a.AddPackage(&api.Package{Type: api.PkgTypeOther, URL: "http://sample.url/pkg", Filename: dat.NullStringFrom("pkg-amd64.tgz"), Version: "640.0.0", Arch: dat.NullStringFrom("amd64"), ApplicationID: tApp.ID})
+	tChannel, _ := a.AddChannel(&api.Channel{Name: "test_channel", Color: "blue", ApplicationID: tApp.ID, PackageID: dat.NullStringFrom(tPkg.ID)})
+	tGroup, _ := a.AddGroup(&api.Group{Name: "test_group", ApplicationID: tApp.ID, ChannelID: dat.NullStringFrom(tChannel.ID), PolicyUpdatesEnabled: true, PolicyPeriodInterval: "15 minutes", PolicyMaxUpdatesPerPeriod: 10, PolicyUpdateTimeout: "60 minutes"})
+	_, _ = a.AddPackageArch(&api.PackageArch{Arch: "arm64", URL: "http://sample.url/pkg-arm64", Filename: dat.NullStringFrom("pkg-arm64.tgz"), PackageID: tPkg.ID})
+
+	doArchRequest := func(machineID, sp, arch string) *omahaSpec.Response {
+		omahaReq := omahaSpec.NewRequest(reqVersion, reqPlatform, sp, arch)
+		app := omahaReq.AddApp(tApp.ID, "600.0.0")
+		app.MachineID = machineID
+		app.Track = tGroup.ID
+		app.AddUpdateCheck()
+
+		omahaReqXML, err := xml.Marshal(omahaReq)
+		assert.NoError(t, err)
+
+		omahaRespXML := new(bytes.Buffer)
+		assert.NoError(t, h.Handle(bytes.NewReader(omahaReqXML), omahaRespXML, "10.0.0.1"))
+
+		var omahaResp *omahaSpec.Response
+		assert.NoError(t, xml.NewDecoder(omahaRespXML).Decode(&omahaResp))
+
+		return omahaResp
+	}
+
+	omahaResp := doArchRequest("amd64-machine-id", "600.0.0_x86_64", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "pkg-amd64.tgz", "http://sample.url/pkg", "ok")
+
+	omahaResp = doArchRequest("arm64-machine-id", "600.0.0_aarch64", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "pkg-arm64.tgz", "http://sample.url/pkg-arm64", "ok")
+
+	omahaResp = doArchRequest("ppc-machine-id", "", "ppc64le")
+	checkOmahaResponse(t, omahaResp, tApp.ID, "error-noPackageForArch")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "", "", "noupdate")
+
+	// Files and deltas are served for the payload of the instance's arch
+	pkg, _ := a.GetPackage(tPkg.ID)
+	pkg.Files = []*api.PackageFile{{Name: "app-arm64", Arch: dat.NullStringFrom("aarch64"), Required: true}}
+	assert.NoError(t, a.UpdatePackage(pkg))
+
+	omahaResp = doArchRequest("arm64-files-machine-id", "600.0.0_aarch64", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "app-arm64", "http://sample.url/pkg-arm64", "ok")
+
+	omahaResp = doArchRequest("amd64-files-machine-id", "600.0.0_x86_64", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "pkg-amd64.tgz", "http://sample.url/pkg", "ok")
+
+	_, err := a.AddPackageDelta(&api.PackageDelta{FromVersion: "600.0.0", Arch: dat.NullStringFrom("arm64"), URL: "http://sample.url/delta-arm64", Filename: dat.NullStringFrom("delta-arm64.tgz"), PackageID: tPkg.ID})
+	assert.NoError(t, err)
+
+	omahaResp = doArchRequest("arm64-delta-machine-id", "600.0.0_aarch64", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "delta-arm64.tgz", "http://sample.url/delta-arm64", "ok")
+
+	omahaResp = doArchRequest("amd64-delta-machine-id", "600.0.0_x86_64", "")
+	checkOmahaUpdateResponse(t, omahaResp, tPkg.Version, "pkg-amd64.tgz", "http://sample.url/pkg", "ok")
+}
+
+func TestRequestArch(t *testing.T) {
+	assert.Equal(t, "arm64", requestArch(omahaSpec.NewRequest(reqVersion, reqPlatform, "1068.9.0_x86_64", "arm64")))
+	assert.Equal(t, "x86_64", requestArch(omahaSpec.NewRequest(reqVersion, reqPlatform, "1068.9.0_x86_64", "")))
+	assert.Equal(t, "", requestArch(omahaSpec.NewRequest(reqVersion, reqPlatform, "linux", "")))
+}
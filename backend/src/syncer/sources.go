@@ -0,0 +1,161 @@
+package syncer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+)
+
+const (
+	coreosUpdatesURL      = "https://public.update.core-os.net/v1/update/"
+	coreosAppID           = "{e96281a6-d1af-4bde-9a0a-97b76e56dc57}"
+	coreosInitialVersion  = "766.0.0"
+	defaultInitialVersion = "0.0.0"
+)
+
+// ErrInvalidSource error indicates that one of the upstream sources the syncer
+// was asked to mirror is not valid.
+var ErrInvalidSource = errors.New("invalid source")
+
+// Source represents an upstream Omaha server whose updates are mirrored by the
+// syncer into a CoreRoller application. Each of the remote tracks is mapped to
+// a channel of the local application, which will be updated to point to the
+// packages created for the updates found in the track.
+type Source struct {
+	// Name identifies the source, it's used as well as prefix of the packages
+	// payloads files when hosting packages is enabled.
+	Name string `json:"name"`
+
+	// URL is the Omaha endpoint of the upstream server.
+	URL string `json:"url"`
+
+	// AppID is the id of the application in the upstream server.
+	AppID string `json:"app_id"`
+
+	// ApplicationID is the id of the local application updates are mirrored
+	// into.
+	ApplicationID string `json:"application_id"`
+
+	// Tracks maps the remote tracks to the names of the local application's
+	// channels.
+	Tracks map[string]string `json:"tracks"`
+
+	// Arches are the architectures tracked (amd64 by default). Channels are
+	// updated only by the first one. Packages are created without
+	// architecture, serving their payload to any of them, unless arches are
+	// listed explicitly.
+	Arches []string `json:"arches"`
+
+	// InitialVersion is the version reported to the upstream server for the
+	// tracks whose channel doesn't point to any package yet.
+	InitialVersion string `json:"initial_version"`
+}
+
+// coreosSource returns the source used by default, which mirrors the official
+// CoreOS channels into the CoreOS application.
+func coreosSource(arches []string) *Source {
+	return &Source{
+		Name:          "coreos",
+		URL:           coreosUpdatesURL,
+		AppID:         coreosAppID,
+		ApplicationID: coreosAppID,
+		Tracks: map[string]string{
+			"stable": "stable",
+			"beta":   "beta",
+			"alpha":  "alpha",
+		},
+		Arches:         arches,
+		InitialVersion: coreosInitialVersion,
+	}
+}
+
+// ReadSources reads the JSON encoded list of sources from the reader provided.
+func ReadSources(r io.Reader) ([]*Source, error) {
+	var sources []*Source
+
+	if err := json.NewDecoder(r).Decode(&sources); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// ValidateSources checks that the sources provided can be mirrored by the
+// syncer, so that configuration errors can be detected on startup. When no
+// sources are provided, the default source tracking the architectures given
+// is checked.
+func ValidateSources(sources []*Source, arches []string) error {
+	_, err := syncSources(sources, arches)
+	return err
+}
+
+// syncSources returns the sources the syncer will mirror (the default one when
+// no sources are provided) once validated, setting the defaults of their
+// optional details. Sources names must be unique, and each local channel can
+// be updated by a single source's track.
+func syncSources(sources []*Source, arches []string) ([]*Source, error) {
+	if len(sources) == 0 {
+		sources = []*Source{coreosSource(arches)}
+	}
+
+	type localChannel struct {
+		applicationID string
+		name          string
+	}
+
+	names := make(map[string]bool, len(sources))
+	channels := make(map[localChannel]bool)
+	for _, src := range sources {
+		if err := src.validate(); err != nil {
+			return nil, err
+		}
+		if names[src.Name] {
+			return nil, ErrInvalidSource
+		}
+		names[src.Name] = true
+
+		for _, channelName := range src.Tracks {
+			channel := localChannel{applicationID: src.ApplicationID, name: channelName}
+			if channels[channel] {
+				return nil, ErrInvalidSource
+			}
+			channels[channel] = true
+		}
+	}
+
+	return sources, nil
+}
+
+// arches returns the architectures tracked for the source, the default one
+// when none are listed.
+func (src *Source) arches() []string {
+	if len(src.Arches) == 0 {
+		return []string{defaultArch}
+	}
+
+	return src.Arches
+}
+
+// validate checks that the source has all the details needed to mirror its
+// updates, setting the defaults of the optional ones.
+func (src *Source) validate() error {
+	if src.Name == "" || src.AppID == "" || src.ApplicationID == "" || len(src.Tracks) == 0 {
+		return ErrInvalidSource
+	}
+	if _, err := url.ParseRequestURI(src.URL); err != nil {
+		return ErrInvalidSource
+	}
+
+	for _, arch := range src.arches() {
+		if _, ok := archesSuffixes[arch]; !ok {
+			return ErrInvalidArch
+		}
+	}
+
+	if src.InitialVersion == "" {
+		src.InitialVersion = defaultInitialVersion
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package syncer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSources(t *testing.T) {
+	config := `[
+		{
+			"name": "flatcar",
+			"url": "https://public.update.flatcar-linux.net/v1/update/",
+			"app_id": "{e96281a6-d1af-4bde-9a0a-97b76e56dc57}",
+			"application_id": "b6e2d9a8-9e4c-4b3e-a26a-6d1a3f1c2b10",
+			"tracks": {"stable": "flatcar-stable", "beta": "flatcar-beta"},
+			"arches": ["amd64", "arm64"]
+		}
+	]`
+
+	sources, err := ReadSources(strings.NewReader(config))
+	assert.NoError(t, err)
+	assert.Len(t, sources, 1)
+	assert.Equal(t, "flatcar", sources[0].Name)
+	assert.Equal(t, "flatcar-beta", sources[0].Tracks["beta"])
+	assert.Equal(t, []string{"amd64", "arm64"}, sources[0].Arches)
+
+	_, err = ReadSources(strings.NewReader(`{"name": "flatcar"}`))
+	assert.Error(t, err)
+}
+
+func TestSourceValidate(t *testing.T) {
+	src := &Source{
+		Name:          "internal",
+		URL:           "http://omaha.internal:8000/v1/update/",
+		AppID:         "{a1b2c3d4-0000-0000-0000-000000000000}",
+		ApplicationID: "b6e2d9a8-9e4c-4b3e-a26a-6d1a3f1c2b10",
+		Tracks:        map[string]string{"prod": "prod"},
+	}
+	assert.NoError(t, src.validate())
+	assert.Empty(t, src.Arches, "Arches not listed explicitly are not set.")
+	assert.Equal(t, []string{defaultArch}, src.arches())
+	assert.Equal(t, defaultInitialVersion, src.InitialVersion)
+
+	assert.NoError(t, coreosSource(nil).validate())
+
+	testCases := []struct {
+		mutate      func(src *Source)
+		expectedErr error
+	}{
+		{func(src *Source) { src.Name = "" }, ErrInvalidSource},
+		{func(src *Source) { src.URL = "omaha.internal" }, ErrInvalidSource},
+		{func(src *Source) { src.AppID = "" }, ErrInvalidSource},
+		{func(src *Source) { src.ApplicationID = "" }, ErrInvalidSource},
+		{func(src *Source) { src.Tracks = nil }, ErrInvalidSource},
+		{func(src *Source) { src.Arches = []string{"mips"} }, ErrInvalidArch},
+	}
+
+	for _, tc := range testCases {
+		src := coreosSource(nil)
+		tc.mutate(src)
+		assert.Equal(t, tc.expectedErr, src.validate())
+	}
+}
+
+func TestValidateSources(t *testing.T) {
+	assert.NoError(t, ValidateSources(nil, nil))
+	assert.NoError(t, ValidateSources(nil, []string{"amd64", "arm64"}))
+	assert.Equal(t, ErrInvalidArch, ValidateSources(nil, []string{"mips"}))
+
+	src1, src2 := coreosSource(nil), coreosSource(nil)
+	src2.ApplicationID = "b6e2d9a8-9e4c-4b3e-a26a-6d1a3f1c2b10"
+	assert.Equal(t, ErrInvalidSource, ValidateSources([]*Source{src1, src2}, nil), "Sources names must be unique.")
+
+	src2.Name = "coreos2"
+	assert.NoError(t, ValidateSources([]*Source{src1, src2}, nil))
+
+	src2.ApplicationID = src1.ApplicationID
+	src2.Tracks = map[string]string{"stable": "stable2", "beta": "beta2"}
+	assert.NoError(t, ValidateSources([]*Source{src1, src2}, nil))
+
+	src2.Tracks["alpha"] = "alpha"
+	assert.Equal(t, ErrInvalidSource, ValidateSources([]*Source{src1, src2}, nil), "Channels can be updated by a single source.")
+
+	src1.Tracks["edge"] = "stable"
+	assert.Equal(t, ErrInvalidSource, ValidateSources([]*Source{src1}, nil), "Channels can be updated by a single track.")
+}
@@ -2,6 +2,7 @@ package syncer
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/xml"
@@ -12,6 +13,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"api"
@@ -23,9 +26,8 @@ import (
 )
 
 const (
-	coreosUpdatesURL = "https://public.update.core-os.net/v1/update/"
-	coreosAppID      = "{e96281a6-d1af-4bde-9a0a-97b76e56dc57}"
-	checkFrequency   = 1 * time.Hour
+	checkFrequency = 1 * time.Hour
+	defaultArch    = "amd64"
 )
 
 var (
@@ -34,32 +36,80 @@ var (
 	// ErrInvalidAPIInstance error indicates that no valid api instance was
 	// provided to the syncer constructor.
 	ErrInvalidAPIInstance = errors.New("invalid api instance")
+
+	// ErrInvalidArch error indicates that one of the architectures the syncer
+	// was asked to track is not supported.
+	ErrInvalidArch = errors.New("invalid architecture")
+
+	// ErrInvalidOmahaResponse error indicates that the Omaha response received
+	// from an upstream source doesn't include the update check of the
+	// application requested.
+	ErrInvalidOmahaResponse = errors.New("invalid omaha response")
+
+	// ErrUnsupportedUpdate error indicates that the update received from an
+	// upstream source doesn't have a single package payload and the url to
+	// download it, which is what the syncer can mirror.
+	ErrUnsupportedUpdate = errors.New("unsupported update")
+
+	// archesSuffixes maps the architectures supported to the suffix the CoreOS
+	// updater adds to the service pack in the Omaha requests.
+	archesSuffixes = map[string]string{
+		"amd64": "x86_64",
+		"arm64": "aarch64",
+	}
 )
 
-// Syncer represents a process in charge of checking for updates in the
-// different official CoreOS channels and updating the CoreOS application in
-// CoreRoller as needed (creating new packages and updating channels to point
-// to them). When hostPackages is enabled, packages payloads will be downloaded
-// into packagesPath and package url/filename will be rewritten.
+// syncTarget represents a track and architecture of an upstream source
+// tracked by the syncer.
+type syncTarget struct {
+	source *Source
+	track  string
+	arch   string
+}
+
+// Syncer represents a process in charge of checking for updates in the tracks
+// of the upstream sources configured (the official CoreOS channels by default)
+// and updating their local application in CoreRoller as needed (creating new
+// packages and updating channels to point to them). When hostPackages is
+// enabled, packages payloads will be downloaded into packagesPath and package
+// url/filename will be rewritten.
+//
+// Each architecture of a source is tracked independently. Channels are updated
+// only by the first one (the primary architecture), the builds found for the
+// other architectures are added as architecture payloads to the packages.
 type Syncer struct {
 	api          *api.API
 	hostPackages bool
 	packagesPath string
 	packagesURL  string
+	sources      []*Source
+	isLeader     func() bool
 	stopCh       chan struct{}
-	machinesIDs  map[string]string
-	bootIDs      map[string]string
-	versions     map[string]string
-	channelsIDs  map[string]string
+	stopOnce     sync.Once
+	targets      []syncTarget
+	machinesIDs  map[syncTarget]string
+	bootIDs      map[syncTarget]string
+	versions     map[syncTarget]string
+	channelsIDs  map[syncTarget]string
 	httpClient   *http.Client
 }
 
 // Config represents the configuration used to create a new Syncer instance.
+// When no sources are provided, the official CoreOS channels are mirrored into
+// the CoreOS application, tracking the architectures given (only amd64 builds
+// when no architectures are provided).
 type Config struct {
 	Api          *api.API
 	HostPackages bool
 	PackagesPath string
 	PackagesURL  string
+	Arches       []string
+	Sources      []*Source
+
+	// IsLeader, when provided, is used to confirm that the syncer can still
+	// make changes (i.e. it runs in the replica acting as leader) before
+	// processing each update.
+	IsLeader func() bool
 }
 
 // New creates a new Syncer instance.
@@ -68,16 +118,23 @@ func New(conf *Config) (*Syncer, error) {
 		return nil, ErrInvalidAPIInstance
 	}
 
+	sources, err := syncSources(conf.Sources, conf.Arches)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &Syncer{
 		api:          conf.Api,
 		hostPackages: conf.HostPackages,
 		packagesPath: conf.PackagesPath,
 		packagesURL:  conf.PackagesURL,
+		sources:      sources,
+		isLeader:     conf.IsLeader,
 		stopCh:       make(chan struct{}),
-		machinesIDs:  make(map[string]string, 3),
-		bootIDs:      make(map[string]string, 3),
-		channelsIDs:  make(map[string]string, 3),
-		versions:     make(map[string]string, 3),
+		machinesIDs:  make(map[syncTarget]string),
+		bootIDs:      make(map[syncTarget]string),
+		channelsIDs:  make(map[syncTarget]string),
+		versions:     make(map[syncTarget]string),
 		httpClient:   &http.Client{},
 	}
 
@@ -92,46 +149,67 @@ func New(conf *Config) (*Syncer, error) {
 // checkFrequency until it's asked to stop.
 func (s *Syncer) Start() {
 	logger.Debug("syncer ready!")
-	checkCh := time.Tick(checkFrequency)
+	ticker := time.NewTicker(checkFrequency)
+	defer ticker.Stop()
 
-L:
 	for {
 		select {
-		case <-checkCh:
+		case <-ticker.C:
 			_ = s.checkForUpdates()
 		case <-s.stopCh:
-			break L
+			return
 		}
 	}
-
-	s.api.Close()
 }
 
-// Stop stops the polling for updates.
+// Stop stops the polling for updates. The syncer stops as soon as the Omaha
+// request in progress, if any, is done.
 func (s *Syncer) Stop() {
 	logger.Debug("stopping syncer..")
-	s.stopCh <- struct{}{}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
 }
 
 // initialize does some initial setup to prepare the syncer, checking in
-// CoreRoller the last versions we know about for the different channels in the
-// CoreOS application and keeping track of some ids.
+// CoreRoller the last versions we know about for the channels the tracks of
+// each source are mapped to and keeping track of some ids. The targets are
+// sorted by source, track and architecture (the primary one first), so that
+// packages are always created by the primary architecture when the updates
+// for all of them are available.
 func (s *Syncer) initialize() error {
-	coreosApp, err := s.api.GetApp(coreosAppID)
-	if err != nil {
-		return err
-	}
+	for _, src := range s.sources {
+		app, err := s.api.GetApp(src.ApplicationID)
+		if err != nil {
+			return err
+		}
 
-	for _, c := range coreosApp.Channels {
-		if c.Name == "stable" || c.Name == "beta" || c.Name == "alpha" {
-			s.machinesIDs[c.Name] = "{" + uuid.NewV4().String() + "}"
-			s.bootIDs[c.Name] = "{" + uuid.NewV4().String() + "}"
-			s.channelsIDs[c.Name] = c.ID
+		tracks := make([]string, 0, len(src.Tracks))
+		for track := range src.Tracks {
+			tracks = append(tracks, track)
+		}
+		sort.Strings(tracks)
+
+		for _, track := range tracks {
+			channelName := src.Tracks[track]
+			channel := findChannel(app, channelName)
+			if channel == nil {
+				logger.Error("initialize, channel not found", "source", src.Name, "track", track, "channelName", channelName)
+				return ErrInvalidSource
+			}
 
-			if c.Package != nil {
-				s.versions[c.Name] = c.Package.Version
-			} else {
-				s.versions[c.Name] = "766.0.0"
+			for _, arch := range src.arches() {
+				target := syncTarget{source: src, track: track, arch: arch}
+				s.targets = append(s.targets, target)
+				s.channelsIDs[target] = channel.ID
+				s.machinesIDs[target] = "{" + uuid.NewV4().String() + "}"
+				s.bootIDs[target] = "{" + uuid.NewV4().String() + "}"
+
+				if channel.Package != nil {
+					s.versions[target] = channel.Package.Version
+				} else {
+					s.versions[target] = src.InitialVersion
+				}
 			}
 		}
 	}
@@ -139,52 +217,88 @@ func (s *Syncer) initialize() error {
 	return nil
 }
 
-// checkForUpdates polls the public CoreOS servers looking for updates in the
-// official channels (stable, beta, alpha) sending Omaha requests. When an
+// findChannel returns the channel of the application provided with the given
+// name, if any.
+func findChannel(app *api.Application, name string) *api.Channel {
+	for _, c := range app.Channels {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// checkForUpdates polls the upstream sources looking for updates in their
+// tracks sending Omaha requests for each of the architectures tracked. When an
 // update is received we'll process it, creating packages and updating channels
-// in CoreRoller as needed.
+// in CoreRoller as needed. A target that can't be checked doesn't prevent the
+// rest from being checked, the first error found is returned.
 func (s *Syncer) checkForUpdates() error {
-	for channel, currentVersion := range s.versions {
-		logger.Debug("checking for updates", "channel", channel, "currentVersion", currentVersion)
+	var firstErr error
 
-		update, err := s.doOmahaRequest(channel, currentVersion)
-		if err != nil {
-			return err
-		}
-		if update.Status == "ok" {
-			logger.Debug("checkForUpdates, got an update", "channel", channel, "currentVersion", currentVersion, "availableVersion", update.Manifest.Version)
-			if err := s.processUpdate(channel, update); err != nil {
-				return err
-			}
-			s.versions[channel] = update.Manifest.Version
-			s.bootIDs[channel] = "{" + uuid.NewV4().String() + "}"
-		} else {
-			logger.Debug("checkForUpdates, no update available", "channel", channel, "currentVersion", currentVersion, "updateStatus", update.Status)
+	for _, target := range s.targets {
+		if err := s.checkTargetForUpdates(target); err != nil && firstErr == nil {
+			firstErr = err
 		}
 
 		select {
 		case <-time.After(1 * time.Minute):
 		case <-s.stopCh:
-			break
+			return firstErr
 		}
 	}
 
+	return firstErr
+}
+
+// checkTargetForUpdates checks if there is an update for the target provided,
+// processing it when available.
+func (s *Syncer) checkTargetForUpdates(target syncTarget) error {
+	currentVersion := s.versions[target]
+	logger.Debug("checking for updates", "source", target.source.Name, "track", target.track, "arch", target.arch, "currentVersion", currentVersion)
+
+	update, err := s.doOmahaRequest(target, currentVersion)
+	if err != nil {
+		return err
+	}
+	if update.Status != "ok" {
+		logger.Debug("checkForUpdates, no update available", "source", target.source.Name, "track", target.track, "arch", target.arch, "currentVersion", currentVersion, "updateStatus", update.Status)
+		return nil
+	}
+
+	if err := checkUpdate(update); err != nil {
+		logger.Error("checkForUpdates, unsupported update", "source", target.source.Name, "track", target.track, "arch", target.arch, "error", err)
+		return err
+	}
+	logger.Debug("checkForUpdates, got an update", "source", target.source.Name, "track", target.track, "arch", target.arch, "currentVersion", currentVersion, "availableVersion", update.Manifest.Version)
+	if s.isLeader != nil && !s.isLeader() {
+		logger.Warn("checkForUpdates, not the leader anymore, update not processed", "source", target.source.Name, "track", target.track, "arch", target.arch)
+		return nil
+	}
+	if err := s.processUpdate(target, update); err != nil {
+		return err
+	}
+	s.versions[target] = update.Manifest.Version
+	s.bootIDs[target] = "{" + uuid.NewV4().String() + "}"
+
 	return nil
 }
 
-// doOmahaRequest sends an Omaha request checking if there is an update for a
-// specific CoreOS channel, returning the update check to the caller.
-func (s *Syncer) doOmahaRequest(channel, currentVersion string) (*omaha.UpdateCheck, error) {
-	req := omaha.NewRequest("Chateau", "CoreOS", currentVersion+"_x86_64", "")
+// doOmahaRequest sends an Omaha request to the target's source checking if
+// there is an update for a specific track and architecture, returning the
+// update check to the caller.
+func (s *Syncer) doOmahaRequest(target syncTarget, currentVersion string) (*omaha.UpdateCheck, error) {
+	req := omaha.NewRequest("Chateau", "CoreOS", currentVersion+"_"+archesSuffixes[target.arch], "")
 	req.Version = "CoreOSUpdateEngine-0.1.0.0"
 	req.UpdaterVersion = "CoreOSUpdateEngine-0.1.0.0"
 	req.InstallSource = "scheduler"
 	req.IsMachine = "1"
-	app := req.AddApp(coreosAppID, currentVersion)
+	app := req.AddApp(target.source.AppID, currentVersion)
 	app.AddUpdateCheck()
-	app.MachineID = s.machinesIDs[channel]
-	app.BootId = s.bootIDs[channel]
-	app.Track = channel
+	app.MachineID = s.machinesIDs[target]
+	app.BootId = s.bootIDs[target]
+	app.Track = target.track
 
 	payload, err := xml.Marshal(req)
 	if err != nil {
@@ -193,7 +307,7 @@ func (s *Syncer) doOmahaRequest(channel, currentVersion string) (*omaha.UpdateCh
 	}
 	logger.Debug("doOmahaRequest", "request", string(payload))
 
-	resp, err := s.httpClient.Post(coreosUpdatesURL, "text/xml", bytes.NewReader(payload))
+	resp, err := s.httpClient.Post(target.source.URL, "text/xml", bytes.NewReader(payload))
 	if err != nil {
 		logger.Error("checkForUpdates, posting omaha response", "error", err)
 		return nil, err
@@ -215,81 +329,154 @@ func (s *Syncer) doOmahaRequest(channel, currentVersion string) (*omaha.UpdateCh
 		return nil, err
 	}
 
+	// Omaha servers may reply without an update check, i.e. when the
+	// application is unknown or the request can't be processed.
+	if len(oresp.Apps) == 0 || oresp.Apps[0].Status != "ok" || oresp.Apps[0].UpdateCheck == nil {
+		logger.Error("checkForUpdates, invalid omaha response", "source", target.source.Name, "track", target.track, "arch", target.arch, "response", string(body))
+		return nil, ErrInvalidOmahaResponse
+	}
+
 	return oresp.Apps[0].UpdateCheck, nil
 }
 
-// processUpdate is in charge of creating packages in the CoreOS application in
-// CoreRoller and updating the appropriate channel to point to the new channel.
-func (s *Syncer) processUpdate(channelName string, update *omaha.UpdateCheck) error {
-	// Create new package and action for CoreOS application in CoreRoller if
-	// needed (package may already exist and we just need to update the channel
-	// reference to it)
-	pkg, err := s.api.GetPackageByVersion(coreosAppID, update.Manifest.Version)
+// checkUpdate checks that the update provided can be mirrored by the syncer,
+// which only supports updates with a single package payload (the rest of the
+// files of multi-file packages would be lost otherwise).
+func checkUpdate(update *omaha.UpdateCheck) error {
+	if update.Urls == nil || len(update.Urls.Urls) == 0 || update.Manifest == nil || len(update.Manifest.Packages.Packages) != 1 {
+		return ErrUnsupportedUpdate
+	}
+
+	return nil
+}
+
+// processUpdate is in charge of creating packages in the source's local
+// application in CoreRoller and updating the appropriate channel to point to
+// the new package. Updates found for architectures other than the primary one
+// only add the architecture payload to the package.
+func (s *Syncer) processUpdate(target syncTarget, update *omaha.UpdateCheck) error {
+	var payloadSha256 string
+	if len(update.Manifest.Actions.Actions) > 0 {
+		payloadSha256 = update.Manifest.Actions.Actions[0].Sha256
+	}
+
+	// Create new package (and its CoreOS action when the update provides one)
+	// in the local application in CoreRoller if needed (package may already
+	// exist and we just need to update the channel reference to it)
+	pkg, err := s.api.GetPackageByVersion(target.source.ApplicationID, update.Manifest.Version)
 	if err != nil {
-		url := update.Urls.Urls[0].CodeBase
-		filename := update.Manifest.Packages.Packages[0].Name
-
-		if s.hostPackages {
-			url = s.packagesURL
-			filename = fmt.Sprintf("coreos-amd64-%s.gz", update.Manifest.Version)
-			if err := s.downloadPackage(update, filename); err != nil {
-				logger.Error("processUpdate, downloading package", "error", err, "channelName", channelName)
-				return err
-			}
+		url, filename, err := s.getPayloadLocation(target, update)
+		if err != nil {
+			return err
 		}
 
 		pkg = &api.Package{
-			Type:          api.PkgTypeCoreos,
+			Type:          api.PkgTypeOther,
 			URL:           url,
 			Version:       update.Manifest.Version,
 			Filename:      dat.NullStringFrom(filename),
 			Size:          dat.NullStringFrom(update.Manifest.Packages.Packages[0].Size),
 			Hash:          dat.NullStringFrom(update.Manifest.Packages.Packages[0].Hash),
-			ApplicationID: coreosAppID,
+			ApplicationID: target.source.ApplicationID,
+		}
+		if len(target.source.Arches) > 0 {
+			pkg.Arch = dat.NullStringFrom(target.arch)
+		}
+		if len(update.Manifest.Actions.Actions) > 0 {
+			pkg.Type = api.PkgTypeCoreos
 		}
 		if _, err = s.api.AddPackage(pkg); err != nil {
-			logger.Error("processUpdate, adding package", "error", err, "channelName", channelName)
+			logger.Error("processUpdate, adding package", "error", err, "source", target.source.Name, "track", target.track, "arch", target.arch)
+			return err
+		}
+
+		if pkg.Type == api.PkgTypeCoreos {
+			coreosAction := &api.CoreosAction{
+				Event:                 update.Manifest.Actions.Actions[0].Event,
+				ChromeOSVersion:       update.Manifest.Actions.Actions[0].ChromeOSVersion,
+				Sha256:                update.Manifest.Actions.Actions[0].Sha256,
+				NeedsAdmin:            update.Manifest.Actions.Actions[0].NeedsAdmin,
+				IsDelta:               update.Manifest.Actions.Actions[0].IsDelta,
+				DisablePayloadBackoff: update.Manifest.Actions.Actions[0].DisablePayloadBackoff,
+				MetadataSignatureRsa:  update.Manifest.Actions.Actions[0].MetadataSignatureRsa,
+				MetadataSize:          update.Manifest.Actions.Actions[0].MetadataSize,
+				Deadline:              update.Manifest.Actions.Actions[0].Deadline,
+				PackageID:             pkg.ID,
+			}
+			if _, err = s.api.AddCoreosAction(coreosAction); err != nil {
+				logger.Error("processUpdate, adding coreos action", "error", err, "source", target.source.Name, "track", target.track, "arch", target.arch)
+				return err
+			}
+		}
+	} else if _, ok := pkg.ArchPayload(target.arch); !ok {
+		// The package's main payload isn't for this architecture (packages
+		// without architecture serve it to any of them), add the build found
+		// as an architecture payload.
+		url, filename, err := s.getPayloadLocation(target, update)
+		if err != nil {
 			return err
 		}
 
-		coreosAction := &api.CoreosAction{
-			Event:                 update.Manifest.Actions.Actions[0].Event,
-			ChromeOSVersion:       update.Manifest.Actions.Actions[0].ChromeOSVersion,
-			Sha256:                update.Manifest.Actions.Actions[0].Sha256,
-			NeedsAdmin:            update.Manifest.Actions.Actions[0].NeedsAdmin,
-			IsDelta:               update.Manifest.Actions.Actions[0].IsDelta,
-			DisablePayloadBackoff: update.Manifest.Actions.Actions[0].DisablePayloadBackoff,
-			MetadataSignatureRsa:  update.Manifest.Actions.Actions[0].MetadataSignatureRsa,
-			MetadataSize:          update.Manifest.Actions.Actions[0].MetadataSize,
-			Deadline:              update.Manifest.Actions.Actions[0].Deadline,
-			PackageID:             pkg.ID,
+		pkgArch := &api.PackageArch{
+			Arch:      target.arch,
+			URL:       url,
+			Filename:  dat.NullStringFrom(filename),
+			Size:      dat.NullStringFrom(update.Manifest.Packages.Packages[0].Size),
+			Hash:      dat.NullStringFrom(update.Manifest.Packages.Packages[0].Hash),
+			PackageID: pkg.ID,
 		}
-		if _, err = s.api.AddCoreosAction(coreosAction); err != nil {
-			logger.Error("processUpdate, adding coreos action", "error", err, "channelName", channelName)
+		if payloadSha256 != "" {
+			pkgArch.Sha256 = dat.NullStringFrom(payloadSha256)
+		}
+		if _, err = s.api.AddPackageArch(pkgArch); err != nil {
+			logger.Error("processUpdate, adding package arch", "error", err, "source", target.source.Name, "track", target.track, "arch", target.arch)
 			return err
 		}
 	}
 
+	if target.arch != target.source.arches()[0] {
+		return nil
+	}
+
 	// Update channel to point to the package with the new version
-	channel, err := s.api.GetChannel(s.channelsIDs[channelName])
+	channel, err := s.api.GetChannel(s.channelsIDs[target])
 	if err != nil {
-		logger.Error("processUpdate, getting channel to update", "error", err, "channelName", channelName)
+		logger.Error("processUpdate, getting channel to update", "error", err, "source", target.source.Name, "track", target.track)
 		return err
 	}
 	channel.PackageID = dat.NullStringFrom(pkg.ID)
 	if err = s.api.UpdateChannel(channel); err != nil {
-		logger.Error("processUpdate, updating channel", "error", err, "channelName", channelName)
+		logger.Error("processUpdate, updating channel", "error", err, "source", target.source.Name, "track", target.track)
 		return err
 	}
 
 	return nil
 }
 
+// getPayloadLocation returns the url and filename of the package payload in
+// the update provided. When hosting packages is enabled, the payload is
+// downloaded and the url and filename returned point to CoreRoller.
+func (s *Syncer) getPayloadLocation(target syncTarget, update *omaha.UpdateCheck) (string, string, error) {
+	url := update.Urls.Urls[0].CodeBase
+	filename := update.Manifest.Packages.Packages[0].Name
+
+	if s.hostPackages {
+		url = s.packagesURL
+		filename = fmt.Sprintf("%s-%s-%s.gz", target.source.Name, target.arch, update.Manifest.Version)
+		if err := s.downloadPackage(update, filename); err != nil {
+			logger.Error("processUpdate, downloading package", "error", err, "source", target.source.Name, "track", target.track, "arch", target.arch)
+			return "", "", err
+		}
+	}
+
+	return url, filename, nil
+}
+
 // downloadPackage downloads and verifies the package payload referenced in the
 // update provided. The downloaded package payload is stored in packagesPath
 // using the filename provided.
 func (s *Syncer) downloadPackage(update *omaha.UpdateCheck, filename string) error {
-	tmpFile, err := ioutil.TempFile(s.packagesPath, "tmp_coreos_pkg_")
+	tmpFile, err := ioutil.TempFile(s.packagesPath, "tmp_pkg_")
 	if err != nil {
 		return err
 	}
@@ -305,12 +492,17 @@ func (s *Syncer) downloadPackage(update *omaha.UpdateCheck, filename string) err
 	}
 	defer resp.Body.Close()
 
-	hashSha256 := sha256.New()
+	// The payload is verified using the sha256 hash in the CoreOS action when
+	// available, or the (sha1) hash of the package otherwise.
+	hash, expectedHash := sha1.New(), update.Manifest.Packages.Packages[0].Hash
+	if len(update.Manifest.Actions.Actions) > 0 {
+		hash, expectedHash = sha256.New(), update.Manifest.Actions.Actions[0].Sha256
+	}
 	logger.Debug("downloadPackage, downloading..", "url", pkgURL)
-	if _, err := io.Copy(io.MultiWriter(tmpFile, hashSha256), resp.Body); err != nil {
+	if _, err := io.Copy(io.MultiWriter(tmpFile, hash), resp.Body); err != nil {
 		return err
 	}
-	if base64.StdEncoding.EncodeToString(hashSha256.Sum(nil)) != update.Manifest.Actions.Actions[0].Sha256 {
+	if base64.StdEncoding.EncodeToString(hash.Sum(nil)) != expectedHash {
 		return errors.New("downloaded file hash mismatch")
 	}
 
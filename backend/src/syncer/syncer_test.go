@@ -0,0 +1,54 @@
+package syncer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquam8/go-omaha/omaha"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoOmahaRequest(t *testing.T) {
+	var response string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(response))
+	}))
+	defer ts.Close()
+
+	src := coreosSource(nil)
+	src.URL = ts.URL
+	target := syncTarget{source: src, track: "stable", arch: defaultArch}
+	s := &Syncer{httpClient: &http.Client{}}
+
+	response = `<response protocol="3.0"><app appid="` + coreosAppID + `" status="ok"><updatecheck status="noupdate"></updatecheck></app></response>`
+	update, err := s.doOmahaRequest(target, "766.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "noupdate", update.Status)
+
+	testCases := []string{
+		`<response protocol="3.0"></response>`,
+		`<response protocol="3.0"><app appid="` + coreosAppID + `" status="ok"></app></response>`,
+		`<response protocol="3.0"><app appid="` + coreosAppID + `" status="error-unknownApplication"></app></response>`,
+	}
+	for _, tc := range testCases {
+		response = tc
+		_, err := s.doOmahaRequest(target, "766.0.0")
+		assert.Equal(t, ErrInvalidOmahaResponse, err, tc)
+	}
+}
+
+func TestCheckUpdate(t *testing.T) {
+	update := &omaha.UpdateCheck{Status: "ok"}
+	assert.Equal(t, ErrUnsupportedUpdate, checkUpdate(update), "Updates without urls or manifest are not supported.")
+
+	update.AddUrl("https://update.release.core-os.net/amd64-usr/766.4.0/")
+	manifest := update.AddManifest("766.4.0")
+	assert.Equal(t, ErrUnsupportedUpdate, checkUpdate(update), "Updates without package payload are not supported.")
+
+	manifest.AddPackage("+LXvjiaPkeYDLHoNKlf9qbJwvnk=", "update.gz", "67546213", true)
+	assert.NoError(t, checkUpdate(update))
+
+	manifest.AddPackage("qvTGHdzF6KLavt4PO0gs2a6pQ00=", "update-extra.gz", "5", true)
+	assert.Equal(t, ErrUnsupportedUpdate, checkUpdate(update), "Multi-file updates are not supported.")
+}
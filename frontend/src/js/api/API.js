@@ -116,6 +116,12 @@ class API {
     return API.doRequest("POST", url, JSON.stringify(packageData))
   }
 
+  static retractPackage(applicationID, packageID) {
+    let url = BASE_URL + "/apps/" + applicationID + "/packages/" + packageID + "/retract"
+
+    return API.doRequest("POST", url, "")
+  }
+
   static updatePackage(packageData) {
     let keysToRemove = ["id", "created_ts", "package"],
         processedPackage = API.removeKeysFromObject(packageData, keysToRemove),
@@ -43,7 +43,7 @@ class Item extends React.Component {
         subtitle = "",
         name = ""
 
-    if (this.state.entryClass.type !== "activityChannelPackageUpdated") {
+    if (this.state.entryClass.groupName) {
       subtitle = "GROUP:"
       name = this.state.entryClass.groupName
     }
@@ -27,7 +27,8 @@ class ModalAdd extends React.Component {
     this.setState({isLoading: true})
     let data = {
       name: this.refs.nameNewApp.getValue(),
-      description: this.refs.descriptionNewApp.getValue()
+      description: this.refs.descriptionNewApp.getValue(),
+      version_scheme: this.refs.versionSchemeNewApp.getValue()
     }
 
     let clonedApplication = this.refs.cloningNewApp.getValue()
@@ -84,6 +85,11 @@ class ModalAdd extends React.Component {
                 }}
               />
               <Input type="textarea" label="Description:" ref="descriptionNewApp" maxLength={250} />
+              <Input type="select" label="Version scheme:" placeholder="" defaultValue="semver" groupClassName="arrow-icon" ref="versionSchemeNewApp">
+                <option value="semver">Semantic versioning (1.2.3)</option>
+                <option value="fourpart">Four-part (1.2.3.4)</option>
+                <option value="calendar">Calendar (2016.03.15)</option>
+              </Input>
               <Input type="select" label="Clone channels/groups from:" placeholder="" groupClassName="arrow-icon" ref="cloningNewApp">
                 <option value="" />
                 { this.props.data.applications &&
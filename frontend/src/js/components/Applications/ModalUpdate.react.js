@@ -28,7 +28,8 @@ class ModalUpdate extends React.Component {
     this.setState({isLoading: true})
     var data = {
       name: this.refs.nameApp.getValue(),
-      description: this.refs.descriptionApp.getValue()
+      description: this.refs.descriptionApp.getValue(),
+      version_scheme: this.refs.versionSchemeApp.getValue()
     }
 
     applicationsStore.updateApplication(this.props.data.id, data).
@@ -83,6 +84,11 @@ class ModalUpdate extends React.Component {
                 }}
               />
               <Input type="textarea" label="Description:" ref="descriptionApp" defaultValue={this.props.data.description} maxLength={250} />
+              <Input type="select" label="Version scheme:" placeholder="" defaultValue={this.props.data.version_scheme || "semver"} groupClassName="arrow-icon" ref="versionSchemeApp">
+                <option value="semver">Semantic versioning (1.2.3)</option>
+                <option value="fourpart">Four-part (1.2.3.4)</option>
+                <option value="calendar">Calendar (2016.03.15)</option>
+              </Input>
               <div className="modal--footer">
                 <Row>
                   <Col xs={8}>
@@ -51,7 +51,6 @@ class ItemExtended extends React.Component {
         policyUpdates,
         policyUpdatesTimeout,
         safeMode,
-        officeHours,
         groupChannel,
         styleGroupChannel
 
@@ -66,7 +65,6 @@ class ItemExtended extends React.Component {
       policyUpdates = group.policy_updates_enabled ? group.policy_updates_enabled : null
       policyUpdatesTimeout = group.policy_update_timeout ? group.policy_update_timeout : null
       safeMode = group.policy_safe_mode ? group.policy_safe_mode : null
-      officeHours = group.policy_office_hours ? group.policy_office_hours : null
       version_breakdown = group.version_breakdown ? group.version_breakdown : []
       groupChannel = _.isEmpty(group.channel) ? "No channel provided" : <ChannelLabel channel={group.channel} />
       styleGroupChannel = _.isEmpty(group.channel) ? "italicText" : ""
@@ -106,10 +104,6 @@ class ItemExtended extends React.Component {
                       <div className="displayInline">
                         <Switch checked={policyUpdates} disabled={true} checkedChildren={"✔"} unCheckedChildren={"✘"} />
                       </div>
-                      <span className="subtitle displayInline">Only office hours:</span>
-                      <div className="displayInline">
-                        <Switch checked={officeHours} disabled={true} checkedChildren={"✔"} unCheckedChildren={"✘"} />
-                      </div>
                       <span className="subtitle displayInline">Safe mode:</span>
                       <div className="displayInline">
                         <Switch checked={safeMode} disabled={true} checkedChildren={"✔"} unCheckedChildren={"✘"} />
@@ -3,9 +3,8 @@ import React, { PropTypes } from "react"
 import { Row, Col, Modal, Input, Button, Alert, OverlayTrigger, ButtonInput } from "react-bootstrap"
 import { Form, ValidatedInput } from "react-bootstrap-validation"
 import Switch from "rc-switch"
-import _ from "underscore"
 import moment from "moment-timezone"
-import {tooltipSafeMode, tooltipOfficeHours} from "../Common/Tooltips.react"
+import {tooltipSafeMode} from "../Common/Tooltips.react"
 
 class ModalAdd extends React.Component {
 
@@ -13,10 +12,8 @@ class ModalAdd extends React.Component {
     super(props);
     this.handleFocus = this.handleFocus.bind(this)
     this.createGroup = this.createGroup.bind(this)
-    this.validateTimezone = this.validateTimezone.bind(this)
     this.changeSafeMode = this.changeSafeMode.bind(this)
     this.changePolicyUpdates = this.changePolicyUpdates.bind(this)
-    this.changePolicyOfficeHours = this.changePolicyOfficeHours.bind(this)
     this.handleValidSubmit = this.handleValidSubmit.bind(this)
     this.handleInvalidSubmit = this.handleInvalidSubmit.bind(this)
     this.exitedModal = this.exitedModal.bind(this)
@@ -24,10 +21,8 @@ class ModalAdd extends React.Component {
     this.state = {
       safeMode: true,
       policyUpdates: true,
-      policyOfficeHours: false,
       isLoading: false,
-      alertVisible: false,
-      timezoneError: false
+      alertVisible: false
     }
   }
 
@@ -38,59 +33,42 @@ class ModalAdd extends React.Component {
   createGroup() {
     this.setState({isLoading: true})
 
-    let isValidTimezone = this.validateTimezone()
+    let period_interval = this.refs.timingUpdatesPerPeriod.getValue() + " " + this.refs.timingUpdatesPerPeriodUnit.getValue(),
+        update_timeout = this.refs.timingUpdatesTimeout.getValue() + " " + this.refs.timingUpdatesTimeoutUnit.getValue()
 
-    if (isValidTimezone) {
-      let period_interval = this.refs.timingUpdatesPerPeriod.getValue() + " " + this.refs.timingUpdatesPerPeriodUnit.getValue(),
-          update_timeout = this.refs.timingUpdatesTimeout.getValue() + " " + this.refs.timingUpdatesTimeoutUnit.getValue()
-
-      var data = {
-        name: this.refs.nameNewGroup.getValue(),
-        description: this.refs.descriptionNewGroup.getValue(),
-        policy_safe_mode: this.state.safeMode,
-        policy_max_updates_per_period: parseInt(this.refs.maxUpdatesPerPeriodInterval.getValue()),
-        policy_updates_enabled: this.state.policyUpdates,
-        policy_period_interval: period_interval,
-        policy_update_timeout: update_timeout,
-        application_id: this.props.data.appID,
-        policy_office_hours: this.state.policyOfficeHours
-      }
-
-      let channel_id = this.refs.channelGroup.getValue()
-      if (channel_id) {
-        data["channel_id"] = channel_id
-      }
-
-      let timezone = this.refs.policyTimezone.getValue()
-      if (timezone) {
-        data["policy_timezone"] = timezone
-      }
+    var data = {
+      name: this.refs.nameNewGroup.getValue(),
+      description: this.refs.descriptionNewGroup.getValue(),
+      policy_safe_mode: this.state.safeMode,
+      policy_max_updates_per_period: parseInt(this.refs.maxUpdatesPerPeriodInterval.getValue()),
+      policy_updates_enabled: this.state.policyUpdates,
+      policy_period_interval: period_interval,
+      policy_update_timeout: update_timeout,
+      application_id: this.props.data.appID
+    }
 
-      applicationsStore.createGroup(data).
-        done(() => {
-          this.props.onHide()
-          this.setState({isLoading: false})
-        }).
-        fail(() => {
-          this.setState({alertVisible: true, isLoading: false})
-        })
-    } else {
-      this.setState({isLoading: false, timezoneError: true})
+    let channel_id = this.refs.channelGroup.getValue()
+    if (channel_id) {
+      data["channel_id"] = channel_id
     }
-  }
 
-  validateTimezone() {
     let timezone = this.refs.policyTimezone.getValue()
-
-    if (this.state.policyOfficeHours && _.isEmpty(timezone)) {
-      return false
-    } else {
-      return true
+    if (timezone) {
+      data["policy_timezone"] = timezone
     }
+
+    applicationsStore.createGroup(data).
+      done(() => {
+        this.props.onHide()
+        this.setState({isLoading: false})
+      }).
+      fail(() => {
+        this.setState({alertVisible: true, isLoading: false})
+      })
   }
 
   handleFocus() {
-    this.setState({alertVisible: false, timezoneError: false})
+    this.setState({alertVisible: false})
   }
 
   changeSafeMode() {
@@ -105,12 +83,6 @@ class ModalAdd extends React.Component {
     })
   }
 
-  changePolicyOfficeHours() {
-    this.setState({
-      policyOfficeHours: !this.state.policyOfficeHours
-    })
-  }
-
   handleValidSubmit() {
     this.createGroup()
   }
@@ -123,10 +95,8 @@ class ModalAdd extends React.Component {
     this.setState({
       safeMode: true,
       policyUpdates: true,
-      policyOfficeHours: false,
       isLoading: false,
-      alertVisible: false,
-      timezoneError: false
+      alertVisible: false
     })
   }
 
@@ -134,8 +104,7 @@ class ModalAdd extends React.Component {
     let channels = this.props.data.channels ? this.props.data.channels : [],
         btnStyle = this.state.isLoading ? " loading" : "",
         btnContent = this.state.isLoading ? "Please wait" : "Submit",
-        timezones = moment.tz.names(),
-        timezoneError = this.state.timezoneError ? {bsStyle: "error"} : ""
+        timezones = moment.tz.names()
 
     return (
       <Modal {...this.props} animation={true} onExited={this.exitedModal}>
@@ -174,14 +143,6 @@ class ModalAdd extends React.Component {
                       <Switch defaultChecked onChange={this.changePolicyUpdates} checkedChildren={"✔"} unCheckedChildren={"✘"} />
                     </div>
                   </div>
-                  <div className="form-group noMargin">
-                    <OverlayTrigger trigger={["hover", "focus"]} container={this} placement="bottom" overlay={tooltipOfficeHours}>
-                      <label className="normalText" htmlFor="policyOfficeHours"><i className="fa fa-question-circle"></i> Only office hours:</label>
-                    </OverlayTrigger>
-                    <div className="displayInline">
-                      <Switch onChange={this.changePolicyOfficeHours} checkedChildren={"✔"} unCheckedChildren={"✘"} />
-                    </div>
-                  </div>
                   <div className="form-group noMargin">
                     <OverlayTrigger trigger={["hover", "focus"]} container={this} placement="bottom" overlay={tooltipSafeMode}>
                       <label className="normalText" htmlFor="safeModeNewGroup"><i className="fa fa-question-circle"></i> Safe mode:</label>
@@ -194,7 +155,7 @@ class ModalAdd extends React.Component {
               </Row>
               <Row>
                 <Col xs={12}>
-                  <Input type="select" label="Timezone:" {...timezoneError} placeholder="" groupClassName="arrow-icon" ref="policyTimezone">
+                  <Input type="select" label="Timezone:" placeholder="" groupClassName="arrow-icon" ref="policyTimezone">
                     <option value="" />
                     {timezones.map((timezone, i) =>
                       <option value={timezone} key={"modalAddGroup_timezone_" + i}>{timezone}</option>
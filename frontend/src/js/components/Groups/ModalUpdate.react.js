@@ -5,7 +5,7 @@ import { Form, ValidatedInput } from "react-bootstrap-validation"
 import Switch from "rc-switch"
 import _ from "underscore"
 import moment from "moment-timezone"
-import {tooltipSafeMode, tooltipOfficeHours} from "../Common/Tooltips.react"
+import {tooltipSafeMode} from "../Common/Tooltips.react"
 
 class ModalUpdate extends React.Component {
 
@@ -16,7 +16,6 @@ class ModalUpdate extends React.Component {
     this.validateTimezone = this.validateTimezone.bind(this)
     this.changeSafeMode = this.changeSafeMode.bind(this)
     this.changePolicyUpdates = this.changePolicyUpdates.bind(this)
-    this.changePolicyOfficeHours = this.changePolicyOfficeHours.bind(this)
     this.handleValidSubmit = this.handleValidSubmit.bind(this)
     this.handleInvalidSubmit = this.handleInvalidSubmit.bind(this)
     this.exitedModal = this.exitedModal.bind(this)
@@ -24,7 +23,6 @@ class ModalUpdate extends React.Component {
     this.state = {
       safeMode: props.data.group.policy_safe_mode,
       policyUpdates: props.data.group.policy_updates_enabled,
-      policyOfficeHours: props.data.group.policy_office_hours,
       isLoading: false,
       alertVisible: false,
       timezoneError: false
@@ -56,7 +54,22 @@ class ModalUpdate extends React.Component {
         policy_updates_enabled: this.state.policyUpdates,
         policy_period_interval: period_interval,
         policy_update_timeout: update_timeout,
-        policy_office_hours: this.state.policyOfficeHours
+        policy_update_windows: this.props.data.group.policy_update_windows,
+        policy_rollout_percentage: this.props.data.group.policy_rollout_percentage,
+        policy_rollout_stages: this.props.data.group.policy_rollout_stages,
+        policy_max_failed_updates: this.props.data.group.policy_max_failed_updates,
+        policy_max_failed_updates_percentage: this.props.data.group.policy_max_failed_updates_percentage,
+        policy_rollback_on_failure: this.props.data.group.policy_rollback_on_failure,
+        policy_exact_version: this.props.data.group.policy_exact_version,
+        policy_gate_group_id: this.props.data.group.policy_gate_group_id,
+        policy_gate_success_percentage: this.props.data.group.policy_gate_success_percentage,
+        policy_gate_soak_time: this.props.data.group.policy_gate_soak_time,
+        policy_max_concurrent_updates: this.props.data.group.policy_max_concurrent_updates,
+        policy_max_timed_out_updates: this.props.data.group.policy_max_timed_out_updates,
+        policy_timed_out_mark_failed: this.props.data.group.policy_timed_out_mark_failed,
+        policy_critical_updates: this.props.data.group.policy_critical_updates,
+        policy_critical_max_concurrent_updates: this.props.data.group.policy_critical_max_concurrent_updates,
+        policy_label_selector: this.props.data.group.policy_label_selector
       }
 
       let channel_id = this.refs.channelGroup.getValue()
@@ -85,7 +98,8 @@ class ModalUpdate extends React.Component {
   validateTimezone() {
     let timezone = this.refs.policyTimezone.getValue()
 
-    if (this.state.policyOfficeHours && _.isEmpty(timezone)) {
+    // Update windows are interpreted in the group's timezone
+    if (!_.isEmpty(this.props.data.group.policy_update_windows) && _.isEmpty(timezone)) {
       return false
     } else {
       return true
@@ -108,17 +122,10 @@ class ModalUpdate extends React.Component {
     })
   }
 
-  changePolicyOfficeHours() {
-    this.setState({
-      policyOfficeHours: !this.state.policyOfficeHours
-    })
-  }
-
   componentWillReceiveProps(nextProps) {
     this.setState({
       safeMode: nextProps.data.group.policy_safe_mode,
-      policyUpdates: nextProps.data.group.policy_updates_enabled,
-      policyOfficeHours: nextProps.data.group.policy_office_hours
+      policyUpdates: nextProps.data.group.policy_updates_enabled
     })
   }
 
@@ -134,7 +141,6 @@ class ModalUpdate extends React.Component {
     this.setState({
       safeMode: this.props.data.group.policy_safe_mode,
       policyUpdates: this.props.data.group.policy_updates_enabled,
-      policyOfficeHours: this.props.data.group.policy_office_hours,
       isLoading: false,
       alertVisible: false,
       timezoneError: false
@@ -188,14 +194,6 @@ class ModalUpdate extends React.Component {
                       <Switch checked={this.state.policyUpdates} onChange={this.changePolicyUpdates} checkedChildren={"✔"} unCheckedChildren={"✘"} />
                     </div>
                   </div>
-                  <div className="form-group noMargin">
-                    <OverlayTrigger trigger={["hover", "focus"]} container={this} placement="bottom" overlay={tooltipOfficeHours}>
-                      <label className="normalText" htmlFor="policyOfficeHours"><i className="fa fa-question-circle"></i> Only office hours:</label>
-                    </OverlayTrigger>
-                    <div className="displayInline">
-                      <Switch checked={this.state.policyOfficeHours} onChange={this.changePolicyOfficeHours} checkedChildren={"✔"} unCheckedChildren={"✘"} />
-                    </div>
-                  </div>
                   <div className="form-group noMargin">
                     <OverlayTrigger trigger={["hover", "focus"]} container={this} placement="bottom" overlay={tooltipSafeMode}>
                       <label className="normalText" htmlFor="safeModeNewGroup"><i className="fa fa-question-circle"></i> Safe mode:</label>
@@ -14,6 +14,7 @@ class Item extends React.Component {
     super(props)
     this.deletePackage = this.deletePackage.bind(this)
     this.updatePackage = this.updatePackage.bind(this)
+    this.retractPackage = this.retractPackage.bind(this)
   }
 
   static propTypes: {
@@ -33,6 +34,13 @@ class Item extends React.Component {
     this.props.handleUpdatePackage(this.props.packageItem.id)
   }
 
+  retractPackage() {
+    let confirmationText = "Are you sure you want to retract this package? Instances running it will be updated to their channel's version, even if that means downgrading them."
+    if (confirm(confirmationText)) {
+      applicationsStore.retractPackage(this.props.packageItem.application_id, this.props.packageItem.id)
+    }
+  }
+
   render() {
     let filename = this.props.packageItem.filename ? this.props.packageItem.filename : "",
         url = this.props.packageItem.url ? this.props.packageItem.url : "#",
@@ -66,6 +74,11 @@ class Item extends React.Component {
             {cleanSemverVersion(this.props.packageItem.version)}
             <br />
             <span className="subtitle">Released:</span> {date}
+            { this.props.packageItem.retracted &&
+              <div className="label-packageItem-container">
+                <Label bsStyle="danger" className="label-packageItem"><i className="fa fa-undo"></i> Retracted</Label>
+              </div>
+            }
             { !_.isNull(this.props.packageItem.channels_blacklist) &&
               <div className="label-packageItem-container">
                 <Label bsStyle="danger" className="label-packageItem"><i className="fa fa-ban"></i> { blacklistInfo }</Label>
@@ -75,6 +88,9 @@ class Item extends React.Component {
         </Col>
         <Col xs={5} className="alignRight marginTop7">
           <button className="cr-button displayInline fa fa-edit" onClick={this.updatePackage}></button>
+          { !this.props.packageItem.retracted &&
+            <button className="cr-button displayInline fa fa-undo" title="Retract" onClick={this.retractPackage}></button>
+          }
           <button className="cr-button displayInline fa fa-trash-o" onClick={this.deletePackage}></button>
         </Col>
       </Row>
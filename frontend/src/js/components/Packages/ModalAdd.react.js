@@ -45,6 +45,7 @@ class ModalAdd extends React.Component {
       type: parseInt(this.refs.typeNewPackage.getValue()),
       size: (this.refs.sizeNewPackage.getValue()).toString(),
       hash: this.refs.hashNewPackage.getValue(),
+      critical: this.refs.criticalNewPackage.getChecked(),
       application_id: this.props.data.appID,
       channels_blacklist: _.isEmpty(this.state.channels_blacklist) ? null : this.state.channels_blacklist.split(",")
     }
@@ -172,6 +173,7 @@ class ModalAdd extends React.Component {
                 errorHelp="Please enter a valid filename (less than 100 characters)"
               />
               <Input type="textarea" label="Description:" ref="descriptionNewPackage" maxLength={250} className="smallHeight" />
+              <Input type="checkbox" label="Critical update (rolled out using the critical updates policy of the groups that enable it)" ref="criticalNewPackage" />
               <Row>
                 <Col xs={6}>
                   <ValidatedInput
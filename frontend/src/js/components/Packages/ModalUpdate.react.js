@@ -47,6 +47,10 @@ class ModalUpdate extends React.Component {
       type: parseInt(this.refs.typePackage.getValue()),
       size: (this.refs.sizePackage.getValue()).toString(),
       hash: this.refs.hashPackage.getValue(),
+      critical: this.refs.criticalPackage.getChecked(),
+      min_version: this.props.data.channel.min_version,
+      files: this.props.data.channel.files,
+      arch: this.props.data.channel.arch,
       application_id: this.props.data.channel.application_id,
       channels_blacklist: _.isEmpty(this.state.channels_blacklist) ? null : this.state.channels_blacklist.split(",")
     }
@@ -183,6 +187,7 @@ class ModalUpdate extends React.Component {
                 errorHelp="Please enter a valid filename (less than 100 characters)"
               />
               <Input type="textarea" label="Description:" defaultValue={this.props.data.channel.description} ref="descriptionPackage" maxLength={250} className="smallHeight" />
+              <Input type="checkbox" label="Critical update (rolled out using the critical updates policy of the groups that enable it)" defaultChecked={this.props.data.channel.critical} ref="criticalPackage" />
               <Row>
                 <Col xs={6}>
                   <ValidatedInput
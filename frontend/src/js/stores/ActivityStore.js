@@ -93,6 +93,69 @@ class ActivityStore extends Store {
         groupName: entry.group_name,
         channelName: entry.channel_name,
         description: "Channel " + entry.channel_name + " is now pointing to version " + entry.version
+      },
+      7: {
+        type: "activityRolloutErrorBudgetExceeded",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Roll out of version " + entry.version + " has been paused as the group's failed updates limit was reached. Group's updates have been disabled"
+      },
+      8: {
+        type: "activityChannelRolledBack",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Channel " + entry.channel_name + " has been rolled back to version " + entry.version + " after a failed roll out"
+      },
+      9: {
+        type: "activityChannelRollbackFailed",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Channel " + entry.channel_name + " could not be rolled back from version " + entry.version + " as no known good package was found"
+      },
+      10: {
+        type: "activityRollbackStarted",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Instances that got the bad version will be rolled back to version " + entry.version + " once the group's updates are enabled again"
+      },
+      11: {
+        type: "activityScheduledChangeSkipped",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Scheduled change of channel " + entry.channel_name + " to version " + entry.version + " has been skipped as the package has blacklisted the channel or has been retracted"
+      },
+      12: {
+        type: "activityInstanceUpdateTimedOut",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Instance " + entry.instance_id + " didn't report back the result of the update to version " + entry.version + " before the update timeout"
+      },
+      13: {
+        type: "activityPackageRetracted",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Version " + entry.version + " has been retracted. " + entry.instances_count + " instances running it will be updated to their channel's version"
+      },
+      14: {
+        type: "activityCriticalUpdatePolicyApplied",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Version " + entry.version + " is a critical update, it's being rolled out using the group's critical updates policy"
+      },
+      15: {
+        type: "activityScheduledChangeFailed",
+        appName: entry.application_name,
+        groupName: entry.group_name,
+        channelName: entry.channel_name,
+        description: "Scheduled change of channel " + entry.channel_name + " to version " + entry.version + " could not be applied"
       }
     }
 
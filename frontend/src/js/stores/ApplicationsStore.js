@@ -208,6 +208,13 @@ class ApplicationsStore extends Store {
       })
   }
 
+  retractPackage(applicationID, packageID) {
+    return API.retractPackage(applicationID, packageID).
+      done(() => {
+        this.getAndUpdateApplication(applicationID)
+      })
+  }
+
   updatePackage(data) {
     return API.updatePackage(data).
       done(packageItem => {
@@ -139,6 +139,15 @@ class InstancesStore extends Store {
         description: "Waiting...",
         status: "On hold",
         explanation: "There was an update pending for the instance but it was put on hold because of the rollout policy"
+      },
+      9: {
+        type: "InstanceStatusTimedOut",
+        className: "danger",
+        spinning: false,
+        icon: "glyphicon glyphicon-time",
+        description: "Update timed out",
+        status: "Timed out",
+        explanation: "The instance didn't report back the result of the update to version " + version + " before the update timeout"
       }
     }
 